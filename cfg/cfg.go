@@ -19,12 +19,41 @@ import (
 )
 
 type Config struct {
-	Logging  Logging
-	File     File
-	Exporter Exporter
+	Server    Server
+	Logging   Logging
+	File      File
+	Exporter  Exporter
+	Retention Retention
+	Store     Store
+	// 수집 상태 journal (재시작시 중단된 수집 복구)
+	Journal Journal
+	// Remote write 전송 설정
+	RemoteWrite RemoteWrite `mapstructure:"REMOTE_WRITE"`
+	Otlp        Otlp
+	Publish     Publish
+	Sink        Sink
+	// OSS, Thanos 응답 기록/재생
+	Fixture Fixture
+	// 네임스페이스 pod 상태 메트릭 (kube-state-metrics 가 없는 클러스터용)
+	KubeState KubeState `mapstructure:"KUBE_STATE"`
+	// 여러 replica 중 leader 만 OSS 수집 (follower 는 공유 저장소의 마지막 수집 결과 제공)
+	LeaderElection LeaderElection `mapstructure:"LEADER_ELECTION"`
+	// 여러 OSS/EMS 를 한 exporter 에서 수집 (비어있으면 EXPORTER, FILE 설정으로 단일 수집)
+	Sites []Site
+	// SiteConfigs 로 만든 사이트별 설정의 사이트 이름 (단일 수집은 "")
+	Site string `mapstructure:"-"`
 	//Prom    Prom
 }
 
+// HTTP 서버 및 종료 설정
+type Server struct {
+	ListenAddress       string `mapstructure:"LISTEN_ADDRESS"`
+	WebConfigFile       string `mapstructure:"WEB_CONFIG_FILE"`       // exporter-toolkit 형식 (TLS, basic/bearer 인증)
+	ShutdownTimeout     int    `mapstructure:"SHUTDOWN_TIMEOUT"`      // 종료 대기(초), 초과시 진행중인 수집을 중단
+	ReadyStaleness      int    `mapstructure:"READY_STALENESS"`       // 마지막 수집 후 ready 유지 시간(초), 0 이면 미확인 (sink 가 metrics 를 수집할 때만 사용)
+	HealthCheckInterval int    `mapstructure:"HEALTH_CHECK_INTERVAL"` // k8s, OSS 확인 주기(초)
+}
+
 type Logging struct {
 	Level  string `mapstructure:"LEVEL"`
 	Encode string `mapstructure:"ENCODE"`
@@ -43,6 +72,140 @@ type Exporter struct {
 	Curl_Url     string `mapstrcuture:"CURL_URL"`
 	Oss_Username string `mapstructure:"OSS_USERNAME"`
 	Oss_Password string `mapstructure:"OSS_PASSWORD"`
+	// OSS 파일을 복사해오는 pod
+	Namespace string `mapstructure:"NAMESPACE"`
+	Pod       string `mapstructure:"POD"`
+	Container string `mapstructure:"CONTAINER"`
+	// 복사(tar) 제한, 0 이면 제한 없음
+	Copy_Max_File_Size int  `mapstructure:"COPY_MAX_FILE_SIZE"` // 파일 하나 최대 크기(MB)
+	Copy_Max_Entries   int  `mapstructure:"COPY_MAX_ENTRIES"`   // 최대 tar 항목 수
+	Copy_Checksum      bool `mapstructure:"COPY_CHECKSUM"`      // 복사 후 pod 의 sha256sum 과 비교
+}
+
+// 백업 폴더 보관 정책 (0 이면 해당 정책 미사용)
+type Retention struct {
+	Enable        bool   `mapstructure:"ENABLE"`
+	Interval      int    `mapstructure:"INTERVAL"`        // 실행 주기(분)
+	MaxAge        int    `mapstructure:"MAX_AGE"`         // 백업 폴더 보관 시간(시간)
+	MaxSize       int    `mapstructure:"MAX_SIZE"`        // 백업 폴더 최대 용량(MB)
+	CompressAfter int    `mapstructure:"COMPRESS_AFTER"`  // 압축 대상 경과 시간(시간)
+	Compression   string `mapstructure:"COMPRESSION"`     // gzip or zstd
+	Rollup        bool   `mapstructure:"ROLLUP"`          // 일별 Family 아카이브 생성
+	ArchiveMaxAge int    `mapstructure:"ARCHIVE_MAX_AGE"` // 일별 아카이브 보관 일수
+}
+
+// 수집 데이터 저장소 (Family 별 세그먼트 파일)
+type Store struct {
+	Enable    bool   `mapstructure:"ENABLE"`
+	Path      string `mapstructure:"PATH"`
+	Retention int    `mapstructure:"RETENTION"` // 보관 시간(시간)
+}
+
+// 수집 주기별 window, Family 상태, checksum, 백업 위치를 CSV_PATH/.journal.json 에 기록
+type Journal struct {
+	Enable bool `mapstructure:"ENABLE"`
+	Keep   int  `mapstructure:"KEEP"` // 보관할 수집 주기 수
+}
+
+// Prometheus remote write 전송 (WAL_PATH 에 배치를 저장 후 전송)
+type RemoteWrite struct {
+	Enable             bool   `mapstructure:"ENABLE"`
+	Url                string `mapstructure:"URL"`
+	WalPath            string `mapstructure:"WAL_PATH"`
+	Shards             int    `mapstructure:"SHARDS"`
+	MaxSamplesPerSend  int    `mapstructure:"MAX_SAMPLES_PER_SEND"`
+	MaxWalFiles        int    `mapstructure:"MAX_WAL_FILES"` // shard 별 최대 대기 배치 수
+	Timeout            int    `mapstructure:"TIMEOUT"`       // 초
+	MaxBackoff         int    `mapstructure:"MAX_BACKOFF"`   // 재전송 최대 대기(초)
+	Username           string `mapstructure:"USERNAME"`
+	Password           string `mapstructure:"PASSWORD"`
+	BearerToken        string `mapstructure:"BEARER_TOKEN"`
+	InsecureSkipVerify bool   `mapstructure:"INSECURE_SKIP_VERIFY"`
+}
+
+// OpenTelemetry OTLP 메트릭 전송
+type Otlp struct {
+	Enable             bool              `mapstructure:"ENABLE"`
+	Protocol           string            `mapstructure:"PROTOCOL"` // grpc or http
+	Endpoint           string            `mapstructure:"ENDPOINT"` // grpc: host:4317, http: http://host:4318/v1/metrics
+	Insecure           bool              `mapstructure:"INSECURE"` // grpc 평문 연결
+	InsecureSkipVerify bool              `mapstructure:"INSECURE_SKIP_VERIFY"`
+	Timeout            int               `mapstructure:"TIMEOUT"`  // 초
+	Interval           int               `mapstructure:"INTERVAL"` // app_config 경로 전송 주기(초)
+	Headers            map[string]string `mapstructure:"HEADERS"`
+	Paths              []string          `mapstructure:"PATHS"` // 전송할 경로 (metrics, cpu/metrics ...)
+}
+
+// OSS 행 데이터 메시지 버스 전송 (kafka or nats)
+type Publish struct {
+	Enable             bool     `mapstructure:"ENABLE"`
+	Bus                string   `mapstructure:"BUS"`     // kafka or nats
+	Brokers            []string `mapstructure:"BROKERS"` // kafka: host:9092, nats: nats://host:4222
+	Topic              string   `mapstructure:"TOPIC"`   // Family 별 topic, {family} 치환
+	Format             string   `mapstructure:"FORMAT"`  // json or avro
+	SchemaRegistry     string   `mapstructure:"SCHEMA_REGISTRY"`
+	SchemaSubject      string   `mapstructure:"SCHEMA_SUBJECT"`
+	SchemaID           int      `mapstructure:"SCHEMA_ID"` // 0 이면 SCHEMA_REGISTRY 에 등록하여 사용
+	SpoolPath          string   `mapstructure:"SPOOL_PATH"`
+	MaxSpoolFiles      int      `mapstructure:"MAX_SPOOL_FILES"`  // 최대 대기 배치 수
+	DeadLetterPath     string   `mapstructure:"DEAD_LETTER_PATH"` // broker 가 거절한 배치 보관, 비어있으면 SPOOL_PATH/dead-letter
+	Timeout            int      `mapstructure:"TIMEOUT"`          // 초
+	MaxBackoff         int      `mapstructure:"MAX_BACKOFF"`      // 재전송 최대 대기(초)
+	Username           string   `mapstructure:"USERNAME"`
+	Password           string   `mapstructure:"PASSWORD"`
+	Tls                bool     `mapstructure:"TLS"`
+	InsecureSkipVerify bool     `mapstructure:"INSECURE_SKIP_VERIFY"`
+}
+
+// scrape 대신 주기적으로 수집하여 pushgateway, textfile 로 내보내기
+type Sink struct {
+	Enable      bool        `mapstructure:"ENABLE"`
+	Interval    int         `mapstructure:"INTERVAL"` // 수집 주기(초)
+	Pushgateway Pushgateway `mapstructure:"PUSHGATEWAY"`
+	Textfile    Textfile    `mapstructure:"TEXTFILE"`
+}
+
+type Pushgateway struct {
+	Url                string   `mapstructure:"URL"`
+	Job                string   `mapstructure:"JOB"`
+	Username           string   `mapstructure:"USERNAME"`
+	Password           string   `mapstructure:"PASSWORD"`
+	Timeout            int      `mapstructure:"TIMEOUT"` // 초
+	InsecureSkipVerify bool     `mapstructure:"INSECURE_SKIP_VERIFY"`
+	Paths              []string `mapstructure:"PATHS"` // 내보낼 경로 (metrics, cpu/metrics ...)
+}
+
+// fixture bundle 기록/재생 (MODE 가 없으면 사용 안함)
+type Fixture struct {
+	Mode      string `mapstructure:"MODE"`      // record or replay
+	Dir       string `mapstructure:"DIR"`       // bundle 경로
+	Anonymize bool   `mapstructure:"ANONYMIZE"` // record 시 ne_name, location 을 hash 값으로 기록
+}
+
+// k8s API 를 informer 로 감시하여 pod 상태를 PATH 로 노출
+type KubeState struct {
+	Enable     bool     `mapstructure:"ENABLE"`
+	Path       string   `mapstructure:"PATH"` // 노출 경로 (kube/metrics)
+	Namespaces []string `mapstructure:"NAMESPACES"`
+	Kubeconfig string   `mapstructure:"KUBECONFIG"` // 비어있으면 in-cluster 설정
+}
+
+// Lease 기반 leader 선출, 시간은 초
+type LeaderElection struct {
+	Enable        bool   `mapstructure:"ENABLE"`
+	Namespace     string `mapstructure:"NAMESPACE"` // Lease 네임스페이스
+	Name          string `mapstructure:"NAME"`      // Lease 이름
+	Identity      string `mapstructure:"IDENTITY"`  // 비어있으면 hostname (pod 이름)
+	LeaseDuration int    `mapstructure:"LEASE_DURATION"`
+	RenewDeadline int    `mapstructure:"RENEW_DEADLINE"`
+	RetryPeriod   int    `mapstructure:"RETRY_PERIOD"`
+	Kubeconfig    string `mapstructure:"KUBECONFIG"` // 비어있으면 in-cluster 설정
+}
+
+// node_exporter textfile collector 폴더
+type Textfile struct {
+	Dir   string   `mapstructure:"DIR"`
+	Paths []string `mapstructure:"PATHS"`
 }
 
 //type Prom struct {
@@ -59,7 +222,7 @@ func InitConfig() Config {
 	case "prd":
 		configFileName = "config"
 	default:
-		fmt.Printf("Unknown environment: %s. Using default config.\n", env)
+		fmt.Fprintf(os.Stderr, "Unknown environment: %s. Using default config.\n", env)
 		configFileName = "config"
 	}
 
@@ -71,6 +234,11 @@ func InitConfig() Config {
 	viper.AutomaticEnv()
 
 	// viper defaultSet 설정
+	viper.SetDefault("server.listen_address", getEnv("LISTEN_ADDRESS", ":8080"))
+	viper.SetDefault("server.web_config_file", getEnv("WEB_CONFIG_FILE", ""))
+	viper.SetDefault("server.shutdown_timeout", getEnvAsInt("SHUTDOWN_TIMEOUT", 30))
+	viper.SetDefault("server.ready_staleness", getEnvAsInt("READY_STALENESS", 0))
+	viper.SetDefault("server.health_check_interval", getEnvAsInt("HEALTH_CHECK_INTERVAL", 60))
 	viper.SetDefault("loging.level", getEnv("LEVEL", "INFO"))
 	viper.SetDefault("loging.encode", getEnv("ENCODE", "JSON"))
 	viper.SetDefault("file.mec_config", getEnv("MEC_CONFIG", "/mnt/data/config"))
@@ -82,19 +250,102 @@ func InitConfig() Config {
 	viper.SetDefault("exporter.curl_url", getEnv("CURL_URL", ""))
 	viper.SetDefault("exporter.oss_username", getEnv("OSS_USERNAME", ""))
 	viper.SetDefault("exporter.oss_password", getEnv("OSS_PASSWORD", ""))
+	viper.SetDefault("exporter.namespace", getEnv("SOURCE_NAMESPACE", "usm-compact"))
+	viper.SetDefault("exporter.pod", getEnv("SOURCE_POD", "mfsm-0"))
+	viper.SetDefault("exporter.container", getEnv("SOURCE_CONTAINER", "process"))
+	viper.SetDefault("exporter.copy_max_file_size", getEnvAsInt("COPY_MAX_FILE_SIZE", 512))
+	viper.SetDefault("exporter.copy_max_entries", getEnvAsInt("COPY_MAX_ENTRIES", 1000))
+	viper.SetDefault("exporter.copy_checksum", getEnvAsBool("COPY_CHECKSUM", false))
+	viper.SetDefault("retention.enable", getEnvAsBool("RETENTION_ENABLE", false))
+	viper.SetDefault("retention.interval", getEnvAsInt("RETENTION_INTERVAL", 60))
+	viper.SetDefault("retention.max_age", getEnvAsInt("RETENTION_MAX_AGE", 0))
+	viper.SetDefault("retention.max_size", getEnvAsInt("RETENTION_MAX_SIZE", 0))
+	viper.SetDefault("retention.compress_after", getEnvAsInt("RETENTION_COMPRESS_AFTER", 0))
+	viper.SetDefault("retention.compression", getEnv("RETENTION_COMPRESSION", "gzip"))
+	viper.SetDefault("retention.rollup", getEnvAsBool("RETENTION_ROLLUP", false))
+	viper.SetDefault("retention.archive_max_age", getEnvAsInt("RETENTION_ARCHIVE_MAX_AGE", 0))
+	viper.SetDefault("journal.enable", getEnvAsBool("JOURNAL_ENABLE", false))
+	viper.SetDefault("journal.keep", getEnvAsInt("JOURNAL_KEEP", 96))
+	viper.SetDefault("store.enable", getEnvAsBool("STORE_ENABLE", false))
+	viper.SetDefault("store.path", getEnv("STORE_PATH", ""))
+	viper.SetDefault("store.retention", getEnvAsInt("STORE_RETENTION", 24))
+	viper.SetDefault("remote_write.enable", getEnvAsBool("REMOTE_WRITE_ENABLE", false))
+	viper.SetDefault("remote_write.url", getEnv("REMOTE_WRITE_URL", ""))
+	viper.SetDefault("remote_write.wal_path", getEnv("REMOTE_WRITE_WAL_PATH", ""))
+	viper.SetDefault("remote_write.shards", getEnvAsInt("REMOTE_WRITE_SHARDS", 2))
+	viper.SetDefault("remote_write.max_samples_per_send", getEnvAsInt("REMOTE_WRITE_MAX_SAMPLES_PER_SEND", 2000))
+	viper.SetDefault("remote_write.max_wal_files", getEnvAsInt("REMOTE_WRITE_MAX_WAL_FILES", 1000))
+	viper.SetDefault("remote_write.timeout", getEnvAsInt("REMOTE_WRITE_TIMEOUT", 30))
+	viper.SetDefault("remote_write.max_backoff", getEnvAsInt("REMOTE_WRITE_MAX_BACKOFF", 300))
+	viper.SetDefault("remote_write.username", getEnv("REMOTE_WRITE_USERNAME", ""))
+	viper.SetDefault("remote_write.password", getEnv("REMOTE_WRITE_PASSWORD", ""))
+	viper.SetDefault("remote_write.bearer_token", getEnv("REMOTE_WRITE_BEARER_TOKEN", ""))
+	viper.SetDefault("remote_write.insecure_skip_verify", getEnvAsBool("REMOTE_WRITE_INSECURE_SKIP_VERIFY", false))
+	viper.SetDefault("otlp.enable", getEnvAsBool("OTLP_ENABLE", false))
+	viper.SetDefault("otlp.protocol", getEnv("OTLP_PROTOCOL", "grpc"))
+	viper.SetDefault("otlp.endpoint", getEnv("OTLP_ENDPOINT", ""))
+	viper.SetDefault("otlp.insecure", getEnvAsBool("OTLP_INSECURE", false))
+	viper.SetDefault("otlp.insecure_skip_verify", getEnvAsBool("OTLP_INSECURE_SKIP_VERIFY", false))
+	viper.SetDefault("otlp.timeout", getEnvAsInt("OTLP_TIMEOUT", 10))
+	viper.SetDefault("otlp.interval", getEnvAsInt("OTLP_INTERVAL", 60))
+	viper.SetDefault("otlp.paths", getEnv("OTLP_PATHS", "metrics"))
+	viper.SetDefault("publish.enable", getEnvAsBool("PUBLISH_ENABLE", false))
+	viper.SetDefault("publish.bus", getEnv("PUBLISH_BUS", "kafka"))
+	viper.SetDefault("publish.brokers", getEnv("PUBLISH_BROKERS", ""))
+	viper.SetDefault("publish.topic", getEnv("PUBLISH_TOPIC", "oss.{family}"))
+	viper.SetDefault("publish.format", getEnv("PUBLISH_FORMAT", "json"))
+	viper.SetDefault("publish.schema_registry", getEnv("PUBLISH_SCHEMA_REGISTRY", ""))
+	viper.SetDefault("publish.schema_subject", getEnv("PUBLISH_SCHEMA_SUBJECT", "oss-record-value"))
+	viper.SetDefault("publish.schema_id", getEnvAsInt("PUBLISH_SCHEMA_ID", 0))
+	viper.SetDefault("publish.spool_path", getEnv("PUBLISH_SPOOL_PATH", ""))
+	viper.SetDefault("publish.max_spool_files", getEnvAsInt("PUBLISH_MAX_SPOOL_FILES", 1000))
+	viper.SetDefault("publish.dead_letter_path", getEnv("PUBLISH_DEAD_LETTER_PATH", ""))
+	viper.SetDefault("publish.timeout", getEnvAsInt("PUBLISH_TIMEOUT", 30))
+	viper.SetDefault("publish.max_backoff", getEnvAsInt("PUBLISH_MAX_BACKOFF", 300))
+	viper.SetDefault("publish.username", getEnv("PUBLISH_USERNAME", ""))
+	viper.SetDefault("publish.password", getEnv("PUBLISH_PASSWORD", ""))
+	viper.SetDefault("publish.tls", getEnvAsBool("PUBLISH_TLS", false))
+	viper.SetDefault("publish.insecure_skip_verify", getEnvAsBool("PUBLISH_INSECURE_SKIP_VERIFY", false))
+	viper.SetDefault("sink.enable", getEnvAsBool("SINK_ENABLE", false))
+	viper.SetDefault("sink.interval", getEnvAsInt("SINK_INTERVAL", 300))
+	viper.SetDefault("sink.pushgateway.url", getEnv("SINK_PUSHGATEWAY_URL", ""))
+	viper.SetDefault("sink.pushgateway.job", getEnv("SINK_PUSHGATEWAY_JOB", "cnf_exporter"))
+	viper.SetDefault("sink.pushgateway.username", getEnv("SINK_PUSHGATEWAY_USERNAME", ""))
+	viper.SetDefault("sink.pushgateway.password", getEnv("SINK_PUSHGATEWAY_PASSWORD", ""))
+	viper.SetDefault("sink.pushgateway.timeout", getEnvAsInt("SINK_PUSHGATEWAY_TIMEOUT", 30))
+	viper.SetDefault("sink.pushgateway.insecure_skip_verify", getEnvAsBool("SINK_PUSHGATEWAY_INSECURE_SKIP_VERIFY", false))
+	viper.SetDefault("sink.pushgateway.paths", getEnv("SINK_PUSHGATEWAY_PATHS", ""))
+	viper.SetDefault("sink.textfile.dir", getEnv("SINK_TEXTFILE_DIR", ""))
+	viper.SetDefault("sink.textfile.paths", getEnv("SINK_TEXTFILE_PATHS", ""))
+	viper.SetDefault("fixture.mode", getEnv("FIXTURE_MODE", ""))
+	viper.SetDefault("fixture.dir", getEnv("FIXTURE_DIR", ""))
+	viper.SetDefault("fixture.anonymize", getEnvAsBool("FIXTURE_ANONYMIZE", false))
+	viper.SetDefault("kube_state.enable", getEnvAsBool("KUBE_STATE_ENABLE", false))
+	viper.SetDefault("kube_state.path", getEnv("KUBE_STATE_PATH", "kube/metrics"))
+	viper.SetDefault("kube_state.namespaces", getEnv("KUBE_STATE_NAMESPACES", "usm-compact"))
+	viper.SetDefault("kube_state.kubeconfig", getEnv("KUBE_STATE_KUBECONFIG", ""))
+	viper.SetDefault("leader_election.enable", getEnvAsBool("LEADER_ELECTION_ENABLE", false))
+	viper.SetDefault("leader_election.namespace", getEnv("LEADER_ELECTION_NAMESPACE", getEnv("POD_NAMESPACE", "usm-compact")))
+	viper.SetDefault("leader_election.name", getEnv("LEADER_ELECTION_NAME", "cnf-exporter"))
+	viper.SetDefault("leader_election.identity", getEnv("LEADER_ELECTION_IDENTITY", getEnv("POD_NAME", "")))
+	viper.SetDefault("leader_election.lease_duration", getEnvAsInt("LEADER_ELECTION_LEASE_DURATION", 15))
+	viper.SetDefault("leader_election.renew_deadline", getEnvAsInt("LEADER_ELECTION_RENEW_DEADLINE", 10))
+	viper.SetDefault("leader_election.retry_period", getEnvAsInt("LEADER_ELECTION_RETRY_PERIOD", 2))
+	viper.SetDefault("leader_election.kubeconfig", getEnv("LEADER_ELECTION_KUBECONFIG", ""))
 
+	// 안내 메시지는 stderr 로 출력 (history, convert CLI 의 stdout 출력과 분리)
 	err := viper.ReadInConfig()
 	if err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			fmt.Printf("Config 파일 로드 중 에러: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Config 파일 로드 중 에러: %v\n", err)
 		}
-		fmt.Println("Config 파일이 존재하지 않아 기본값을 사용합니다.")
+		fmt.Fprintln(os.Stderr, "Config 파일이 존재하지 않아 기본값을 사용합니다.")
 	}
 
 	var config Config
 	err = viper.Unmarshal(&config)
 	if err != nil {
-		fmt.Println("config 매핑 에러")
+		fmt.Fprintln(os.Stderr, "config 매핑 에러")
 	}
 
 	return config
@@ -126,8 +377,7 @@ func getEnvAsInt(key string, defaultValue int) int {
 		return defaultValue
 	}
 
-	var intValue int
-	_, err := fmt.Scan(value, &intValue)
+	intValue, err := strconv.Atoi(value)
 	if err != nil {
 		return defaultValue
 	}
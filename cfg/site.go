@@ -0,0 +1,142 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package cfg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// 수집 대상 OSS/EMS (비어있는 값은 EXPORTER, FILE 설정을 사용)
+type Site struct {
+	Name         string   `mapstructure:"NAME"`
+	Curl_Url     string   `mapstructure:"CURL_URL"`
+	Oss_Username string   `mapstructure:"OSS_USERNAME"`
+	Oss_Password string   `mapstructure:"OSS_PASSWORD"`
+	Namespace    string   `mapstructure:"NAMESPACE"`
+	Pod          string   `mapstructure:"POD"`
+	Container    string   `mapstructure:"CONTAINER"`
+	Family_Name  []string `mapstructure:"FAMILY_NAME"`
+	Path         string   `mapstructure:"PATH"` // CSV_PATH, API_PATH, STORE_PATH 아래 폴더 (기본 NAME)
+}
+
+// MultiSite SITES 가 설정되어 있으면 메트릭에 site 라벨을 붙인다.
+func (c Config) MultiSite() bool {
+	return len(c.Sites) > 0
+}
+
+// ValidateSites SITES 설정 검사
+// NAME 이 비어있거나 중복된 사이트, 폴더가 CSV_PATH/API_PATH 밖이거나 다른 사이트와 같은(또는 겹치는) 사이트는
+// store, journal, snapshot 을 함께 쓰게 되므로 오류로 본다.
+func (c Config) ValidateSites() error {
+	if !c.MultiSite() {
+		return nil
+	}
+	names := map[string]bool{}
+	for i, site := range c.Sites {
+		name := strings.TrimSpace(site.Name)
+		if name == "" {
+			return fmt.Errorf("sites[%d]: NAME is empty", i)
+		}
+		if names[name] {
+			return fmt.Errorf("sites[%d]: duplicate NAME %q", i, name)
+		}
+		names[name] = true
+	}
+
+	configs := c.SiteConfigs()
+	for _, kind := range []struct {
+		name string
+		base string
+		path func(Config) string
+	}{
+		{"CSV_PATH", c.File.CSV_Path, func(config Config) string { return config.File.CSV_Path }},
+		{"API_PATH", c.File.API_Path, func(config Config) string { return config.File.API_Path }},
+	} {
+		for i, config := range configs {
+			if !within(kind.base, kind.path(config)) || filepath.Clean(kind.base) == filepath.Clean(kind.path(config)) {
+				return fmt.Errorf("site %q: folder %s is not under %s %s", config.Site, kind.path(config), kind.name, kind.base)
+			}
+			for _, other := range configs[:i] {
+				if within(kind.path(other), kind.path(config)) || within(kind.path(config), kind.path(other)) {
+					return fmt.Errorf("sites %q and %q share %s folder %s", other.Site, config.Site, kind.name, kind.path(config))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// path 가 dir 이거나 dir 아래 경로인지
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// SiteConfigs 사이트별 설정
+// SITES 가 없으면 기존 설정 하나를, 있으면 사이트마다 OSS 접속정보, pod, Family 와
+// CSV_PATH/API_PATH/STORE_PATH 를 사이트 폴더로 바꾼 설정을 반환한다.
+func (c Config) SiteConfigs() []Config {
+	if !c.MultiSite() {
+		return []Config{c}
+	}
+
+	configs := make([]Config, 0, len(c.Sites))
+	for _, site := range c.Sites {
+		config := c
+		config.Site = site.Name
+		config.Sites = nil
+
+		if site.Curl_Url != "" {
+			config.Exporter.Curl_Url = site.Curl_Url
+		}
+		if site.Oss_Username != "" {
+			config.Exporter.Oss_Username = site.Oss_Username
+		}
+		if site.Oss_Password != "" {
+			config.Exporter.Oss_Password = site.Oss_Password
+		}
+		if site.Namespace != "" {
+			config.Exporter.Namespace = site.Namespace
+		}
+		if site.Pod != "" {
+			config.Exporter.Pod = site.Pod
+		}
+		if site.Container != "" {
+			config.Exporter.Container = site.Container
+		}
+		if len(site.Family_Name) > 0 {
+			config.File.Family_Name = site.Family_Name
+		}
+
+		dir := site.Path
+		if dir == "" {
+			dir = site.Name
+		}
+		config.File.CSV_Path = filepath.Join(c.File.CSV_Path, dir)
+		config.File.API_Path = filepath.Join(c.File.API_Path, dir)
+		config.Store.Path = filepath.Join(c.Store.Path, dir)
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+// SiteConfig 이름으로 사이트 설정 찾기 (단일 수집은 "" 만 허용)
+func (c Config) SiteConfig(name string) (Config, bool) {
+	for _, config := range c.SiteConfigs() {
+		if config.Site == name {
+			return config, true
+		}
+	}
+	return Config{}, false
+}
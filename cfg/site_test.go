@@ -0,0 +1,53 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package cfg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSites(t *testing.T) {
+	tests := []struct {
+		name  string
+		sites []Site
+		err   string
+	}{
+		{"single site", nil, ""},
+		{"ok", []Site{{Name: "east"}, {Name: "west"}}, ""},
+		{"path", []Site{{Name: "east", Path: "oss-east"}, {Name: "west"}}, ""},
+		{"empty name", []Site{{Name: "east"}, {Name: " "}}, "sites[1]: NAME is empty"},
+		{"duplicate name", []Site{{Name: "east"}, {Name: "east", Path: "east2"}}, `duplicate NAME "east"`},
+		{"same path", []Site{{Name: "east"}, {Name: "west", Path: "east"}}, `sites "east" and "west" share CSV_PATH folder`},
+		{"nested path", []Site{{Name: "east"}, {Name: "west", Path: "east/west"}}, `sites "east" and "west" share CSV_PATH folder`},
+		{"base path", []Site{{Name: "east", Path: "."}}, `site "east": folder /data/csv is not under CSV_PATH`},
+		{"outside path", []Site{{Name: "east", Path: "../east"}}, `site "east": folder /data/east is not under CSV_PATH`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{Sites: tt.sites}
+			config.File.CSV_Path = "/data/csv"
+			config.File.API_Path = "/data/api"
+
+			err := config.ValidateSites()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/exporter"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// convertInput convert CLI 입력 파일
+type convertInput struct {
+	File   string
+	Family string
+	Rows   int
+	Header []string // 2행 컬럼명
+}
+
+// runConvert OSS CSV 를 cnf_config.yml 메트릭으로 변환하는 CLI (수집과 같은 변환, delta 제외)
+// ./exporter convert -metricConfig cnf_config.yml AMFTPS.csv UECON_AMF.csv > oss.prom
+// ./exporter convert -format openmetrics AMFTPS.csv > oss.om
+// ./exporter convert -family AMFTPS -dry-run performanceData_20231108_144317.csv
+func runConvert(args []string) int {
+	return convert(os.Stdout, args)
+}
+
+// convert 변환 결과를 w 에 출력
+// OpenMetrics 는 counter 이름이 _total 로 끝나야 하므로, /metrics 와 같은 이름을 유지하는 대신
+// _total 이 없는 counter 는 unknown 타입으로 출력된다. 그래서 기본 출력은 Prometheus text 로 둔다.
+func convert(w io.Writer, args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	configFile := fs.String("metricConfig", "cnf_config.yml", "cnf metric configuration file")
+	family := fs.String("family", "", "family name of every file (default file name, e.g. AMFTPS.csv)")
+	format := fs.String("format", "prometheus", "output format (prometheus|openmetrics), openmetrics includes the period timestamp but types counters without _total as unknown")
+	dryRun := fs.Bool("dry-run", false, "report the metrics and unmapped columns of each file instead of converting")
+	_ = fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: exporter convert [flags] file.csv ...")
+		fs.PrintDefaults()
+		return 2
+	}
+	if *format != "openmetrics" && *format != "prometheus" {
+		fmt.Fprintln(os.Stderr, "unsupported format:", *format)
+		return 2
+	}
+	if err := loadMetricConfig(*configFile, ""); err != nil {
+		fmt.Fprintln(os.Stderr, "metric config:", err)
+		return 1
+	}
+
+	var inputs []convertInput
+	data := map[string][][]string{}
+	for _, file := range files {
+		input, rows, err := readConvertInput(file, *family)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		inputs = append(inputs, input)
+		// 같은 Family 의 파일은 데이터 행을 합친다.
+		if prev, ok := data[input.Family]; ok {
+			data[input.Family] = append(prev, rows[3:]...)
+		} else {
+			data[input.Family] = rows
+		}
+	}
+
+	selectMetrics(data)
+	load := func(family string) ([][]string, error) {
+		rows, ok := data[family]
+		if !ok {
+			return nil, fmt.Errorf("%s: no input file", family)
+		}
+		rows, _ = rowFilter.Apply(family, rows)
+		return rows, nil
+	}
+	// dry-run 은 변환 전에 입력 헤더로 컬럼을 확인하고, 없는 컬럼을 읽는 메트릭을 표시한다.
+	if *dryRun {
+		if err := convertReport(w, inputs, load); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	plain, stamped := convertCollect(load)
+
+	metrics, outFormat := stamped, expfmt.FmtOpenMetrics_1_0_0
+	if *format == "prometheus" {
+		metrics, outFormat = plain, expfmt.FmtText
+	}
+	families, err := gatherMetrics(metrics)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	encoder := expfmt.NewEncoder(w, outFormat)
+	for _, mf := range families {
+		if err := encoder.Encode(mf); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if closer, ok := encoder.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// readConvertInput CSV 파일과 Family 이름 (family 가 없으면 파일명, AMFTPS.csv.gz => AMFTPS)
+func readConvertInput(file, family string) (convertInput, [][]string, error) {
+	if family == "" {
+		name, ok := csv.FamilyName(filepath.Base(file))
+		if !ok {
+			return convertInput{}, nil, fmt.Errorf("%s: unknown extension, use -family", file)
+		}
+		family = name
+	}
+	rows, err := csv.LoadCsv(file)
+	if err != nil {
+		return convertInput{}, nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if len(rows) < 3 {
+		return convertInput{}, nil, fmt.Errorf("%s: missing header rows", file)
+	}
+	return convertInput{
+		File:   file,
+		Family: strings.ReplaceAll(family, " ", "_"),
+		Rows:   len(rows) - 3,
+		Header: rows[2],
+	}, rows, nil
+}
+
+// selectMetrics 입력 Family 의 metrics 와, 기준/join Family 가 모두 있는 derived 만 남긴다.
+func selectMetrics(data map[string][][]string) {
+	for metricName, metric := range metricConfig.Metrics {
+		if _, ok := data[metric.Description]; !ok {
+			delete(metricConfig.Metrics, metricName)
+		}
+	}
+	for metricName, def := range metricConfig.Derived {
+		families := append([]string{def.Description}, def.Parsed.Families()...)
+		for _, family := range families {
+			if _, ok := data[family]; !ok {
+				delete(metricConfig.Derived, metricName)
+				break
+			}
+		}
+	}
+}
+
+// convertCollect 수집과 같은 변환으로 메트릭과 period timestamp 를 붙인 메트릭을 만든다.
+func convertCollect(load func(family string) ([][]string, error)) ([]prometheus.Metric, []prometheus.Metric) {
+	descs := make(chan *prometheus.Desc)
+	go func() {
+		for range descs {
+		}
+	}()
+	(&CnfCollector{}).Describe(descs)
+	close(descs)
+
+	ch := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var plain []prometheus.Metric
+	go func() {
+		for metric := range ch {
+			plain = append(plain, metric)
+		}
+		close(done)
+	}()
+	var stamped []prometheus.Metric
+	metricsCollect(load, nil, nil, ch, &stamped)
+	close(ch)
+	<-done
+	return plain, stamped
+}
+
+func gatherMetrics(metrics []prometheus.Metric) ([]*dto.MetricFamily, error) {
+	snapshot := &exporter.Snapshot{}
+	snapshot.Set(metrics)
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(snapshot); err != nil {
+		return nil, err
+	}
+	return registry.Gather()
+}
+
+// convertReport 파일마다 만들어지는 메트릭(타입, 컬럼, 시계열 수)과 사용하지 않는 값 컬럼 출력
+// 입력 헤더에 없는 컬럼을 읽는 메트릭은 시계열 수 대신 없는 컬럼을 표시한다.
+func convertReport(w io.Writer, inputs []convertInput, load func(family string) ([][]string, error)) error {
+	metrics, _ := convertCollect(load)
+	families, err := gatherMetrics(metrics)
+	if err != nil {
+		return err
+	}
+	samples := map[string]int{}
+	for _, mf := range families {
+		samples[mf.GetName()] = len(mf.GetMetric())
+	}
+	// result 시계열 수 또는 없는 컬럼
+	result := func(name string, header []string, columns []int) string {
+		var missing []string
+		for _, column := range columns {
+			if column >= len(header) {
+				missing = append(missing, fmt.Sprint(column))
+			}
+		}
+		if len(missing) > 0 {
+			return "missing column " + strings.Join(missing, ", ")
+		}
+		return fmt.Sprintf("%d series", samples[name])
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, input := range inputs {
+		fmt.Fprintf(tw, "%s (family %s, %d rows)\n", input.File, input.Family, input.Rows)
+
+		used := map[int]bool{}
+		lines := 0
+		for _, metricName := range sortedKeys(metricConfig.Metrics) {
+			metric := metricConfig.Metrics[metricName]
+			if metric.Description != input.Family {
+				continue
+			}
+			columns := metricColumns(metric)
+			for _, column := range columns {
+				used[column] = true
+			}
+			name := prometheus.BuildFQName("p5g_exporter", "", metricName)
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", name, strings.ToLower(metric.Type), columnNames(input.Header, columns), result(name, input.Header, columns))
+			lines++
+		}
+		for _, metricName := range sortedKeys(metricConfig.Derived) {
+			def := metricConfig.Derived[metricName]
+			var columns []int
+			for _, ref := range def.Parsed.Refs() {
+				refFamily := ref.Family
+				if refFamily == "" {
+					refFamily = def.Description
+				}
+				if refFamily == input.Family {
+					columns = append(columns, ref.Column)
+					used[ref.Column] = true
+				}
+			}
+			if len(columns) == 0 {
+				continue
+			}
+			name := prometheus.BuildFQName("p5g_exporter", "", metricName)
+			kind := "derived"
+			if def.Description != input.Family {
+				kind = "derived (join)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", name, kind, columnNames(input.Header, columns), result(name, input.Header, columns))
+			lines++
+		}
+		if lines == 0 {
+			fmt.Fprintf(tw, "  no metrics configured for %s\n", input.Family)
+		}
+
+		var unmapped []int
+		for column := 7; column < len(input.Header); column++ {
+			if !used[column] {
+				unmapped = append(unmapped, column)
+			}
+		}
+		if len(unmapped) > 0 {
+			fmt.Fprintf(tw, "  unmapped columns: %s\n", columnNames(input.Header, unmapped))
+		}
+	}
+	return tw.Flush()
+}
+
+// metricColumns 메트릭이 읽는 값 컬럼
+func metricColumns(metric CnfMetric) []int {
+	if strings.ToLower(metric.Type) != "histogram" {
+		return []int{metric.Value_Sequence}
+	}
+	var columns []int
+	for _, bucket := range metric.Buckets {
+		columns = append(columns, bucket.Column)
+	}
+	for _, column := range []int{metric.Sum_Sequence, metric.Avg_Sequence, metric.Count_Sequence} {
+		if column > 0 {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// columnNames 9 (TotalMsg), 10 (CurReg)
+func columnNames(header []string, columns []int) string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		name := fmt.Sprint(column)
+		if column < len(header) && strings.TrimSpace(header[column]) != "" {
+			name += " (" + strings.TrimSpace(header[column]) + ")"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
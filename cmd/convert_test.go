@@ -0,0 +1,118 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"bytes"
+	"github.com/prometheus/client_golang/prometheus"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// convert CLI 출력 (testdata/oss 의 모든 Family, period 는 +09:00)
+func TestConvertGolden(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("KST", 9*60*60)
+	t.Cleanup(func() {
+		time.Local = local
+		metricConfig = Config{}
+		rowFilter = nil
+	})
+
+	files, err := filepath.Glob("testdata/oss/*.csv")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("testdata/oss: %v", err)
+	}
+	for _, tc := range []struct {
+		args   []string
+		golden string
+	}{
+		{nil, "convert_prometheus.golden"},
+		{[]string{"-format", "openmetrics"}, "convert_openmetrics.golden"},
+		{[]string{"-dry-run"}, "convert_dry_run.golden"},
+	} {
+		t.Run(tc.golden, func(t *testing.T) {
+			// selectMetrics 가 입력에 없는 메트릭을 지우므로 매번 다시 읽는다.
+			metricConfig = Config{}
+			rowFilter = nil
+
+			var out bytes.Buffer
+			args := append(append([]string{"-metricConfig", "testdata/cnf_config.yml"}, tc.args...), files...)
+			if code := convert(&out, args); code != 0 {
+				t.Fatalf("convert %v: exit %d", tc.args, code)
+			}
+			compareGolden(t, tc.golden, out.Bytes())
+		})
+	}
+}
+
+// 없는 컬럼을 읽는 메트릭은 변환시 건너뛰고 dry-run 에 표시한다.
+func TestConvertMissingColumn(t *testing.T) {
+	t.Cleanup(func() {
+		metricConfig = Config{}
+		rowFilter = nil
+	})
+	for _, tc := range []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: nil,
+			want: []string{`p5g_exporter_amf_transaction_total_message{gran_period="900",init_name="2023-11-08 13:15:00",location="AMF_01"`},
+		},
+		{
+			args: []string{"-dry-run"},
+			want: []string{
+				"p5g_exporter_amf_transaction_missing        gauge    25            missing column 25",
+				"p5g_exporter_amf_transaction_total_message  counter  9 (TotalMsg)  2 series",
+			},
+		},
+	} {
+		metricConfig = Config{}
+		var out bytes.Buffer
+		args := append(append([]string{"-metricConfig", "testdata/convert_missing.yml"}, tc.args...), "testdata/oss/AMFTPS.csv")
+		if code := convert(&out, args); code != 0 {
+			t.Fatalf("convert %v: exit %d", tc.args, code)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("convert %v output:\n%s\nwant %q", tc.args, out.String(), want)
+			}
+		}
+		if strings.Contains(out.String(), "amf_transaction_missing{") {
+			t.Errorf("convert %v exported the missing column metric", tc.args)
+		}
+	}
+}
+
+// 라벨 컬럼이나 값 컬럼이 없는 행은 건너뛴다.
+func TestCommonCollectShortRows(t *testing.T) {
+	desc := prometheus.NewDesc("p5g_exporter_test", "test", defaultLabels, nil)
+	data := [][]string{
+		{"Family name : AMFTPS"},
+		{"Period : 15min"},
+		{"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "TPS_Avg", "TPS_Max", "TotalMsg"},
+		{"ne101", "1", "NE-101", "2023-11-08 13:15:00", "+09:00", "900", "AMF_01", "15", "40", "13500"},
+		{"ne102", "1", "NE-102", "2023-11-08 13:15:00", "+09:00", "900", "AMF_02", "10"},
+		{"ne103"},
+	}
+	ch := make(chan prometheus.Metric, 10)
+	if err := commonCollect(data, 9, "counter", desc, nil, ch, nil); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+	if len(ch) != 1 {
+		t.Errorf("metrics = %d, want 1", len(ch))
+	}
+}
@@ -0,0 +1,243 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/remotewrite"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emitter 한 행의 메트릭을 scrape, push 전송용(stamped), remote write 로 내보낸다.
+type emitter struct {
+	ch      chan<- prometheus.Metric
+	stamped *[]prometheus.Metric
+	series  *[]remotewrite.TimeSeries
+	now     time.Time
+}
+
+func (e *emitter) emit(metricName string, row []string, metric prometheus.Metric) {
+	e.ch <- metric
+	period := csv.ParsePeriod(row, e.now)
+	if e.stamped != nil {
+		*e.stamped = append(*e.stamped, prometheus.NewMetricWithTimestamp(period, metric))
+	}
+	if e.series != nil {
+		*e.series = append(*e.series, metricSeries(prometheus.BuildFQName("p5g_exporter", "", metricName), metric, period.UnixMilli())...)
+	}
+}
+
+// valueLabel info, stateset 메트릭의 값 라벨명 (그 외 타입은 "")
+func valueLabel(metric CnfMetric) string {
+	switch strings.ToLower(metric.Type) {
+	case "info":
+		if metric.Label != "" {
+			return metric.Label
+		}
+		return "value"
+	case "stateset":
+		if metric.Label != "" {
+			return metric.Label
+		}
+		return "state"
+	}
+	return ""
+}
+
+// validateMetric 메트릭 타입과 histogram, stateset 설정 확인
+// label 은 info, stateset 만 사용하며, summary 는 OSS CSV 에 분위수 컬럼이 없어 지원하지 않는다.
+func validateMetric(metric CnfMetric) error {
+	metricType := strings.ToLower(metric.Type)
+	switch metricType {
+	case "counter", "gauge", "histogram", "info", "stateset":
+	case "summary":
+		return fmt.Errorf("summary is not supported (OSS CSV has no quantile columns), use histogram with buckets")
+	default:
+		return fmt.Errorf("unsupported metric type %q (counter|gauge|histogram|info|stateset)", metric.Type)
+	}
+	if metric.Label != "" && metricType != "info" && metricType != "stateset" {
+		return fmt.Errorf("label is only used by info and stateset metrics, not %s", metricType)
+	}
+
+	switch metricType {
+	case "histogram":
+		if len(metric.Buckets) == 0 {
+			return fmt.Errorf("histogram needs buckets")
+		}
+		for i, bucket := range metric.Buckets {
+			// +Inf bucket 은 개수(COUNT_SEQUENCE 또는 구간 합계)로 자동 생성
+			if math.IsInf(bucket.Le, 0) || math.IsNaN(bucket.Le) {
+				return fmt.Errorf("histogram bucket le %v is not allowed, +Inf is the count (COUNT_SEQUENCE)", bucket.Le)
+			}
+			if i > 0 && bucket.Le <= metric.Buckets[i-1].Le {
+				return fmt.Errorf("histogram buckets must be in increasing le order")
+			}
+		}
+	case "stateset":
+		if len(metric.States) == 0 {
+			return fmt.Errorf("stateset needs states")
+		}
+	}
+	return nil
+}
+
+// distributionCollect histogram, info, stateset 메트릭을 CSV 행마다 만든다.
+// 값을 읽을 수 없는 행은 건너뛴다.
+func distributionCollect(csvData [][]string, metricName string, metric CnfMetric, siteLabels []string, e *emitter) {
+	for i := 3; i < len(csvData); i++ {
+		row := csvData[i]
+		if len(row) < 7 {
+			continue
+		}
+		labelVals := append([]string{}, row[:7]...)
+
+		switch strings.ToLower(metric.Type) {
+		case "histogram":
+			count, sum, buckets, err := histogramValues(row, metric)
+			if err != nil {
+				logger.LogErr(metricName+" histogram skipped", err)
+				continue
+			}
+			e.emit(metricName, row, prometheus.MustNewConstHistogram(metric.MetricDesc, count, sum, buckets, append(labelVals, siteLabels...)...))
+		case "info":
+			if metric.Value_Sequence >= len(row) {
+				continue
+			}
+			value := strings.TrimSpace(row[metric.Value_Sequence])
+			labels := append(append(labelVals, value), siteLabels...)
+			e.emit(metricName, row, prometheus.MustNewConstMetric(metric.MetricDesc, prometheus.GaugeValue, 1, labels...))
+		case "stateset":
+			if metric.Value_Sequence >= len(row) {
+				continue
+			}
+			current := strings.TrimSpace(row[metric.Value_Sequence])
+			for _, state := range metric.States {
+				val := 0.0
+				if strings.EqualFold(state, current) {
+					val = 1
+				}
+				labels := append(append(append([]string{}, labelVals...), state), siteLabels...)
+				e.emit(metricName, row, prometheus.MustNewConstMetric(metric.MetricDesc, prometheus.GaugeValue, val, labels...))
+			}
+		}
+	}
+}
+
+// histogramValues 구간 컬럼을 누적 bucket 으로 변환
+// 개수는 COUNT_SEQUENCE (없으면 구간 합계), 합계는 SUM_SEQUENCE 또는 AVG_SEQUENCE * 개수
+// 누적 bucket 이 줄어들거나 개수가 마지막 bucket 보다 작으면 잘못된 행으로 보고 오류를 반환한다.
+func histogramValues(row []string, metric CnfMetric) (uint64, float64, map[float64]uint64, error) {
+	buckets := map[float64]uint64{}
+	var cumulative float64
+	for _, bucket := range metric.Buckets {
+		val, err := cellValue(row, bucket.Column)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		previous := cumulative
+		if metric.Cumulative {
+			cumulative = val
+		} else {
+			cumulative += val
+		}
+		if cumulative < previous {
+			return 0, 0, nil, fmt.Errorf("bucket le %v (column %d) is not monotonic", bucket.Le, bucket.Column)
+		}
+		buckets[bucket.Le] = uint64(math.Round(cumulative))
+	}
+
+	count := cumulative
+	if metric.Count_Sequence > 0 {
+		val, err := cellValue(row, metric.Count_Sequence)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		if val < cumulative {
+			return 0, 0, nil, fmt.Errorf("count %v (column %d) is smaller than the last bucket %v", val, metric.Count_Sequence, cumulative)
+		}
+		count = val
+	}
+
+	var sum float64
+	switch {
+	case metric.Sum_Sequence > 0:
+		val, err := cellValue(row, metric.Sum_Sequence)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		sum = val
+	case metric.Avg_Sequence > 0:
+		val, err := cellValue(row, metric.Avg_Sequence)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		sum = val * count
+	}
+	return uint64(math.Round(count)), sum, buckets, nil
+}
+
+func cellValue(row []string, column int) (float64, error) {
+	if column >= len(row) {
+		return 0, fmt.Errorf("column %d is out of range", column)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(row[column]), 64)
+}
+
+// metricSeries 메트릭을 remote write 시계열로 변환 (histogram 은 _bucket, _sum, _count)
+func metricSeries(name string, metric prometheus.Metric, timestamp int64) []remotewrite.TimeSeries {
+	m := &dto.Metric{}
+	if err := metric.Write(m); err != nil {
+		return nil
+	}
+	labels := make([]remotewrite.Label, 0, len(m.GetLabel())+1)
+	for _, pair := range m.GetLabel() {
+		labels = append(labels, remotewrite.Label{Name: pair.GetName(), Value: pair.GetValue()})
+	}
+	series := func(name string, val float64, extra ...remotewrite.Label) remotewrite.TimeSeries {
+		ls := append([]remotewrite.Label{{Name: "__name__", Value: name}}, labels...)
+		return remotewrite.TimeSeries{
+			Labels:  append(ls, extra...),
+			Samples: []remotewrite.Sample{{Value: val, Timestamp: timestamp}},
+		}
+	}
+
+	switch {
+	case m.Histogram != nil:
+		h := m.GetHistogram()
+		var out []remotewrite.TimeSeries
+		bounds := h.GetBucket()
+		sort.Slice(bounds, func(i, j int) bool { return bounds[i].GetUpperBound() < bounds[j].GetUpperBound() })
+		for _, bucket := range bounds {
+			le := strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)
+			out = append(out, series(name+"_bucket", float64(bucket.GetCumulativeCount()), remotewrite.Label{Name: "le", Value: le}))
+		}
+		out = append(out,
+			series(name+"_bucket", float64(h.GetSampleCount()), remotewrite.Label{Name: "le", Value: "+Inf"}),
+			series(name+"_sum", h.GetSampleSum()),
+			series(name+"_count", float64(h.GetSampleCount())),
+		)
+		return out
+	case m.Counter != nil:
+		return []remotewrite.TimeSeries{series(name, m.GetCounter().GetValue())}
+	case m.Gauge != nil:
+		return []remotewrite.TimeSeries{series(name, m.GetGauge().GetValue())}
+	}
+	return nil
+}
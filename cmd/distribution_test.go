@@ -0,0 +1,306 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateMetric(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric CnfMetric
+		err    string
+	}{
+		{"counter", CnfMetric{Type: "counter"}, ""},
+		{"gauge upper case", CnfMetric{Type: "GAUGE"}, ""},
+		{"info label", CnfMetric{Type: "info", Label: "version"}, ""},
+		{"stateset label", CnfMetric{Type: "stateset", Label: "status", States: []string{"up", "down"}}, ""},
+		{"histogram", CnfMetric{Type: "histogram", Buckets: []Bucket{{Column: 8, Le: 10}, {Column: 9, Le: 50}}}, ""},
+		{"counter label", CnfMetric{Type: "counter", Label: "value"}, "label is only used by info and stateset"},
+		{"histogram label", CnfMetric{Type: "histogram", Label: "value", Buckets: []Bucket{{Column: 8, Le: 10}}}, "label is only used by info and stateset"},
+		{"summary", CnfMetric{Type: "summary"}, "summary is not supported"},
+		{"unknown", CnfMetric{Type: "untyped"}, `unsupported metric type "untyped"`},
+		{"empty", CnfMetric{}, `unsupported metric type ""`},
+		{"histogram without buckets", CnfMetric{Type: "histogram"}, "histogram needs buckets"},
+		{"histogram order", CnfMetric{Type: "histogram", Buckets: []Bucket{{Column: 8, Le: 50}, {Column: 9, Le: 10}}}, "increasing le order"},
+		{"histogram +Inf", CnfMetric{Type: "histogram", Buckets: []Bucket{{Column: 8, Le: 10}, {Column: 9, Le: math.Inf(1)}}}, "+Inf is the count"},
+		{"histogram -Inf", CnfMetric{Type: "histogram", Buckets: []Bucket{{Column: 8, Le: math.Inf(-1)}}}, "is not allowed"},
+		{"histogram NaN", CnfMetric{Type: "histogram", Buckets: []Bucket{{Column: 8, Le: math.NaN()}}}, "is not allowed"},
+		{"stateset without states", CnfMetric{Type: "stateset"}, "stateset needs states"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMetric(tt.metric)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValueLabel(t *testing.T) {
+	tests := []struct {
+		metric CnfMetric
+		want   string
+	}{
+		{CnfMetric{Type: "info"}, "value"},
+		{CnfMetric{Type: "info", Label: "version"}, "version"},
+		{CnfMetric{Type: "stateset"}, "state"},
+		{CnfMetric{Type: "stateset", Label: "status"}, "status"},
+		{CnfMetric{Type: "counter", Label: "value"}, ""},
+		{CnfMetric{Type: "histogram", Label: "value"}, ""},
+	}
+	for _, tt := range tests {
+		if got := valueLabel(tt.metric); got != tt.want {
+			t.Errorf("valueLabel(%s, %q) = %q, want %q", tt.metric.Type, tt.metric.Label, got, tt.want)
+		}
+	}
+}
+
+// 컬럼 7~ : 구간 3개, 합계, 평균, 개수
+func distributionRow(location string, values ...string) []string {
+	return append([]string{"ne101", "1", "NE-101", "2023-11-08 13:15:00", "+09:00", "900", location}, values...)
+}
+
+func TestHistogramValues(t *testing.T) {
+	buckets := []Bucket{{Column: 7, Le: 10}, {Column: 8, Le: 50}, {Column: 9, Le: 100}}
+	tests := []struct {
+		name    string
+		metric  CnfMetric
+		row     []string
+		count   uint64
+		sum     float64
+		buckets map[float64]uint64
+		err     string
+	}{
+		{
+			name:    "interval buckets accumulate",
+			metric:  CnfMetric{Buckets: buckets},
+			row:     distributionRow("AMF_01", "3", "5", "2"),
+			count:   10,
+			buckets: map[float64]uint64{10: 3, 50: 8, 100: 10},
+		},
+		{
+			name:    "cumulative buckets",
+			metric:  CnfMetric{Buckets: buckets, Cumulative: true},
+			row:     distributionRow("AMF_01", "3", "8", "10"),
+			count:   10,
+			buckets: map[float64]uint64{10: 3, 50: 8, 100: 10},
+		},
+		{
+			name:    "sum and count columns",
+			metric:  CnfMetric{Buckets: buckets, Sum_Sequence: 10, Count_Sequence: 12},
+			row:     distributionRow("AMF_01", "3", "5", "2", "412.5", "30", "12"),
+			count:   12,
+			sum:     412.5,
+			buckets: map[float64]uint64{10: 3, 50: 8, 100: 10},
+		},
+		{
+			name:    "avg times count",
+			metric:  CnfMetric{Buckets: buckets, Avg_Sequence: 11, Count_Sequence: 12},
+			row:     distributionRow("AMF_01", "3", "5", "2", "412.5", "30", "12"),
+			count:   12,
+			sum:     360,
+			buckets: map[float64]uint64{10: 3, 50: 8, 100: 10},
+		},
+		{
+			name:    "avg times bucket total",
+			metric:  CnfMetric{Buckets: buckets, Avg_Sequence: 11},
+			row:     distributionRow("AMF_01", "3", "5", "2", "412.5", "30"),
+			count:   10,
+			sum:     300,
+			buckets: map[float64]uint64{10: 3, 50: 8, 100: 10},
+		},
+		{
+			name:    "count equals last bucket",
+			metric:  CnfMetric{Buckets: buckets, Count_Sequence: 10},
+			row:     distributionRow("AMF_01", "3", "5", "2", "10"),
+			count:   10,
+			buckets: map[float64]uint64{10: 3, 50: 8, 100: 10},
+		},
+		{
+			name:   "count below last bucket",
+			metric: CnfMetric{Buckets: buckets, Count_Sequence: 10},
+			row:    distributionRow("AMF_01", "3", "5", "2", "9"),
+			err:    "smaller than the last bucket",
+		},
+		{
+			name:   "cumulative decreasing",
+			metric: CnfMetric{Buckets: buckets, Cumulative: true},
+			row:    distributionRow("AMF_01", "3", "8", "7"),
+			err:    "le 100 (column 9) is not monotonic",
+		},
+		{
+			name:   "negative interval",
+			metric: CnfMetric{Buckets: buckets},
+			row:    distributionRow("AMF_01", "3", "-1", "2"),
+			err:    "le 50 (column 8) is not monotonic",
+		},
+		{
+			name:   "negative first bucket",
+			metric: CnfMetric{Buckets: buckets, Cumulative: true},
+			row:    distributionRow("AMF_01", "-3", "8", "10"),
+			err:    "le 10 (column 7) is not monotonic",
+		},
+		{
+			name:   "empty bucket",
+			metric: CnfMetric{Buckets: buckets},
+			row:    distributionRow("AMF_01", "3", "", "2"),
+			err:    "invalid syntax",
+		},
+		{
+			name:   "missing count column",
+			metric: CnfMetric{Buckets: buckets, Count_Sequence: 12},
+			row:    distributionRow("AMF_01", "3", "5", "2"),
+			err:    "column 12 is out of range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, sum, got, err := histogramValues(tt.row, tt.metric)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.count || sum != tt.sum || !reflect.DeepEqual(got, tt.buckets) {
+				t.Errorf("histogramValues = %d, %v, %v, want %d, %v, %v", count, sum, got, tt.count, tt.sum, tt.buckets)
+			}
+		})
+	}
+}
+
+// render location 과 값 라벨, 값 (histogram 은 count/sum/bucket)
+func render(t *testing.T, metric prometheus.Metric) string {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := metric.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, pair := range m.GetLabel() {
+		switch pair.GetName() {
+		case "ne_id", "system_id", "ne_name", "init_name", "time_offset", "gran_period":
+			continue
+		}
+		labels = append(labels, pair.GetName()+"="+pair.GetValue())
+	}
+	out := strings.Join(labels, ",")
+	if h := m.GetHistogram(); h != nil {
+		out += fmt.Sprintf(" count=%d sum=%v", h.GetSampleCount(), h.GetSampleSum())
+		for _, bucket := range h.GetBucket() {
+			out += fmt.Sprintf(" le%v=%d", bucket.GetUpperBound(), bucket.GetCumulativeCount())
+		}
+		return out
+	}
+	return out + fmt.Sprintf(" %v", m.GetGauge().GetValue())
+}
+
+func TestDistributionCollect(t *testing.T) {
+	data := [][]string{
+		{"Family name : AMFTPS"},
+		{"Period : 15min"},
+		{"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "B10", "B50", "B100", "SUM", "COUNT"},
+		distributionRow("AMF_01", "3", "5", "2", "412.5", "12"),
+		// 개수가 마지막 bucket 보다 작은 행은 제외
+		distributionRow("AMF_02", "3", "5", "2", "100", "9"),
+		distributionRow("AMF_03", "1", "0", "0", "2", "1"),
+		{"short"},
+		distributionRow("AMF_04", "up"),
+	}
+	tests := []struct {
+		name   string
+		metric CnfMetric
+		want   []string
+	}{
+		{
+			name:   "histogram",
+			metric: CnfMetric{Type: "histogram", Buckets: []Bucket{{Column: 7, Le: 10}, {Column: 8, Le: 50}, {Column: 9, Le: 100}}, Sum_Sequence: 10, Count_Sequence: 11},
+			want: []string{
+				"location=AMF_01 count=12 sum=412.5 le10=3 le50=8 le100=10",
+				"location=AMF_03 count=1 sum=2 le10=1 le50=1 le100=1",
+			},
+		},
+		{
+			name:   "info",
+			metric: CnfMetric{Type: "info", Label: "version", Value_Sequence: 7},
+			want: []string{
+				"location=AMF_01,version=3 1",
+				"location=AMF_02,version=3 1",
+				"location=AMF_03,version=1 1",
+				"location=AMF_04,version=up 1",
+			},
+		},
+		{
+			name:   "stateset",
+			metric: CnfMetric{Type: "stateset", Value_Sequence: 7, States: []string{"UP", "3"}},
+			want: []string{
+				"location=AMF_01,state=3 1",
+				"location=AMF_01,state=UP 0",
+				"location=AMF_02,state=3 1",
+				"location=AMF_02,state=UP 0",
+				"location=AMF_03,state=3 0",
+				"location=AMF_03,state=UP 0",
+				"location=AMF_04,state=3 0",
+				"location=AMF_04,state=UP 1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMetric(tt.metric); err != nil {
+				t.Fatal(err)
+			}
+			labels := append([]string{}, defaultLabels...)
+			if label := valueLabel(tt.metric); label != "" {
+				labels = append(labels, label)
+			}
+			tt.metric.MetricDesc = prometheus.NewDesc("p5g_exporter_test", "test", labels, nil)
+
+			ch := make(chan prometheus.Metric, 100)
+			var stamped []prometheus.Metric
+			distributionCollect(data, "test", tt.metric, nil, &emitter{ch: ch, stamped: &stamped, now: time.Now()})
+			close(ch)
+
+			var got []string
+			for metric := range ch {
+				got = append(got, render(t, metric))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metrics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if len(stamped) != len(tt.want) {
+				t.Errorf("stamped = %d, want %d", len(stamped), len(tt.want))
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -21,100 +22,520 @@ import (
 	"github.com/prometheus/common/version"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
-	"io"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/exporter"
 
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/curl"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/derived"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/discovery"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/filter"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/fixture"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/health"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/history"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/journal"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/k8sClient"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/kubestate"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/leader"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/metricApi"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/otlp"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/publish"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/remotewrite"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/retention"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/sink"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/snapshot"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/store"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/utils"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/web"
+	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Config struct {
-	Metrics map[string]struct {
-		Description    string
-		Type           string
-		Labels         []string
-		Value          string
-		Value_Sequence int
-		MetricDesc     *prometheus.Desc
+	Metrics map[string]CnfMetric
+	// 수집시 행 필터 (location, ne_id 등 라벨 조건, 값이 모두 0 인 행)
+	Filters []filter.Rule
+	// 같은 행(또는 join 한 다른 Family 행)의 컬럼으로 계산하는 gauge
+	Derived map[string]struct {
+		Description string // 기준 FamilyName
+		Help        string
+		Expr        string // col(9) / col("Family", 7) * 100
+		Join        string // 다른 Family 행을 찾는 컬럼 (location or ne_id, period 는 항상 비교)
+		Labels      []string
+		Parsed      *derived.Expr    `yaml:"-"`
+		JoinColumn  int              `yaml:"-"`
+		MetricDesc  *prometheus.Desc `yaml:"-"`
 	}
 }
 
+// CnfMetric cnf_config.yml 의 메트릭 (counter, gauge, histogram, info, stateset)
+type CnfMetric struct {
+	Description    string
+	Type           string
+	Labels         []string
+	Value          string
+	Value_Sequence int
+	Delta          bool
+	// histogram: 구간 컬럼과 상한(le), 합계/평균/개수 컬럼
+	Buckets        []Bucket
+	Cumulative     bool // 구간 컬럼이 이미 누적값인 경우
+	Sum_Sequence   int
+	Avg_Sequence   int // SUM_SEQUENCE 가 없으면 평균 * 개수를 합계로 사용
+	Count_Sequence int // 없으면 구간 합계
+	// info, stateset: VALUE_SEQUENCE 컬럼 값을 담는 라벨명 (기본 info: value, stateset: state)
+	Label      string
+	States     []string // stateset 상태 목록
+	MetricDesc *prometheus.Desc
+	DeltaDesc  *prometheus.Desc
+}
+
+// Bucket histogram 구간 컬럼
+type Bucket struct {
+	Column int
+	Le     float64
+}
+
+// CSV 라벨 컬럼 0~6 의 기본 라벨명
+var defaultLabels = []string{"ne_id", "system_id", "ne_name", "init_name", "time_offset", "gran_period", "location"}
+
 var metricConfig Config
 var collectors map[string]*exporter.Collector
 
+// cnf_config.yml filters (설정이 없으면 nil)
+var rowFilter *filter.Filter
+
+// 사이트별 수집 데이터 저장소 (STORE.ENABLE 시 사용, 단일 수집은 "")
+var stores = map[string]*store.Store{}
+
+// SITES 설정시 CNF 메트릭에 site 라벨을 붙인다.
+var multiSite bool
+
+// remote write 전송 (REMOTE_WRITE.ENABLE 시 사용)
+var remoteWriter *remotewrite.Sender
+
+// OTLP 전송 (OTLP.ENABLE 시 사용)
+var otlpExporter *otlp.Exporter
+var publisher *publish.Publisher
+var sinkScheduler *sink.Scheduler
+var lifecycleManager *lifecycle.Manager
+
+// Lease leader 선출 (사용하지 않으면 nil, 항상 leader)
+var elector *leader.Elector
+
+// p5g_mec Thanos token (nil 이면 MEC_CONFIG 로 생성)
+var tokenSource exporter.TokenSource
+var healthChecker *health.Checker
+
+// 마지막 수집 주기의 CNF 메트릭 (period timestamp 포함, push 전송용)
+var cnfSnapshot = &exporter.Snapshot{}
+var cnfSnapshotRegistry = prometheus.NewRegistry()
+
 func main() {
 	var err error
 	var configFile string
 	var deviceConfig string
+	var listenAddress string
+	var webConfigFile string
+
+	// 서브커맨드 실행 (exporter history ...)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "convert":
+			os.Exit(runConvert(os.Args[2:]))
+		case "generate-config":
+			os.Exit(runGenerateConfig(os.Args[2:]))
+		}
+	}
+
 	// =====================
 	// Get OS parameter
 	// =====================
 
 	flag.StringVar(&configFile, "metricConfig", "cnf_config.yml", "configuration file")
 	flag.StringVar(&deviceConfig, "config-metrics", "app_config.yml", "configuration metrics")
+	flag.StringVar(&listenAddress, "web.listen-address", "", "listen address (default server.LISTEN_ADDRESS)")
+	flag.StringVar(&webConfigFile, "web.config.file", "", "exporter-toolkit web config file for TLS and authentication (default server.WEB_CONFIG_FILE)")
 
 	flag.Parse()
 
-	var b []byte
-	if b, err = os.ReadFile(configFile); err != nil {
-		logger.LogErr("Failed to read metricConfig file: ", err)
+	if err := loadMetricConfig(configFile, deviceConfig); err != nil {
+		logger.LogErr("Failed to load metric config: ", err)
 		os.Exit(1)
 	}
 
-	// Load yaml
-	if err := yaml.Unmarshal(b, &metricConfig); err != nil {
-		logger.LogErr("Failed to load metricConfig: ", err)
+	ymlConfig := cfg.InitConfig()
+	if err := ymlConfig.ValidateSites(); err != nil {
+		logger.LogErr("Invalid sites config: ", err)
 		os.Exit(1)
 	}
+	multiSite = ymlConfig.MultiSite()
+	siteConfigs := ymlConfig.SiteConfigs()
 
-	if b, err = os.ReadFile(deviceConfig); err != nil {
-		logger.LogErr("Failed to read deviceConfig file: ", err)
+	// fixture 기록/재생
+	if err := setupFixture(ymlConfig.Fixture); err != nil {
+		logger.LogErr("Failed to set up fixture: ", err)
 		os.Exit(1)
 	}
+	replay := strings.EqualFold(ymlConfig.Fixture.Mode, "replay")
 
-	if err := yaml.Unmarshal(b, &collectors); err != nil {
-		logger.LogErr("Failed to load deviceConfig: ", err)
+	// 종료 신호 처리 (진행중인 수집 대기 후 종료)
+	lifecycleManager = lifecycle.NewManager(time.Duration(ymlConfig.Server.ShutdownTimeout) * time.Second)
+
+	// readiness: config 로드, k8s client, OSS 계정, 마지막 수집 시간
+	healthChecker = health.NewChecker(
+		time.Duration(ymlConfig.Server.HealthCheckInterval)*time.Second,
+		lifecycleManager.ShuttingDown,
+	)
+	healthChecker.Set("config", nil)
+	for _, siteConfig := range siteConfigs {
+		// 재생시 k8s, OSS 접속 없음
+		if replay {
+			break
+		}
+		site := siteConfig.Site
+		suffix := ""
+		if multiSite {
+			suffix = ":" + site
+		}
+		healthChecker.AddCheck("k8s"+suffix, func(ctx context.Context) error {
+			siteConfig, _ := cfg.InitConfig().SiteConfig(site)
+			return k8sClient.Ping(ctx, siteConfig.Exporter.Namespace, siteConfig.Exporter.Pod)
+		})
+		healthChecker.AddCheck("oss"+suffix, func(ctx context.Context) error {
+			siteConfig, _ := cfg.InitConfig().SiteConfig(site)
+			return curl.CheckCredentials(ctx, siteConfig)
+		})
+	}
+	healthChecker.Start(lifecycleManager.Stopping())
+
+	// OTLP 전송
+	cnfSnapshotRegistry.MustRegister(cnfSnapshot)
+	if ymlConfig.Otlp.Enable {
+		otlpExporter, err = otlp.NewExporter(ymlConfig.Otlp)
+		if err != nil {
+			logger.LogErr("Failed to create otlp exporter: ", err)
+			os.Exit(1)
+		}
+	}
+
+	// pushgateway, textfile 내보내기
+	if ymlConfig.Sink.Enable {
+		sinkScheduler = sink.NewScheduler(lifecycleManager.Stopping(), ymlConfig.Sink)
+	}
+
+	// 수신 주소, TLS, 인증 (flag 가 config 보다 우선)
+	if listenAddress == "" {
+		listenAddress = ymlConfig.Server.ListenAddress
+	}
+	if webConfigFile == "" {
+		webConfigFile = ymlConfig.Server.WebConfigFile
+	}
+	webServer, err := web.NewServer(webConfigFile)
+	if err != nil {
+		logger.LogErr("Failed to load web config: ", err)
 		os.Exit(1)
 	}
 
+	router := gin.Default()
+	router.Use(webServer.Headers())
+
+	/*
+		APP Exporter
+	*/
+	// 재생시 k8s 접속 없이 url 사용
+	if !replay {
+		startDiscovery(lifecycleManager.Stopping())
+	}
+	apps := appRegistries()
+
+	// 네임스페이스 pod 상태 (재생시 k8s 접속 없음)
+	if ymlConfig.KubeState.Enable && !replay {
+		kubeState, err := kubestate.NewCollector(lifecycleManager.Stopping(), ymlConfig.KubeState)
+		if err != nil {
+			logger.LogErr("Failed to start kube state collector: ", err)
+			os.Exit(1)
+		}
+		path := strings.Trim(ymlConfig.KubeState.Path, "/")
+		if _, ok := apps[path]; ok || path == "metrics" {
+			logger.LogErr("Failed to start kube state collector: ", fmt.Errorf("kube_state.PATH %q is already used", path))
+			os.Exit(1)
+		}
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(kubeState)
+		apps[path] = registry
+	}
+	for path, registry := range apps {
+		if otlpExporter != nil && otlpExporter.Enabled(path) {
+			otlpExporter.Start(lifecycleManager.Stopping(), path, registry)
+		}
+		if sinkScheduler != nil && sinkScheduler.Add(path, nil) {
+			sinkScheduler.Start(path, registry)
+		}
+	}
+
+	/*
+		Prometheus에 Metric Data 보내기
+	*/
+	cnf := newCnfRegistry()
+
+	// replica 여러 개 운영시 leader 만 OSS 수집 (재생시 k8s 접속 없음)
+	if ymlConfig.LeaderElection.Enable && !replay {
+		elector, err = leader.Start(ymlConfig.LeaderElection)
+		if err != nil {
+			logger.LogErr("Failed to start leader election: ", err)
+			os.Exit(1)
+		}
+		cnf.MustRegister(elector)
+		// 정리 작업이 끝난 뒤 Lease 반납 (hook 은 등록 역순 실행)
+		lifecycleManager.OnShutdown(elector.Stop)
+	}
+
+	// 수집 데이터 저장소 복구 (사이트별 STORE_PATH/<site>)
+	if ymlConfig.Store.Enable {
+		for _, siteConfig := range siteConfigs {
+			tsdb, err := store.Open(siteConfig.Store.Path, time.Duration(ymlConfig.Store.Retention)*time.Hour)
+			if err != nil {
+				logger.LogErr("Failed to open store: "+siteConfig.Store.Path, err)
+				os.Exit(1)
+			}
+			stores[siteConfig.Site] = tsdb
+			metricApi.SetStore(siteConfig.Site, tsdb)
+		}
+	}
+
+	// 수집 상태 journal (사이트별 CSV_PATH/.journal.json)
+	if ymlConfig.Journal.Enable {
+		for _, siteConfig := range siteConfigs {
+			j, err := journal.Open(siteConfig.File.CSV_Path, siteConfig.Site, ymlConfig.Journal.Keep)
+			if err != nil {
+				logger.LogErr("Failed to open journal: "+siteConfig.File.CSV_Path, err)
+				os.Exit(1)
+			}
+			journals[siteConfig.Site] = j
+			cnf.Register(j)
+		}
+	}
+
+	// OSS 데이터 remote write 전송
+	if ymlConfig.RemoteWrite.Enable {
+		remoteWriter, err = remotewrite.NewSender(ymlConfig.RemoteWrite)
+		if err != nil {
+			logger.LogErr("Failed to create remote write sender: ", err)
+			os.Exit(1)
+		}
+		cnf.Register(remoteWriter)
+		remoteWriter.Start(lifecycleManager.Stopping())
+	}
+
+	// OSS 행 데이터 메시지 버스 전송
+	if ymlConfig.Publish.Enable {
+		bus, err := publish.NewBus(ymlConfig.Publish)
+		if err != nil {
+			logger.LogErr("Failed to create message bus: ", err)
+			os.Exit(1)
+		}
+		publisher, err = publish.NewPublisher(ymlConfig.Publish, bus)
+		if err != nil {
+			logger.LogErr("Failed to create publisher: ", err)
+			os.Exit(1)
+		}
+		cnf.Register(publisher)
+		publisher.Start(lifecycleManager.Stopping())
+	}
+
+	// 백업 폴더 보관 정책 (백그라운드 실행)
+	if ymlConfig.Retention.Enable {
+		for _, siteConfig := range siteConfigs {
+			manager := retention.NewManager(siteConfig.File.CSV_Path, siteConfig.Site, ymlConfig.Retention)
+			manager.Leading = elector.Leading
+			cnf.Register(manager)
+			manager.Start(lifecycleManager.Stopping())
+		}
+	}
+
+	// sink 는 OSS 수집을 다시 하지 않고 마지막 /metrics 수집 결과를 내보낸다.
+	// INTERVAL 동안 scrape 가 없으면(scrape 불가 사이트) 직접 수집한다.
+	cnfCache := sink.NewCache(cnf)
+	if sinkScheduler != nil && sinkScheduler.Add("metrics", cnfGroup) {
+		sinkScheduler.Start("metrics", cnfCache.Recent(sinkScheduler.Interval()))
+		// scrape 가 없어도 sink 가 주기적으로 수집하므로 마지막 수집 시간을 readiness 에 반영
+		healthChecker.SetStaleness(time.Duration(ymlConfig.Server.ReadyStaleness) * time.Second)
+	} else if ymlConfig.Server.ReadyStaleness > 0 {
+		logger.LogWarn("READY_STALENESS 는 sink 가 metrics 를 주기 수집할 때만 사용합니다.")
+	}
+
+	routes(router, webServer, apps, cnfCache)
+
+	// 종료시 CSV_PATH 에 남은 수집중 파일 정리 (공유 저장소이므로 leader 만)
+	lifecycleManager.OnShutdown(func() {
+		if !elector.Leading() {
+			return
+		}
+		for _, siteConfig := range siteConfigs {
+			// journal 사용시 다음 시작시 checksum 확인 후 이어서 받는다.
+			if journals[siteConfig.Site] == nil {
+				curl.RemovePartial(siteConfig)
+			}
+		}
+	})
+
+	server := &http.Server{Addr: listenAddress, Handler: router}
+	if err := lifecycleManager.Run(server, func() error { return webServer.ListenAndServe(server) }); err != nil {
+		logger.LogErr("Failed to run server: ", err)
+		os.Exit(1)
+	}
+
+}
+
+// loadMetricConfig cnf_config.yml(CNF 메트릭, 필터, 계산식)과 app_config.yml 로드 및 검증 (deviceConfig 가 "" 이면 cnf_config.yml 만)
+func loadMetricConfig(configFile, deviceConfig string) error {
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		return errors.Wrap(err, configFile)
+	}
+	if err := yaml.Unmarshal(b, &metricConfig); err != nil {
+		return errors.Wrap(err, configFile)
+	}
+
+	for metricName, metric := range metricConfig.Metrics {
+		if err := validateMetric(metric); err != nil {
+			return errors.Wrap(err, metricName)
+		}
+	}
+
+	if len(metricConfig.Filters) > 0 {
+		if rowFilter, err = filter.New(metricConfig.Filters); err != nil {
+			return err
+		}
+		metricApi.SetFilter(rowFilter)
+	}
+
+	// 계산식 검증
+	for metricName, def := range metricConfig.Derived {
+		if def.Parsed, err = derived.Parse(def.Expr); err != nil {
+			return errors.Wrap(err, metricName)
+		}
+		if def.JoinColumn, err = derived.JoinColumn(def.Join); err != nil {
+			return errors.Wrap(err, metricName)
+		}
+		if len(def.Labels) == 0 {
+			def.Labels = defaultLabels
+		}
+		if len(def.Labels) != len(defaultLabels) {
+			return fmt.Errorf("%s: labels must name the %d label columns", metricName, len(defaultLabels))
+		}
+		metricConfig.Derived[metricName] = def
+	}
+
+	// convert CLI 는 app_config 없이 사용
+	if deviceConfig == "" {
+		return nil
+	}
+	if b, err = os.ReadFile(deviceConfig); err != nil {
+		return errors.Wrap(err, deviceConfig)
+	}
+	if err := yaml.Unmarshal(b, &collectors); err != nil {
+		return errors.Wrap(err, deviceConfig)
+	}
+	return nil
+}
+
+// setupFixture FIXTURE.MODE 에 따라 OSS 요청, pod 파일 복사, Thanos 요청을 기록하거나 bundle 로 대신한다.
+func setupFixture(config cfg.Fixture) error {
+	switch strings.ToLower(config.Mode) {
+	case "":
+		return nil
+	case "record":
+		recorder, err := fixture.NewRecorder(config.Dir, config.Anonymize)
+		if err != nil {
+			return err
+		}
+		curl.SetClient(recorder.Client(curl.HTTPClient{}))
+		curl.SetRetriever(recorder.Retriever(nil))
+		exporter.SetTransport(recorder.Transport(exporter.NewTransport()))
+		logger.LogInfo("fixture record : " + config.Dir)
+	case "replay":
+		replayer, err := fixture.Open(config.Dir)
+		if err != nil {
+			return err
+		}
+		curl.SetClient(replayer)
+		curl.SetRetriever(replayer)
+		curl.SetCopyDelay(0)
+		exporter.SetTransport(replayer)
+		tokenSource = exporter.StaticToken("")
+		logger.LogInfo("fixture replay : " + config.Dir)
+	default:
+		return fmt.Errorf("unsupported FIXTURE.MODE %q (record|replay)", config.Mode)
+	}
+	return nil
+}
+
+// appRegistries app_config 경로별 registry
+func appRegistries() map[string]*prometheus.Registry {
 	statusDesc := prometheus.NewDesc(
 		prometheus.BuildFQName("cnf_exporter", "", "status"),
 		"cnf_exporter collect status",
 		[]string{"instance"}, nil,
 	)
 
-	router := gin.Default()
-
-	/*
-		APP Exporter
-	*/
+	registries := map[string]*prometheus.Registry{}
 	for path, collector := range collectors {
 		logger.LogInfo("path : " + path)
 
-		for i := range collector.Collects {
-			collect := &collector.Collects[i]
+		// const_labels, rename_to, merge 반영
+		collector.BuildDescriptors()
+
+		registry := prometheus.NewRegistry()
+		registry.Register(&exporter.DeviceCollector{Collects: collector.Collects, StatusDesc: statusDesc, Context: lifecycleManager.Context(), Tokens: tokenSource})
+		registries[path] = registry
+	}
+	return registries
+}
 
-			for metricKey, metric := range collect.Metrics {
-				metric.MetricDesc = prometheus.NewDesc(
-					prometheus.BuildFQName(metric.Prefix, "", metricKey),
-					metric.Description,
-					metric.Labels, nil,
-				)
+// startDiscovery app_config 메트릭의 discovery 대상 감시 (찾기 전이나 실패시 url 사용)
+func startDiscovery(ctx context.Context) {
+	manager := discovery.NewManager()
+	watching := false
+	for path, collector := range collectors {
+		for _, collect := range collector.Collects {
+			for key, metric := range collect.Metrics {
+				if metric.Discovery == nil {
+					continue
+				}
+				if err := manager.Watch(ctx, *metric.Discovery); err != nil {
+					logger.LogErr(path+" "+key+" discovery, using url", err)
+					continue
+				}
+				watching = true
 			}
 		}
+	}
+	if watching {
+		exporter.SetDiscovery(manager)
+	}
+}
 
-		registry := prometheus.NewRegistry()
-		registry.Register(&exporter.DeviceCollector{Collects: collector.Collects, StatusDesc: statusDesc})
+// newCnfRegistry OSS CSV 로 만드는 CNF 메트릭 registry (/metrics)
+func newCnfRegistry() *prometheus.Registry {
+	cnf := prometheus.NewRegistry()
+	cnf.Register(version.NewCollector("cnf_exporter"))
+	cnf.Register(&CnfCollector{})
+	return cnf
+}
 
-		router.GET("/"+path, gin.WrapH(
+// routes app 경로, /metrics, API, health 경로 등록
+func routes(router *gin.Engine, webServer *web.Server, apps map[string]*prometheus.Registry, cnf prometheus.Gatherer) {
+	for path, registry := range apps {
+		router.GET("/"+path, webServer.Auth("metrics"), gin.WrapH(
 			promhttp.HandlerFor(prometheus.Gatherers{
 				registry,
 			},
@@ -122,22 +543,31 @@ func main() {
 		)
 	}
 
-	/*
-		Prometheus에 Metric Data 보내기
-	*/
-	cnf := prometheus.NewRegistry()
-	cnf.Register(version.NewCollector("cnf_exporter"))
-	cnf.Register(&CnfCollector{})
-
-	router.GET("/api/metrics", metricApi.CnfMetricHandler())
+	router.GET("/-/healthy", webServer.Auth("health"), health.HealthyHandler(healthChecker))
+	router.GET("/-/ready", webServer.Auth("health"), health.ReadyHandler(healthChecker))
+	router.GET("/api/metrics", webServer.Auth("api"), metricApi.CnfMetricHandler())
+	router.GET("/api/history", webServer.Auth("api"), history.HistoryHandler())
 
-	router.GET("/metrics", gin.WrapH(
+	router.GET("/metrics", webServer.Auth("metrics"), gin.WrapH(
 		promhttp.HandlerFor(prometheus.Gatherers{cnf},
 			promhttp.HandlerOpts{})),
 	)
+}
 
-	router.Run(":8080")
-
+// cnfGroup pushgateway grouping key 로 쓸 OSS Family (exporter 자체 메트릭은 "exporter")
+func cnfGroup(metricName string) string {
+	name := strings.TrimPrefix(metricName, "p5g_exporter_")
+	metric, ok := metricConfig.Metrics[name]
+	if !ok {
+		metric, ok = metricConfig.Metrics[strings.TrimSuffix(name, "_delta")]
+	}
+	if def, found := metricConfig.Derived[name]; !ok && found {
+		return strings.ReplaceAll(def.Description, " ", "_")
+	}
+	if !ok {
+		return "exporter"
+	}
+	return strings.ReplaceAll(metric.Description, " ", "_")
 }
 
 type CnfCollector struct{}
@@ -146,40 +576,240 @@ type CnfCollector struct{}
 // 메트릭에 사용하는 스펙정의
 func (c *CnfCollector) Describe(ch chan<- *prometheus.Desc) {
 	for metricName, metric := range metricConfig.Metrics {
+		labels := append([]string{}, metric.Labels...)
+		if label := valueLabel(metric); label != "" {
+			labels = append(labels, label)
+		}
+		if multiSite {
+			labels = append(labels, "site")
+		}
 		metric.MetricDesc = prometheus.NewDesc(
 			prometheus.BuildFQName("p5g_exporter", "", metricName),
 			metric.Description,
 			//라벨명 배열, 이 순서로 라벨값들이 추후 맵핑되어야 함
-			metric.Labels,
+			labels,
 			nil,
 		)
+		if metric.Delta {
+			// 직전 period 대비 변화량
+			metric.DeltaDesc = prometheus.NewDesc(
+				prometheus.BuildFQName("p5g_exporter", "", metricName+"_delta"),
+				metric.Description+" delta from previous period",
+				labels,
+				nil,
+			)
+		}
 		metricConfig.Metrics[metricName] = metric
 		logger.LogInfo("Metric description register : "+metricName, zap.String("MetricName", metricName))
 
 	}
+	for metricName, def := range metricConfig.Derived {
+		labels := def.Labels
+		if multiSite {
+			labels = append(append([]string{}, def.Labels...), "site")
+		}
+		help := def.Help
+		if help == "" {
+			help = def.Description + " " + def.Expr
+		}
+		def.MetricDesc = prometheus.NewDesc(prometheus.BuildFQName("p5g_exporter", "", metricName), help, labels, nil)
+		metricConfig.Derived[metricName] = def
+		logger.LogInfo("Derived metric description register : "+metricName, zap.String("MetricName", metricName))
+	}
+	if rowFilter != nil {
+		rowFilter.Describe(ch)
+	}
 }
 
 // Collect prometheus collect
+// SITES 설정시 사이트마다 독립적으로 수집하며, 한 사이트의 실패가 다른 사이트를 막지 않는다.
 func (c *CnfCollector) Collect(ch chan<- prometheus.Metric) {
+	// 종료중이면 새 수집을 시작하지 않는다.
+	ctx, done, ok := lifecycleManager.BeginCycle()
+	defer done()
+	if !ok {
+		logger.LogWarn("shutting down, collection skipped")
+		return
+	}
+
 	ymlConfig := cfg.InitConfig()
 	start, end := utils.IntervalTime()
 	//YYYY-MM-DD 년-월-일로 폴더 생성
 	foldername := start[:10]
 	//hhmm-hhmm 시간분-시간분 으로 폴더생성
 	backupTime := fmt.Sprintf("%s%s-%s%s", start[11:13], start[14:16], end[11:13], end[14:16])
+
+	// follower 는 OSS 수집, 백업, 전송 없이 leader 의 마지막 수집 데이터만 내보낸다.
+	leading := elector.Leading()
+
+	// push 전송(OTLP) 대상이면 period timestamp 를 붙인 메트릭을 함께 모은다.
+	pushCnf := leading && otlpExporter != nil && otlpExporter.Enabled("metrics")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var series []remotewrite.TimeSeries
+	var stamped []prometheus.Metric
+	var failed []string
+	for _, siteConfig := range ymlConfig.SiteConfigs() {
+		wg.Add(1)
+		go func(siteConfig cfg.Config) {
+			defer wg.Done()
+			siteSeries, siteStamped, err := collectSite(ctx, siteConfig, leading, start, end, foldername, backupTime, ch, pushCnf)
+
+			mu.Lock()
+			defer mu.Unlock()
+			series = append(series, siteSeries...)
+			stamped = append(stamped, siteStamped...)
+			if err != nil {
+				if multiSite {
+					err = errors.Wrap(err, siteConfig.Site)
+				}
+				failed = append(failed, err.Error())
+			}
+		}(siteConfig)
+	}
+	wg.Wait()
+
+	var err error
+	if len(failed) > 0 {
+		err = errors.New(strings.Join(failed, "; "))
+	}
+	healthChecker.CycleFinished(err)
+	// 필터로 제외한 행 수는 이번 수집 주기까지 반영해 내보낸다.
+	if rowFilter != nil {
+		rowFilter.Collect(ch)
+	}
+	// 종료로 중단된 수집과 follower, 수집 중 leader 를 잃은 경우는 내보내지 않는다.
+	if ctx.Err() != nil || !leading || !elector.Leading() {
+		return
+	}
+
+	if remoteWriter != nil && len(series) > 0 {
+		if err := remoteWriter.Enqueue(series); err != nil {
+			logger.LogErr("remote write enqueue failed", err)
+		}
+	}
+
+	if pushCnf {
+		cnfSnapshot.Set(stamped)
+		go func() {
+			if err := otlpExporter.Push("metrics", cnfSnapshotRegistry); err != nil {
+				logger.LogErr("otlp push failed : metrics", err)
+			}
+		}()
+	}
+}
+
+// collectSite 사이트 하나의 OSS 수집, 메트릭 생성, API 파일 복사, 백업
+// remote write 시계열과 push 전송용 메트릭, ExporterCurl 오류를 반환한다.
+// leader 가 아니면 leader 가 남긴 데이터로 메트릭만 만든다.
+func collectSite(ctx context.Context, ymlConfig cfg.Config, leading bool, start, end, foldername, backupTime string, ch chan<- prometheus.Metric, pushCnf bool) ([]remotewrite.TimeSeries, []prometheus.Metric, error) {
+	// 사이트 라벨 값 (단일 수집은 라벨 없음)
+	var siteLabels []string
+	if multiSite {
+		siteLabels = []string{ymlConfig.Site}
+	}
+	tsdb := stores[ymlConfig.Site]
+
+	if !leading {
+		forgetRecovered(ymlConfig.Site)
+		read, err := followerLoader(ymlConfig, tsdb)
+		if err != nil {
+			logger.LogErr("follower load failed : "+ymlConfig.Site, err)
+			return nil, nil, err
+		}
+		metricsCollect(filteredLoad(read), tsdb, siteLabels, ch, nil)
+		return nil, nil, nil
+	}
+
+	// 수집 상태 journal (사용하지 않으면 nil)
+	j := journals[ymlConfig.Site]
+	if j != nil {
+		recoverSite(ctx, ymlConfig, j, start, end)
+	}
+
 	//수집전 curl 날려서 파일저장하기
 	//curl 후 폴더만 생성진행 함
-	err := curl.ExporterCurl(start, end, foldername, backupTime, ymlConfig)
+	curlErr := fetchSite(ctx, ymlConfig, j, start, end, foldername, backupTime)
+	if curlErr != nil {
+		logger.LogErr("ExporterCurl Method Error : "+ymlConfig.Site, curlErr)
+	}
+	// 종료로 중단된 수집은 받은 파일을 정리하고 내보내지 않는다.
+	// journal 사용시 받은 파일은 남겨두고 다음 시작시 이어서 받는다.
+	if ctx.Err() != nil {
+		if j == nil {
+			curl.RemovePartial(ymlConfig)
+		}
+		return nil, nil, curlErr
+	}
+
+	// 수집 중 leader 를 잃었으면 저장, 게시, 백업은 새 leader 에 맡긴다.
+	if !stillLeading(ymlConfig.Site, "store") {
+		return nil, nil, curlErr
+	}
+	storeCollected(ymlConfig, j)
+
+	load := filteredLoad(func(family string) ([][]string, error) {
+		return loadCnfData(ymlConfig, family)
+	})
+
+	var stamped *[]prometheus.Metric
+	if pushCnf {
+		stamped = &[]prometheus.Metric{}
+	}
+	series := metricsCollect(load, tsdb, siteLabels, ch, stamped)
+
+	if !stillLeading(ymlConfig.Site, "snapshot publish") {
+		return nil, nil, curlErr
+	}
+	// CORE, RAN API 파일을 새 snapshot 으로 게시 (API 는 요청마다 한 snapshot 만 읽는다)
+	apiNames := append(append([]string{}, ymlConfig.File.CORE_NAME...), ymlConfig.File.RAN_NAME...)
+	_, err := snapshot.Publish(ymlConfig.File.API_Path, ymlConfig.File.CSV_Path, apiNames, snapshot.Period{Start: start, End: end})
+	if err != nil {
+		logger.LogErr("API snapshot publish failed", err)
+	} else if j != nil {
+		logJournal(j.APIUpdated())
+	}
+
+	//파일 백업
+	if !stillLeading(ymlConfig.Site, "backup") {
+		return nil, nil, curlErr
+	}
+	if j != nil {
+		err = backupCycle(ymlConfig, j, foldername, backupTime)
+	} else {
+		err = backup(foldername, backupTime, ymlConfig.File.CSV_Path, ymlConfig.File.Family_Name)
+	}
 	if err != nil {
-		logger.LogErr("ExporterCurl Method Error", err)
+		logger.LogErr("exporter file backup failed", err)
 	}
 
+	if stamped != nil {
+		return series, *stamped, curlErr
+	}
+	return series, nil, curlErr
+}
+
+// stillLeading leader 여부를 다시 확인, 수집 중 Lease 를 잃었으면 step 을 건너뛴다고 남긴다.
+func stillLeading(site, step string) bool {
+	if elector.Leading() {
+		return true
+	}
+	logger.LogWarn("leader lease lost during collection, "+step+" skipped", zap.String("site", site))
+	return false
+}
+
+// metricsCollect cnf_config.yml metrics, derived 를 Family 데이터로 만든다. (수집, convert CLI 공통)
+// tsdb 가 있으면 delta 메트릭도 만들고, remote write 사용시 시계열을 반환한다.
+func metricsCollect(load func(family string) ([][]string, error), tsdb *store.Store, siteLabels []string, ch chan<- prometheus.Metric, stamped *[]prometheus.Metric) []remotewrite.TimeSeries {
 	var csvData [][]string
+	var err error
+	var series []remotewrite.TimeSeries
+
 	for metricName, metricKey := range metricConfig.Metrics {
 		//csv 파일 가져오기
 		// metrics.descripon을 가져와 FamilyName.csv를 연다
-		path := fmt.Sprintf(ymlConfig.File.CSV_Path + "/" + metricKey.Description + ".csv")
-		csvData, err = csv.LoadCsv(path)
+		csvData, err = load(metricKey.Description)
 		if err != nil {
 			logger.LogErr("CSV 파일 Open 실패", err)
 			continue
@@ -196,48 +826,43 @@ func (c *CnfCollector) Collect(ch chan<- prometheus.Metric) {
 			logger.LogWarn(metricName+" 에 해당 데이터가 없습니다.", zap.String("MetricName", metricName))
 			continue
 		} else {
-			err = commonCollect(csvData, metricSequence, metricType, metricDesc, ch)
+			switch strings.ToLower(metricType) {
+			case "histogram", "info", "stateset":
+				e := &emitter{ch: ch, stamped: stamped, now: time.Now()}
+				if remoteWriter != nil {
+					e.series = &series
+				}
+				distributionCollect(csvData, metricName, metricKey, siteLabels, e)
+				continue
+			}
+			err = commonCollect(csvData, metricSequence, metricType, metricDesc, siteLabels, ch, stamped)
 			if err != nil {
 				logger.LogErr("Failed to run collector", err)
 			}
+			if remoteWriter != nil {
+				series = append(series, toSeries(metricName, metricKey.Labels, siteLabels, metricKey.Type, metricSequence, csvData)...)
+			}
+			if metricKey.Delta && tsdb != nil {
+				if previous, err := tsdb.Previous(metricKey.Description); err == nil {
+					deltaCollect(csvData, previous, metricSequence, metricKey.DeltaDesc, siteLabels, ch, stamped)
+				}
+			}
 		}
 	}
 
-	if _, err := os.Stat(ymlConfig.File.API_Path); err == nil {
-		_ = os.Mkdir(ymlConfig.File.API_Path, 0755)
-	} else {
-		logger.LogInfo("폴더가 존재합니다.")
-	}
-
-	// API 파일경로가 없을경우 생성 ( 있으면 넘어감 )
-	if _, err := os.Stat(ymlConfig.File.API_Path); err != nil {
-		_ = os.MkdirAll(ymlConfig.File.API_Path, 0755)
-	}
-
-	// CORE API FILE BACKUP
-	for _, value := range ymlConfig.File.CORE_NAME {
-		err = copyFile(ymlConfig.File.CSV_Path, ymlConfig.File.API_Path, value)
-		if err != nil {
-			logger.LogErr("API metric backup failed", err)
-		}
-	}
-	// CORE API FILE BACKUP
-	for _, value := range ymlConfig.File.RAN_NAME {
-		err = copyFile(ymlConfig.File.CSV_Path, ymlConfig.File.API_Path, value)
-		if err != nil {
-			logger.LogErr("API metric API backup failed", err)
-		}
-	}
-
-	//파일 백업
-	err = backup(foldername, backupTime, ymlConfig.File.CSV_Path, ymlConfig.File.Family_Name)
-	if err != nil {
-		logger.LogErr("exporter file backup failed", err)
-	}
+	return append(series, derivedCollect(load, siteLabels, ch, stamped)...)
 }
 
-func commonCollect(csvData [][]string, metricSequnce int, metricType string, metricDesc *prometheus.Desc, ch chan<- prometheus.Metric) error {
+// commonCollect CSV 데이터 행마다 메트릭을 만든다.
+// siteLabels 는 CSV 라벨 컬럼 뒤에 붙는 site 라벨 값 (단일 수집은 nil)
+// stamped 가 nil 이 아니면 period(INIT TIME) timestamp 를 붙인 메트릭을 함께 모은다. (push 전송용)
+func commonCollect(csvData [][]string, metricSequnce int, metricType string, metricDesc *prometheus.Desc, siteLabels []string, ch chan<- prometheus.Metric, stamped *[]prometheus.Metric) error {
+	now := time.Now()
 	for i := 3; i < len(csvData); i++ {
+		// 라벨 컬럼이나 값 컬럼이 없는 행은 건너뛴다.
+		if len(csvData[i]) < 7 || metricSequnce >= len(csvData[i]) {
+			continue
+		}
 		// 라벨 데이터
 		labelVals := []string{}
 
@@ -245,6 +870,7 @@ func commonCollect(csvData [][]string, metricSequnce int, metricType string, met
 		for j := 0; j < 7; j++ {
 			labelVals = append(labelVals, csvData[i][j])
 		}
+		labelVals = append(labelVals, siteLabels...)
 
 		// 값을 넣기위해 float64 parser
 		val, err := strconv.ParseFloat(csvData[i][metricSequnce], 64)
@@ -253,19 +879,196 @@ func commonCollect(csvData [][]string, metricSequnce int, metricType string, met
 			return errors.Cause(err)
 		}
 		// metricType 설정
+		var metric prometheus.Metric
 		switch strings.ToLower(metricType) {
 		case "counter":
-			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.CounterValue, val, labelVals...)
+			metric = prometheus.MustNewConstMetric(metricDesc, prometheus.CounterValue, val, labelVals...)
 		case "gauge":
-			ch <- prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, val, labelVals...)
+			metric = prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, val, labelVals...)
 		default:
 			logger.LogWarn("Fail to add metric for is not valid type")
 			continue
 		}
+		ch <- metric
+		if stamped != nil {
+			*stamped = append(*stamped, prometheus.NewMetricWithTimestamp(csv.ParsePeriod(csvData[i], now), metric))
+		}
 	}
 	return nil
 }
 
+// filteredLoad Family 데이터는 수집 주기마다 한번 읽어 필터를 적용한다.
+func filteredLoad(read func(family string) ([][]string, error)) func(family string) ([][]string, error) {
+	cache := map[string][][]string{}
+	return func(family string) ([][]string, error) {
+		if data, ok := cache[family]; ok {
+			return data, nil
+		}
+		data, err := read(family)
+		if err != nil {
+			return nil, err
+		}
+		data, dropped := rowFilter.Apply(family, data)
+		rowFilter.Count(family, dropped)
+		cache[family] = data
+		return data, nil
+	}
+}
+
+// followerLoader leader 가 공유 저장소에 남긴 마지막 수집 데이터
+// 저장소 사용시 저장소를 다시 읽고, 아니면 CSV_PATH 의 Family 별 가장 최근 백업 파일을 사용한다.
+func followerLoader(ymlConfig cfg.Config, tsdb *store.Store) (func(family string) ([][]string, error), error) {
+	if tsdb != nil {
+		if err := tsdb.Reload(); err != nil {
+			return nil, err
+		}
+		return tsdb.Latest, nil
+	}
+	entries, err := history.BuildIndex(ymlConfig.File.CSV_Path)
+	if err != nil {
+		return nil, err
+	}
+	// 시작시간 순이므로 마지막 항목이 가장 최근
+	latest := map[string]string{}
+	for _, entry := range entries {
+		latest[entry.Family] = entry.Path
+	}
+	return func(family string) ([][]string, error) {
+		path, ok := latest[family]
+		if !ok {
+			return nil, fmt.Errorf("%s: no backup from leader", family)
+		}
+		return csv.LoadCsv(path)
+	}, nil
+}
+
+// loadCnfData 저장소 사용시 마지막 수집 데이터, 아니면 CSV_PATH 의 FamilyName.csv 를 읽는다.
+func loadCnfData(ymlConfig cfg.Config, family string) ([][]string, error) {
+	if tsdb := stores[ymlConfig.Site]; tsdb != nil {
+		return tsdb.Latest(family)
+	}
+	return csv.LoadCsv(ymlConfig.File.CSV_Path + "/" + family + ".csv")
+}
+
+// toSeries commonCollect 와 같은 이름/라벨로 remote write 시계열을 만든다.
+// timestamp 는 수집시간이 아닌 각 행의 period(INIT TIME) 를 사용한다.
+func toSeries(metricName string, labels, siteLabels []string, metricType string, metricSequnce int, csvData [][]string) []remotewrite.TimeSeries {
+	switch strings.ToLower(metricType) {
+	case "counter", "gauge":
+	default:
+		return nil
+	}
+
+	now := time.Now()
+	var series []remotewrite.TimeSeries
+	for i := 3; i < len(csvData); i++ {
+		if len(csvData[i]) <= metricSequnce {
+			continue
+		}
+		val, err := strconv.ParseFloat(csvData[i][metricSequnce], 64)
+		if err != nil {
+			continue
+		}
+		series = append(series, rowSeries(metricName, labels, siteLabels, csvData[i], val, now))
+	}
+	return series
+}
+
+// rowSeries CSV 한 행의 remote write 시계열
+func rowSeries(metricName string, labels, siteLabels []string, row []string, val float64, now time.Time) remotewrite.TimeSeries {
+	ts := remotewrite.TimeSeries{
+		Labels: []remotewrite.Label{{Name: "__name__", Value: prometheus.BuildFQName("p5g_exporter", "", metricName)}},
+		Samples: []remotewrite.Sample{{
+			Value:     val,
+			Timestamp: csv.ParsePeriod(row, now).UnixMilli(),
+		}},
+	}
+	for j, label := range labels {
+		if j < len(row) {
+			ts.Labels = append(ts.Labels, remotewrite.Label{Name: label, Value: row[j]})
+		}
+	}
+	for _, site := range siteLabels {
+		ts.Labels = append(ts.Labels, remotewrite.Label{Name: "site", Value: site})
+	}
+	return ts
+}
+
+// derivedCollect 계산식 메트릭을 gauge 로 내보내고 remote write 시계열을 반환한다.
+// 0 으로 나누거나 join 할 행이 없는 행은 내보내지 않는다.
+func derivedCollect(load func(family string) ([][]string, error), siteLabels []string, ch chan<- prometheus.Metric, stamped *[]prometheus.Metric) []remotewrite.TimeSeries {
+	now := time.Now()
+	var series []remotewrite.TimeSeries
+	for metricName, def := range metricConfig.Derived {
+		base, err := load(def.Description)
+		if err != nil {
+			logger.LogErr(metricName+" derived base load failed", err)
+			continue
+		}
+		others := map[string][][]string{}
+		for _, family := range def.Parsed.Families() {
+			data, err := load(family)
+			if err != nil {
+				logger.LogErr(metricName+" derived join load failed : "+family, err)
+				continue
+			}
+			others[family] = data
+		}
+
+		for _, sample := range derived.Evaluate(def.Parsed, base, others, def.JoinColumn) {
+			labelVals := append(append([]string{}, sample.Row[:7]...), siteLabels...)
+			metric := prometheus.MustNewConstMetric(def.MetricDesc, prometheus.GaugeValue, sample.Value, labelVals...)
+			ch <- metric
+			if stamped != nil {
+				*stamped = append(*stamped, prometheus.NewMetricWithTimestamp(csv.ParsePeriod(sample.Row, now), metric))
+			}
+			if remoteWriter != nil {
+				series = append(series, rowSeries(metricName, def.Labels, siteLabels, sample.Row, sample.Value, now))
+			}
+		}
+	}
+	return series
+}
+
+// deltaCollect 직전 period 의 같은 라벨(period 컬럼 제외) 행과의 차이를 gauge 로 내보낸다.
+func deltaCollect(csvData, previous [][]string, metricSequnce int, metricDesc *prometheus.Desc, siteLabels []string, ch chan<- prometheus.Metric, stamped *[]prometheus.Metric) {
+	now := time.Now()
+	rowKey := func(row []string) string {
+		return strings.Join(append(append([]string{}, row[0:3]...), row[4:7]...), "|")
+	}
+
+	prevValues := map[string]float64{}
+	for i := 3; i < len(previous); i++ {
+		if len(previous[i]) <= metricSequnce {
+			continue
+		}
+		val, err := strconv.ParseFloat(previous[i][metricSequnce], 64)
+		if err != nil {
+			continue
+		}
+		prevValues[rowKey(previous[i])] = val
+	}
+
+	for i := 3; i < len(csvData); i++ {
+		if len(csvData[i]) <= metricSequnce {
+			continue
+		}
+		prev, ok := prevValues[rowKey(csvData[i])]
+		if !ok {
+			continue
+		}
+		val, err := strconv.ParseFloat(csvData[i][metricSequnce], 64)
+		if err != nil {
+			continue
+		}
+		metric := prometheus.MustNewConstMetric(metricDesc, prometheus.GaugeValue, val-prev, append(append([]string{}, csvData[i][:7]...), siteLabels...)...)
+		ch <- metric
+		if stamped != nil {
+			*stamped = append(*stamped, prometheus.NewMetricWithTimestamp(csv.ParsePeriod(csvData[i], now), metric))
+		}
+	}
+}
+
 func backup(foldername, backupfolder, path string, familyName []string) error {
 	//파일 이동
 	for _, familyValue := range familyName {
@@ -296,29 +1099,3 @@ func moveFile(folderName, backupFolderName, path, familyName string) error {
 	logger.LogInfo("백업 파일이동 성공", zap.String("familyName", familyName))
 	return nil
 }
-
-func copyFile(oldpath, newpath, familyName string) error {
-	sourceFile, err := os.Open(oldpath + "/" + familyName + ".csv")
-	if err != nil {
-		logger.LogErr(familyName+": file open is failed", err)
-		return errors.Cause(err)
-	}
-	defer sourceFile.Close()
-
-	// 대상 파일 생성 또는 열기 (존재하면 덮어쓰기)
-	destinationFilePath := filepath.Join(newpath, filepath.Base(oldpath+"/"+familyName+".csv"))
-	destinationFile, err := os.Create(destinationFilePath)
-	if err != nil {
-		logger.LogErr(familyName+": file create is failed", err)
-		return errors.Cause(err)
-	}
-	defer destinationFile.Close()
-
-	// 파일 복사
-	_, err = io.Copy(destinationFile, sourceFile)
-	if err != nil {
-		logger.LogErr(familyName+": file copy is failed", err)
-		return errors.Cause(err)
-	}
-	return nil
-}
@@ -0,0 +1,222 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/curl"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/exporter"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/filter"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/fixture"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/harness"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/health"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/utils"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/web"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 수집 기간 2023-11-08 13:15:00 ~ 13:32:00
+var collectTime = time.Date(2023, 11, 8, 13, 31, 0, 0, time.Local)
+
+const (
+	ossUser     = "ossuser"
+	ossPassword = "osspasswd"
+	mecToken    = "test-token"
+)
+
+// newRouter fake OSS, retriever, Thanos 로 exporter 라우터 구성
+func newRouter(t *testing.T) (*gin.Engine, *harness.OSS, *harness.Thanos) {
+	t.Helper()
+
+	oss := harness.NewOSS(ossUser, ossPassword)
+	t.Cleanup(oss.Close)
+	if err := oss.LoadDir("testdata/oss", "2023-11-08 13:15:00"); err != nil {
+		t.Fatal(err)
+	}
+
+	thanos := harness.NewThanos()
+	t.Cleanup(thanos.Close)
+	thanos.Set("node_cpu_seconds_total",
+		harness.Sample{Labels: map[string]string{"container": "node-exporter", "cpu": "0", "endpoint": "https", "instance": "10.0.0.1:9100", "job": "node-exporter", "mode": "idle", "namespace": "monitoring", "pod": "node-exporter-abcde", "service": "node-exporter"}, Value: "12345.67"},
+		harness.Sample{Labels: map[string]string{"container": "node-exporter", "cpu": "0", "endpoint": "https", "instance": "10.0.0.1:9100", "job": "node-exporter", "mode": "user", "namespace": "monitoring", "pod": "node-exporter-abcde", "service": "node-exporter"}, Value: "890.12"},
+	)
+
+	dir := t.TempDir()
+	t.Setenv("ENV", "prd")
+	t.Setenv("CSV_PATH", filepath.Join(dir, "csv"))
+	t.Setenv("API_PATH", filepath.Join(dir, "api"))
+	t.Setenv("FAMILY_NAME", "UECON_AMF,UEID_AMF,AMFTPS,AMFMS,Air MAC Packet,Air MAC Packet (PCell),Air MAC Packet (SCell),Downlink Active UE Number")
+	t.Setenv("CORE_NAME", "UECON_AMF,UEID_AMF,AMFTPS,AMFMS")
+	t.Setenv("RAN_NAME", "Air_MAC_Packet,Air_MAC_Packet_(PCell),Air_MAC_Packet_(SCell),Downlink_Active_UE_Number")
+	t.Setenv("CURL_URL", oss.URL+"/oss/performanceData")
+	t.Setenv("OSS_USERNAME", ossUser)
+	t.Setenv("OSS_PASSWORD", ossPassword)
+
+	utils.Now = func() time.Time { return collectTime }
+	curl.SetRetriever(harness.Retriever{OSS: oss})
+	curl.SetCopyDelay(0)
+	tokenSource = exporter.StaticToken(mecToken)
+	t.Cleanup(func() {
+		utils.Now = time.Now
+		curl.SetRetriever(nil)
+		curl.SetCopyDelay(time.Second)
+		tokenSource = nil
+	})
+
+	b, err := os.ReadFile("testdata/app_config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceConfig := filepath.Join(dir, "app_config.yml")
+	if err := os.WriteFile(deviceConfig, bytes.ReplaceAll(b, []byte("{{THANOS}}"), []byte(thanos.URL)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	metricConfig = Config{}
+	if err := loadMetricConfig("testdata/cnf_config.yml", deviceConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	lifecycleManager = lifecycle.NewManager(time.Second)
+	healthChecker = health.NewChecker(time.Minute, lifecycleManager.ShuttingDown)
+
+	webServer, err := web.NewServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	routes(router, webServer, appRegistries(), newCnfRegistry())
+	return router, oss, thanos
+}
+
+func TestGolden(t *testing.T) {
+	router, oss, thanos := newRouter(t)
+
+	// /metrics 수집 후 API_PATH 에 복사된 CSV 로 /api/metrics 를 만든다.
+	for _, tc := range []struct {
+		path   string
+		golden string
+	}{
+		{"/metrics", "metrics.golden"},
+		{"/cpu/metrics", "cpu_metrics.golden"},
+		{"/api/metrics", "api_metrics.golden"},
+	} {
+		t.Run(tc.golden, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s: %d %s", tc.path, w.Code, w.Body.String())
+			}
+			compareGolden(t, tc.golden, normalize(t, tc.path, w.Body.Bytes()))
+		})
+	}
+
+	requests := oss.Requests()
+	if len(requests) != 8 {
+		t.Fatalf("OSS requests = %d, want 8", len(requests))
+	}
+	for _, req := range requests {
+		if req.StartTime != "2023-11-08 13:15:00" || req.EndTime != "2023-11-08 13:32:00" {
+			t.Errorf("%s window = %s ~ %s", req.Family, req.StartTime, req.EndTime)
+		}
+	}
+
+	// p5g_wrcp1 은 token 없이 ("Bearer"), p5g_mec 는 token 으로 조회
+	seen := map[string]bool{}
+	for _, token := range thanos.Tokens() {
+		seen[token] = true
+	}
+	if !seen["Bearer"] || !seen["Bearer "+mecToken] {
+		t.Errorf("Authorization = %q", thanos.Tokens())
+	}
+}
+
+// 기록한 bundle 로 재생하면 fake OSS, Thanos 없이 같은 결과가 나와야 한다.
+func TestFixtureReplay(t *testing.T) {
+	router, oss, thanos := newRouter(t)
+	t.Cleanup(func() {
+		curl.SetClient(curl.HTTPClient{})
+		exporter.SetTransport(nil)
+	})
+
+	dir := filepath.Join(t.TempDir(), "fixture")
+	recorder, err := fixture.NewRecorder(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	curl.SetClient(recorder.Client(curl.HTTPClient{}))
+	curl.SetRetriever(recorder.Retriever(harness.Retriever{OSS: oss}))
+	exporter.SetTransport(recorder.Transport(exporter.NewTransport()))
+	for _, path := range []string{"/metrics", "/cpu/metrics"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	oss.Close()
+	thanos.Close()
+
+	if err := setupFixture(cfg.Fixture{Mode: "replay", Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	// 새로 실행한 것처럼 필터 카운터 초기화
+	if rowFilter, err = filter.New(metricConfig.Filters); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		path   string
+		golden string
+	}{
+		{"/metrics", "metrics.golden"},
+		{"/cpu/metrics", "cpu_metrics.golden"},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", tc.path, w.Code, w.Body.String())
+		}
+		compareGolden(t, tc.golden, normalize(t, tc.path, w.Body.Bytes()))
+	}
+}
+
+// normalize 빌드마다 달라지는 build_info 제거, JSON 은 들여쓰기
+func normalize(t *testing.T, path string, body []byte) []byte {
+	t.Helper()
+	if strings.HasPrefix(path, "/api/") {
+		var out bytes.Buffer
+		if err := json.Indent(&out, body, "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		out.WriteByte('\n')
+		return out.Bytes()
+	}
+	var lines []string
+	for _, line := range strings.SplitAfter(string(body), "\n") {
+		if !strings.Contains(line, "cnf_exporter_build_info") {
+			lines = append(lines, line)
+		}
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+func compareGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	harness.CompareGolden(t, filepath.Join("testdata", "golden", name), got)
+}
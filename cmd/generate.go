@@ -0,0 +1,132 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/generate"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runGenerateConfig OSS CSV 컬럼명으로 cnf_config.yml 메트릭을 제안하고 기존 설정 뒤에 추가하는 CLI
+// ./exporter generate-config -metricConfig cnf_config.yml AMFTPS.csv > cnf_config.new.yml
+// ./exporter generate-config -w -prefix amf_tps performanceData_20231108_144317.csv
+func runGenerateConfig(args []string) int {
+	fs := flag.NewFlagSet("generate-config", flag.ExitOnError)
+	configFile := fs.String("metricConfig", "cnf_config.yml", "cnf metric configuration file to merge into")
+	family := fs.String("family", "", "family name of every file (default the \"Family name\" row, then the file name)")
+	prefix := fs.String("prefix", "", "metric name prefix (default snake_case family name)")
+	write := fs.Bool("w", false, "write the merged config back to -metricConfig instead of stdout")
+	_ = fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: exporter generate-config [flags] file.csv ...")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	config, err := os.ReadFile(*configFile)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "metric config:", err)
+		return 1
+	}
+
+	var proposed []generate.Metric
+	for _, file := range files {
+		metrics, err := proposeMetrics(file, *family, *prefix)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		proposed = append(proposed, metrics...)
+	}
+
+	merged, added, skipped, err := generate.Merge(config, proposed, defaultLabels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configFile, err)
+		return 1
+	}
+	for _, metric := range added {
+		fmt.Fprintf(os.Stderr, "add   %s (%s, %s column %d %s)\n", metric.Name, metric.Type, metric.Family, metric.Column, metric.Header)
+	}
+	for _, skip := range skipped {
+		fmt.Fprintf(os.Stderr, "skip  %s (%s column %d %s): %s\n", skip.Metric.Name, skip.Metric.Family, skip.Metric.Column, skip.Metric.Header, skip.Reason)
+	}
+
+	if !*write {
+		_, _ = os.Stdout.Write(merged)
+		return 0
+	}
+	if len(added) == 0 {
+		return 0
+	}
+	if err := writeFileAtomic(*configFile, merged); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// proposeMetrics CSV 파일 하나의 값 컬럼별 제안 메트릭
+func proposeMetrics(file, family, prefix string) ([]generate.Metric, error) {
+	rows, err := csv.LoadCsv(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if len(rows) < 3 {
+		return nil, fmt.Errorf("%s: missing header rows", file)
+	}
+	if family == "" {
+		family = generate.Family(rows)
+	}
+	if family == "" {
+		name, ok := csv.FamilyName(filepath.Base(file))
+		if !ok {
+			return nil, fmt.Errorf("%s: no family name, use -family", file)
+		}
+		family = name
+	}
+	metrics := generate.Propose(strings.TrimSpace(family), prefix, rows[2])
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("%s: no value columns", file)
+	}
+	return metrics, nil
+}
+
+// writeFileAtomic 같은 디렉토리의 임시 파일에 쓴 뒤 rename (기존 파일 권한 유지)
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
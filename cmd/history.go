@@ -0,0 +1,94 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/history"
+	"os"
+	"time"
+)
+
+// runHistory 백업 폴더 기간 조회 CLI
+// ./exporter history -family AMFTPS -column 9 -start "2023-11-08 13:00" -end "2023-11-08 18:00"
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	root := fs.String("path", "", "backup root (default CSV_PATH)")
+	site := fs.String("site", "", "site name when SITES is configured")
+	family := fs.String("family", "", "family name (e.g. AMFTPS)")
+	column := fs.String("column", "", "column index or header name")
+	groupBy := fs.String("group_by", "location", "group column (location|ne_id|ne_name|none)")
+	agg := fs.String("agg", "sum", "aggregation (sum|avg|max|min)")
+	start := fs.String("start", "", "start time (default 24h ago)")
+	end := fs.String("end", "", "end time (default now)")
+	format := fs.String("format", "json", "output format (json|csv)")
+	_ = fs.Parse(args)
+
+	if *root == "" {
+		ymlConfig := cfg.InitConfig()
+		if err := ymlConfig.ValidateSites(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		siteConfig, ok := ymlConfig.SiteConfig(*site)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "unknown site:", *site)
+			return 2
+		}
+		*root = siteConfig.File.CSV_Path
+	}
+
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+	var err error
+	if *start != "" {
+		if from, err = history.ParseTime(*start); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid start:", err)
+			return 2
+		}
+	}
+	if *end != "" {
+		if to, err = history.ParseTime(*end); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid end:", err)
+			return 2
+		}
+	}
+
+	result, err := history.Run(*root, history.Query{
+		Family:  *family,
+		Column:  *column,
+		GroupBy: *groupBy,
+		Agg:     *agg,
+		From:    from,
+		To:      to,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "history query failed:", err)
+		return 1
+	}
+
+	if *format == "csv" {
+		err = history.WriteCSV(os.Stdout, result)
+	} else {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(result)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
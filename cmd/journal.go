@@ -0,0 +1,162 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package main
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/curl"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/journal"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 사이트별 수집 상태 journal (journal.ENABLE 일 때)
+var journals = map[string]*journal.Journal{}
+
+// 복구를 마친 사이트, follower 가 되면 지워서 다시 leader 가 되었을 때 복구한다.
+var recovered = map[string]bool{}
+var recoveredMu sync.Mutex
+
+// recoverSite 시작(또는 leader 획득) 후 첫 수집에서 한번, 중단된 수집 주기 복구
+// 다른 window 의 수집이 중단되었으면 못 받은 Family 를 받아 기록, 백업까지 마친다. (같은 window 는 이번 수집에서 이어서 진행)
+func recoverSite(ctx context.Context, ymlConfig cfg.Config, j *journal.Journal, start, end string) {
+	recoveredMu.Lock()
+	done := recovered[ymlConfig.Site]
+	recovered[ymlConfig.Site] = true
+	recoveredMu.Unlock()
+	if done {
+		return
+	}
+
+	cycle, err := j.Recover(ymlConfig.File.Family_Name)
+	if err != nil {
+		logger.LogErr("journal recover failed : "+ymlConfig.Site, err)
+		return
+	}
+	if cycle == nil {
+		return
+	}
+	j.Resumed()
+	logger.LogInfo("resuming interrupted cycle", zap.String("site", ymlConfig.Site), zap.String("start", cycle.Start), zap.String("end", cycle.End))
+	if cycle.Start == start && cycle.End == end {
+		return
+	}
+
+	if err := fetchSite(ctx, ymlConfig, j, cycle.Start, cycle.End, cycle.Folder, cycle.Window); err != nil {
+		logger.LogErr("resumed cycle fetch failed : "+ymlConfig.Site, err)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	storeCollected(ymlConfig, j)
+	// 지난 window 이므로 API_PATH 는 이번 수집에서 갱신한다.
+	if err := backupCycle(ymlConfig, j, cycle.Folder, cycle.Window); err != nil {
+		logger.LogErr("resumed cycle backup failed : "+ymlConfig.Site, err)
+	}
+}
+
+// forgetRecovered follower 로 수집한 사이트는 leader 가 되면 다시 복구
+func forgetRecovered(site string) {
+	recoveredMu.Lock()
+	delete(recovered, site)
+	recoveredMu.Unlock()
+}
+
+// fetchSite OSS 수집, journal 사용시 아직 받지 못한 Family 만 받고 받은 파일의 checksum 기록
+func fetchSite(ctx context.Context, ymlConfig cfg.Config, j *journal.Journal, start, end, foldername, backupTime string) error {
+	if j == nil {
+		return curl.ExporterCurl(ctx, start, end, foldername, backupTime, ymlConfig)
+	}
+	cycle, err := j.Begin(start, end, foldername, backupTime, ymlConfig.File.Family_Name)
+	logJournal(err)
+
+	pending := cycle.Names(ymlConfig.File.Family_Name, journal.StatusPending, journal.StatusFailed)
+	fetchConfig := ymlConfig
+	fetchConfig.File.Family_Name = pending
+	err = curl.ExporterCurlFamilies(ctx, start, end, foldername, backupTime, fetchConfig, func(family, path string) {
+		logJournal(j.Fetched(family, path))
+	})
+	// 종료로 중단된 Family 는 pending 으로 남겨 다음 시작시 받는다.
+	if err != nil && ctx.Err() == nil {
+		logJournal(j.Failed(j.Current().Names(pending, journal.StatusPending, journal.StatusFailed), err))
+	}
+	return err
+}
+
+// storeCollected 수집한 Family CSV 를 저장소에 기록하고 메시지 버스로 전송
+// journal 사용시 이번에 받은 Family 만 기록하여 재개한 수집의 중복 기록을 막는다.
+func storeCollected(ymlConfig cfg.Config, j *journal.Journal) {
+	tsdb := stores[ymlConfig.Site]
+	if tsdb == nil && publisher == nil {
+		return
+	}
+	families := ymlConfig.File.Family_Name
+	if j != nil {
+		families = j.Current().Names(families, journal.StatusFetched)
+	}
+	collected := time.Now()
+	for _, familyValue := range families {
+		family := strings.ReplaceAll(familyValue, " ", "_")
+		data, err := csv.LoadCsv(ymlConfig.File.CSV_Path + "/" + family + ".csv")
+		if err != nil {
+			logger.LogErr(family+" csv load skipped", err)
+			continue
+		}
+		if tsdb != nil {
+			if err := tsdb.Append(family, data, collected); err != nil {
+				logger.LogErr(family+" store append failed", err)
+			}
+		}
+		if publisher != nil {
+			if err := publisher.Publish(ymlConfig.Site, family, data, collected); err != nil {
+				logger.LogErr(family+" publish failed", err)
+			}
+		}
+		if j != nil {
+			logJournal(j.Stored(familyValue))
+		}
+	}
+}
+
+// backupCycle journal 의 받은 Family 만 백업 폴더로 옮기고 위치를 기록한 뒤 수집 완료
+func backupCycle(ymlConfig cfg.Config, j *journal.Journal, foldername, backupTime string) error {
+	dir := filepath.Join(ymlConfig.File.CSV_Path, foldername, backupTime)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var failed error
+	for _, familyValue := range j.Current().Names(ymlConfig.File.Family_Name, journal.StatusFetched, journal.StatusStored) {
+		family := strings.ReplaceAll(familyValue, " ", "_")
+		if err := moveFile(foldername, backupTime, ymlConfig.File.CSV_Path, family); err != nil {
+			logger.LogErr("File backup failed", err)
+			failed = err
+			continue
+		}
+		logJournal(j.BackedUp(familyValue, filepath.Join(dir, family+".csv")))
+	}
+	logJournal(j.Complete())
+	return failed
+}
+
+// logJournal journal 기록 실패는 수집을 막지 않는다.
+func logJournal(err error) {
+	if err != nil {
+		logger.LogErr("journal update failed", err)
+	}
+}
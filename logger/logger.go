@@ -26,6 +26,10 @@ var log = newLogger(config.Logging.Encode)
 
 func newLogger(encode string) *zap.Logger {
 	cfg := os.Getenv(config.Logging.Level)
+	// config 파일이 없으면 기본 json
+	if encode == "" {
+		encode = "json"
+	}
 
 	var level zapcore.Level
 
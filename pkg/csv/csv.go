@@ -12,12 +12,31 @@
 package csv
 
 import (
+	"compress/gzip"
 	"encoding/csv"
 	"errors"
+	"github.com/klauspost/compress/zstd"
+	"io"
 	"os"
 	"strings"
+	"time"
 )
 
+// OSS CSV 의 period(INIT TIME) 컬럼 위치와 지원 형식
+const PeriodColumn = 3
+
+var periodLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"20060102150405",
+	"200601021504",
+}
+
+// 백업 폴더에서 사용하는 Family CSV 확장자 (retention 압축 포함)
+var extensions = []string{".csv", ".csv.gz", ".csv.zst"}
+
 func LoadCsv(path string) ([][]string, error) {
 	_, err := os.Stat(path)
 	if err != nil {
@@ -35,14 +54,43 @@ func LoadCsv(path string) ([][]string, error) {
 	}
 	defer file.Close()
 
+	// 압축된 백업 파일은 확장자로 구분하여 해제
+	var source io.Reader = file
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		source = gz
+	case strings.HasSuffix(path, ".zst"):
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		source = zr
+	}
+
 	// Read the CSV data
-	reader := csv.NewReader(file)
+	reader := csv.NewReader(source)
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 	data, err := reader.ReadAll()
 
 	return data, err
 }
 
+// FamilyName 파일명에서 Family 이름을 반환 (AMFTPS.csv.gz => AMFTPS)
+func FamilyName(fileName string) (string, bool) {
+	for _, ext := range extensions {
+		if strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext), true
+		}
+	}
+	return "", false
+}
+
 // 라벨의 값을 붙일 컬럼 갯수 반환
 // 라벨 넣을 컬럼이 공통이므로 뺌
 func MetricColumnCount(data [][]string) (int, error) {
@@ -63,3 +111,17 @@ func MetricColumnCount(data [][]string) (int, error) {
 
 	return count, nil
 }
+
+// ParsePeriod 데이터 행의 period(INIT TIME) 시간, 형식이 맞지 않으면 fallback 반환
+func ParsePeriod(row []string, fallback time.Time) time.Time {
+	if len(row) <= PeriodColumn {
+		return fallback
+	}
+	value := strings.TrimSpace(row[PeriodColumn])
+	for _, layout := range periodLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+	return fallback
+}
@@ -12,6 +12,7 @@
 package curl
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"github.com/pkg/errors"
@@ -20,30 +21,107 @@ import (
 	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/k8sClient"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/utils"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
-// startime과 endtime은 15분단위로 설정 됨
-// config.yml or k8s ENV에 설정시 사용하는 옵션
-func ExporterCurl(startime, endtime, foldername, backupTime string, config cfg.Config) error {
+// OssClient OSS performanceData 요청, 응답은 OSS pod 안에 만들어진 CSV 파일 경로
+type OssClient interface {
+	PerformanceData(ctx context.Context, config cfg.Config, familyName, startTime, endTime string) ([]byte, error)
+}
+
+// Retriever OSS pod 의 srcPath 파일을 destDir 로 복사
+type Retriever interface {
+	Retrieve(ctx context.Context, config cfg.Config, srcPath, destDir string) error
+}
+
+// HTTPClient EXPORTER.CURL_URL 로 basic 인증 GET 요청
+type HTTPClient struct{}
+
+func (HTTPClient) PerformanceData(ctx context.Context, config cfg.Config, familyName, startTime, endTime string) ([]byte, error) {
+	return curl(ctx, config.Exporter.Curl_Url, familyName, startTime, endTime, config.Exporter.Oss_Username, config.Exporter.Oss_Password)
+}
+
+// PodRetriever k8s exec(tar) 로 EXPORTER.NAMESPACE/POD/CONTAINER 의 파일 복사
+type PodRetriever struct {
+	Client *k8sClient.Client
+}
+
+func (r PodRetriever) Retrieve(ctx context.Context, config cfg.Config, srcPath, destDir string) error {
+	return r.Client.CopyFromPod(ctx, config.Exporter.Pod, config.Exporter.Namespace, config.Exporter.Container, srcPath, destDir, k8sClient.CopyOptions{
+		MaxFileSize: int64(config.Exporter.Copy_Max_File_Size) << 20,
+		MaxEntries:  config.Exporter.Copy_Max_Entries,
+		Checksum:    config.Exporter.Copy_Checksum,
+	})
+}
+
+// NewPodRetriever kubeconfig 로 k8s client 를 만들어 PodRetriever 생성
+func NewPodRetriever() Retriever {
 	// k8sclient , k8sconfig 생성
 	k8s_client, k8sconfig := k8sClient.CreateClientSet()
-	podexec := k8sClient.NewK8sClient(*k8sconfig, k8s_client)
+	return PodRetriever{Client: k8sClient.NewK8sClient(*k8sconfig, k8s_client)}
+}
+
+var ossClient OssClient = HTTPClient{}
+
+// nil 이면 수집마다 kubeconfig 로 PodRetriever 생성
+var retriever Retriever
+
+// Family 사이 대기 시간
+var copyDelay = 1 * time.Second
+
+// SetClient OSS 요청 클라이언트 교체 (테스트용 fake OSS 등)
+func SetClient(c OssClient) {
+	ossClient = c
+}
+
+// SetRetriever 파일 복사 방법 교체
+func SetRetriever(r Retriever) {
+	retriever = r
+}
+
+// SetCopyDelay Family 사이 대기 시간 변경
+func SetCopyDelay(d time.Duration) {
+	copyDelay = d
+}
+
+// startime과 endtime은 15분단위로 설정 됨
+// config.yml or k8s ENV에 설정시 사용하는 옵션
+// ctx 가 취소되면 진행중인 curl, CopyFromPod 를 중단하고 ctx.Err() 를 반환한다.
+func ExporterCurl(ctx context.Context, startime, endtime, foldername, backupTime string, config cfg.Config) error {
+	return ExporterCurlFamilies(ctx, startime, endtime, foldername, backupTime, config, nil)
+}
+
+// ExporterCurlFamilies ExporterCurl 과 같고, Family 파일을 받을 때마다 fetched(Family 이름, 파일 경로) 호출
+func ExporterCurlFamilies(ctx context.Context, startime, endtime, foldername, backupTime string, config cfg.Config, fetched func(family, path string)) error {
+	podexec := retriever
+	if podexec == nil {
+		podexec = NewPodRetriever()
+	}
+
+	// 사이트별 CSV_PATH 는 처음 수집시 생성
+	if err := os.MkdirAll(config.File.CSV_Path, 0755); err != nil {
+		return errors.Cause(err)
+	}
 
 	// config.yml에  File에 적어둔 FamilyName 을 하나씩 가져와서 Curl을 날리고
 	// k8s cp 를 통해 서버 로컬(config.file.path)에 저장 함
 	for _, familyValue := range config.File.Family_Name {
 		baseURL := config.Exporter.Curl_Url
-		err := exporterCommon(baseURL, familyValue, startime, endtime, config, podexec)
+		err := exporterCommon(ctx, baseURL, familyValue, startime, endtime, config, podexec)
 		if err != nil {
 			logger.LogErr("apicommon is error", err)
 			return err
 		}
+		if fetched != nil {
+			fetched(familyValue, config.File.CSV_Path+"/"+strings.ReplaceAll(familyValue, " ", "_")+".csv")
+		}
 	}
 	/*
 		파일 경로 설정 후 폴더만 생성
@@ -56,7 +134,7 @@ func ExporterCurl(startime, endtime, foldername, backupTime string, config cfg.C
 	return nil
 }
 
-func curl(baseUrl, familyName, startTime, endTime, username, userpassword string) ([]byte, error) {
+func curl(ctx context.Context, baseUrl, familyName, startTime, endTime, username, userpassword string) ([]byte, error) {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
@@ -79,7 +157,7 @@ func curl(baseUrl, familyName, startTime, endTime, username, userpassword string
 
 	logger.LogInfo(fullUrl)
 
-	req, err := http.NewRequest("GET", fullUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullUrl, nil)
 	if err != nil {
 		logger.LogErr("request Error ", err)
 		return nil, errors.Cause(err)
@@ -101,10 +179,10 @@ func curl(baseUrl, familyName, startTime, endTime, username, userpassword string
 	return bodyText, nil
 }
 
-func exporterCommon(baseURL, familyValue, startime, endtime string, config cfg.Config, podexec *k8sClient.Client) error {
+func exporterCommon(ctx context.Context, baseURL, familyValue, startime, endtime string, config cfg.Config, podexec Retriever) error {
 	// curl 날리는 명령어 확인하기
 	logger.LogInfo("curl command", zap.String("baseURL", baseURL), zap.String("familyName", familyValue), zap.String("startime", startime), zap.String("endtime", endtime))
-	output, err := curl(baseURL, familyValue, startime, endtime, config.Exporter.Oss_Username, config.Exporter.Oss_Password)
+	output, err := ossClient.PerformanceData(ctx, config, familyValue, startime, endtime)
 	if err != nil {
 		logger.LogErr("Unable to execute the curl command.", err)
 		return errors.Cause(err)
@@ -115,35 +193,72 @@ func exporterCommon(baseURL, familyValue, startime, endtime string, config cfg.C
 	logger.LogInfo(string(output))
 
 	// output로 파일 떨구기
-	if strings.Contains(familyValue, " ") {
-		NewFamilyValue := strings.ReplaceAll(familyValue, " ", "_")
-		// nameSpace : usm-compact
-		// podName : mfsm-0
-		err := podexec.CopyFromPod("mfsm-0", "usm-compact", "process", string(output), config.File.CSV_Path)
-		oldFileName := strings.Split(string(output), "/")
-		oldName := fmt.Sprint(config.File.CSV_Path + "/" + oldFileName[7])
-		newName := fmt.Sprint(config.File.CSV_Path + "/" + NewFamilyValue + ".csv")
-		os.Rename(oldName, newName)
-		if err != nil {
-			logger.LogErr("Copy Failed", err)
-			return errors.Cause(err)
-		}
-		// ANSICODE 없는 name
-	} else {
-		// nameSpace : usm-compact
-		// podName : mfsm-0
-		err := podexec.CopyFromPod("mfsm-0", "usm-compact", "process", string(output), config.File.CSV_Path)
-		oldFileName := strings.Split(string(output), "/")
-		oldName := fmt.Sprint(config.File.CSV_Path + "/" + oldFileName[7])
-		newName := fmt.Sprint(config.File.CSV_Path + "/" + familyValue + ".csv")
-		os.Rename(oldName, newName)
-		if err != nil {
-			logger.LogErr("Copy Failed", err)
-			return errors.Cause(err)
-		}
+	// ANSICODE 없는 name
+	NewFamilyValue := strings.ReplaceAll(familyValue, " ", "_")
+	oldFileName := strings.Split(string(output), "/")
+	if len(oldFileName) < 8 {
+		return fmt.Errorf("unexpected curl output : %s", strings.TrimSpace(string(output)))
+	}
+	oldName := fmt.Sprint(config.File.CSV_Path + "/" + oldFileName[7])
+	newName := fmt.Sprint(config.File.CSV_Path + "/" + NewFamilyValue + ".csv")
+
+	// nameSpace : usm-compact (EXPORTER.NAMESPACE)
+	// podName : mfsm-0 (EXPORTER.POD)
+	err = podexec.Retrieve(ctx, config, string(output), config.File.CSV_Path)
+	if err != nil {
+		// 복사가 중단된 파일은 Family 파일로 옮기지 않고 삭제
+		_ = os.Remove(oldName)
+		logger.LogErr("Copy Failed", err)
+		return errors.Cause(err)
 	}
+	os.Rename(oldName, newName)
+
 	// 1초 delay
-	time.Sleep(1 * time.Second)
+	if !lifecycle.Sleep(ctx, copyDelay) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// CheckCredentials OSS 접속 및 계정 확인 (HEAD 요청, 401/403 이면 인증 실패)
+func CheckCredentials(ctx context.Context, config cfg.Config) error {
+	if config.Exporter.Curl_Url == "" {
+		return fmt.Errorf("CURL_URL is empty")
+	}
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	req, err := http.NewRequestWithContext(ctx, "HEAD", config.Exporter.Curl_Url, nil)
+	if err != nil {
+		return errors.Cause(err)
+	}
+	req.SetBasicAuth(config.Exporter.Oss_Username, config.Exporter.Oss_Password)
 
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Cause(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("OSS credentials rejected: %s", resp.Status)
+	}
 	return nil
 }
+
+// RemovePartial CSV_PATH 에 남은 수집중 파일 삭제 (Family.csv, CopyFromPod 원본 파일)
+// 정상 수집된 파일은 backup 으로 날짜 폴더로 옮겨지므로 종료/중단 시점에 남은 파일은 완료되지 않은 파일이다.
+func RemovePartial(config cfg.Config) {
+	var files []string
+	for _, familyValue := range config.File.Family_Name {
+		files = append(files, filepath.Join(config.File.CSV_Path, strings.ReplaceAll(familyValue, " ", "_")+".csv"))
+	}
+	if raw, err := filepath.Glob(filepath.Join(config.File.CSV_Path, "performanceData_*.csv")); err == nil {
+		files = append(files, raw...)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err == nil {
+			logger.LogInfo("partial file removed", zap.String("file", file))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package derived
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 다른 Family 행을 찾을 때 사용하는 라벨 컬럼 (period 는 항상 함께 비교)
+var joinColumns = map[string]int{
+	"ne_id":    0,
+	"location": 6,
+}
+
+// Sample 계산 결과 한 행 (라벨은 기준 Family 행의 라벨 컬럼)
+type Sample struct {
+	Row   []string
+	Value float64
+}
+
+// JoinColumn JOIN 이름의 컬럼 번호 (기본 location)
+func JoinColumn(join string) (int, error) {
+	if join == "" {
+		join = "location"
+	}
+	index, ok := joinColumns[strings.ToLower(join)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported join %q (ne_id|location)", join)
+	}
+	return index, nil
+}
+
+// Evaluate 기준 Family 의 행마다 계산식을 계산
+// 다른 Family 는 join 컬럼과 period(INIT TIME) 가 같은 행을 사용하고, 없으면 해당 행은 제외한다.
+func Evaluate(expr *Expr, base [][]string, others map[string][][]string, joinColumn int) []Sample {
+	index := map[string]map[string][]string{}
+	for family, data := range others {
+		rows := map[string][]string{}
+		for i := 3; i < len(data); i++ {
+			if len(data[i]) > joinColumn && len(data[i]) > 3 {
+				rows[rowKey(data[i], joinColumn)] = data[i]
+			}
+		}
+		index[family] = rows
+	}
+
+	var samples []Sample
+	for i := 3; i < len(base); i++ {
+		row := base[i]
+		if len(row) < 7 {
+			continue
+		}
+		key := rowKey(row, joinColumn)
+		value, ok := expr.Eval(func(family string, column int) (float64, bool) {
+			if family == "" {
+				return cell(row, column)
+			}
+			target, ok := index[family][key]
+			if !ok {
+				return 0, false
+			}
+			return cell(target, column)
+		})
+		if ok {
+			samples = append(samples, Sample{Row: row, Value: value})
+		}
+	}
+	return samples
+}
+
+func rowKey(row []string, joinColumn int) string {
+	return row[joinColumn] + "|" + row[3]
+}
+
+func cell(row []string, column int) (float64, bool) {
+	if column >= len(row) {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(row[column]), 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
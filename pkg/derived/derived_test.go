@@ -0,0 +1,121 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package derived
+
+import (
+	"reflect"
+	"testing"
+)
+
+func family(rows ...[]string) [][]string {
+	return append([][]string{{"Family name"}, {"Period : 15min"}, {"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "A", "B"}}, rows...)
+}
+
+func row(ne, period, location, a, b string) []string {
+	return []string{ne, "1", "NE", period, "+09:00", "900", location, a, b}
+}
+
+func TestJoinColumn(t *testing.T) {
+	tests := []struct {
+		join  string
+		want  int
+		valid bool
+	}{
+		{"", 6, true},
+		{"location", 6, true},
+		{"NE_ID", 0, true},
+		{"ne_name", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := JoinColumn(tt.join)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("JoinColumn(%q) = %d, %v", tt.join, got, err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	base := family(
+		row("ne101", "2023-11-08 13:15:00", "AMF_01", "100", "4"),
+		row("ne101", "2023-11-08 13:15:00", "AMF_02", "50", "0"),
+		row("ne102", "2023-11-08 13:15:00", "AMF_03", "30", "3"),
+		row("ne101", "2023-11-08 13:00:00", "AMF_01", "80", "2"),
+		row("ne101", "2023-11-08 13:15:00", "AMF_04", "", "1"),
+		[]string{"short"},
+	)
+	others := map[string][][]string{
+		"AMFMS": family(
+			row("ne101", "2023-11-08 13:15:00", "AMF_01", "10", "0"),
+			row("ne101", "2023-11-08 13:15:00", "AMF_02", "5", "0"),
+			// period 가 다른 행은 join 하지 않는다.
+			row("ne102", "2023-11-08 13:00:00", "AMF_03", "3", "0"),
+			row("ne101", "2023-11-08 13:00:00", "AMF_01", "8", "0"),
+		),
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		join string
+		want []Sample
+	}{
+		{
+			name: "own columns",
+			expr: "col(7) / col(8)",
+			want: []Sample{
+				{Row: base[3], Value: 25},
+				{Row: base[5], Value: 10},
+				{Row: base[6], Value: 40},
+			},
+		},
+		{
+			name: "join location and period",
+			expr: `col(7) / col("AMFMS", 7)`,
+			want: []Sample{
+				{Row: base[3], Value: 10},
+				{Row: base[4], Value: 10},
+				{Row: base[6], Value: 10},
+			},
+		},
+		{
+			// ne_id 로 join 하면 같은 ne_id, period 의 마지막 행을 사용
+			name: "join ne_id",
+			expr: `col(7) - col("AMFMS", 7)`,
+			join: "ne_id",
+			want: []Sample{
+				{Row: base[3], Value: 95},
+				{Row: base[4], Value: 45},
+				{Row: base[6], Value: 72},
+			},
+		},
+		{
+			name: "unknown family",
+			expr: `col(7) + col("UECON_AMF", 7)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			joinColumn, err := JoinColumn(tt.join)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Evaluate(expr, base, others, joinColumn)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package derived
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Lookup Family 의 컬럼 값 (family "" 는 기준 Family), 값이 없으면 false
+type Lookup func(family string, column int) (float64, bool)
+
+// Expr 파싱된 계산식
+// 문법: 숫자, col(9), col("Family", 9), + - * /, 괄호
+type Expr struct {
+	root     node
+	families []string
+	refs     []Ref
+}
+
+// Ref 계산식이 참조하는 컬럼 (Family "" 는 기준 Family)
+type Ref struct {
+	Family string
+	Column int
+}
+
+type node interface {
+	eval(lookup Lookup) (float64, bool)
+}
+
+type number float64
+
+type column struct {
+	family string
+	index  int
+}
+
+type unary struct {
+	x node
+}
+
+type binary struct {
+	op   byte
+	l, r node
+}
+
+func (n number) eval(Lookup) (float64, bool) {
+	return float64(n), true
+}
+
+func (c column) eval(lookup Lookup) (float64, bool) {
+	return lookup(c.family, c.index)
+}
+
+func (u unary) eval(lookup Lookup) (float64, bool) {
+	v, ok := u.x.eval(lookup)
+	return -v, ok
+}
+
+// 0 으로 나누면 값 없음 (해당 행은 내보내지 않음)
+func (b binary) eval(lookup Lookup) (float64, bool) {
+	l, ok := b.l.eval(lookup)
+	if !ok {
+		return 0, false
+	}
+	r, ok := b.r.eval(lookup)
+	if !ok {
+		return 0, false
+	}
+	switch b.op {
+	case '+':
+		return l + r, true
+	case '-':
+		return l - r, true
+	case '*':
+		return l * r, true
+	default:
+		if r == 0 {
+			return 0, false
+		}
+		return l / r, true
+	}
+}
+
+// Eval 계산, 참조한 컬럼이 없거나 0 으로 나누거나 결과가 NaN/Inf 이면 false
+func (e *Expr) Eval(lookup Lookup) (float64, bool) {
+	v, ok := e.root.eval(lookup)
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// Families col("Family", N) 로 참조한 다른 Family 목록
+func (e *Expr) Families() []string {
+	return e.families
+}
+
+// Refs 계산식이 참조하는 컬럼 목록
+func (e *Expr) Refs() []Ref {
+	return e.refs
+}
+
+// Parse 계산식 파싱
+func Parse(expr string) (*Expr, error) {
+	p := &parser{input: expr}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.tokens[p.pos].text, expr)
+	}
+	return &Expr{root: root, families: p.families, refs: p.refs}, nil
+}
+
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type parser struct {
+	input    string
+	tokens   []token
+	pos      int
+	families []string
+	refs     []Ref
+}
+
+func (p *parser) tokenize() error {
+	s := p.input
+	for i := 0; i < len(s); {
+		ch := rune(s[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case strings.ContainsRune("+-*/(),", ch):
+			p.tokens = append(p.tokens, token{tokOp, string(ch)})
+			i++
+		case ch == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return fmt.Errorf("unterminated string in %q", s)
+			}
+			p.tokens = append(p.tokens, token{tokString, s[i+1 : i+1+end]})
+			i += end + 2
+		case unicode.IsDigit(ch) || ch == '.':
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			p.tokens = append(p.tokens, token{tokNumber, s[i:j]})
+			i = j
+		case unicode.IsLetter(ch) || ch == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			p.tokens = append(p.tokens, token{tokIdent, s[i:j]})
+			i = j
+		default:
+			return fmt.Errorf("unexpected %q in %q", ch, s)
+		}
+	}
+	return nil
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return token{}, false
+}
+
+func (p *parser) expect(op string) error {
+	t, ok := p.peek()
+	if !ok || t.kind != tokOp || t.text != op {
+		return fmt.Errorf("expected %q in %q", op, p.input)
+	}
+	p.pos++
+	return nil
+}
+
+// expr := term (('+'|'-') term)*
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOp || (t.text != "+" && t.text != "-") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: t.text[0], l: left, r: right}
+	}
+}
+
+// term := unary (('*'|'/') unary)*
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOp || (t.text != "*" && t.text != "/") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: t.text[0], l: left, r: right}
+	}
+}
+
+// unary := '-' unary | primary
+func (p *parser) parseUnary() (node, error) {
+	if t, ok := p.peek(); ok && t.kind == tokOp && t.text == "-" {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+// primary := number | col([string,] int) | '(' expr ')'
+func (p *parser) parsePrimary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of %q", p.input)
+	}
+	p.pos++
+	switch {
+	case t.kind == tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in %q", t.text, p.input)
+		}
+		return number(v), nil
+	case t.kind == tokOp && t.text == "(":
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case t.kind == tokIdent && t.text == "col":
+		return p.parseColumn()
+	}
+	return nil, fmt.Errorf("unexpected %q in %q", t.text, p.input)
+}
+
+func (p *parser) parseColumn() (node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	c := column{}
+	if t, ok := p.peek(); ok && t.kind == tokString {
+		p.pos++
+		c.family = t.text
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		p.addFamily(c.family)
+	}
+	t, ok := p.peek()
+	if !ok || t.kind != tokNumber {
+		return nil, fmt.Errorf("col() needs a column index in %q", p.input)
+	}
+	index, err := strconv.Atoi(t.text)
+	if err != nil || index < 7 {
+		return nil, fmt.Errorf("invalid column %q in %q (value columns start at 7)", t.text, p.input)
+	}
+	p.pos++
+	c.index = index
+	p.refs = append(p.refs, Ref{Family: c.family, Column: index})
+	return c, p.expect(")")
+}
+
+func (p *parser) addFamily(family string) {
+	for _, f := range p.families {
+		if f == family {
+			return
+		}
+	}
+	p.families = append(p.families, family)
+}
@@ -0,0 +1,129 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package derived
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 기준 Family 행 [.., 7:10, 8:4, 9:0, 11:1e308] 과 AMFMS 행 [.., 7:5]
+func lookup(family string, column int) (float64, bool) {
+	values := map[string]map[int]float64{
+		"":      {7: 10, 8: 4, 9: 0, 11: 1e308},
+		"AMFMS": {7: 5},
+	}
+	v, ok := values[family][column]
+	return v, ok
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr  string
+		want  float64
+		valid bool
+	}{
+		// 우선순위
+		{"1 + 2 * 3", 7, true},
+		{"(1 + 2) * 3", 9, true},
+		{"10 - 4 - 3", 3, true},
+		{"24 / 4 / 2", 3, true},
+		{"2 * 3 + 4 * 5", 26, true},
+		{"col(7) - col(8) * 2", 2, true},
+		{"col(7) / col(8) * 100", 250, true},
+		// 단항 -
+		{"-3 + 5", 2, true},
+		{"2 * -3", -6, true},
+		{"--3", 3, true},
+		{"-(col(7) + 2)", -12, true},
+		{"1 - -col(8)", 5, true},
+		// 다른 Family 컬럼
+		{`col("AMFMS", 7) * 2`, 10, true},
+		{`col(7) / col("AMFMS", 7)`, 2, true},
+		{`col("UECON_AMF", 7) + 1`, 0, false},
+		{"col(10)", 0, false},
+		// 0 으로 나누면 값 없음
+		{"col(7) / col(9)", 0, false},
+		{"1 / 0", 0, false},
+		{"0 / 0", 0, false},
+		{"col(7) / (col(8) - 4)", 0, false},
+		{"col(11) * 10", 0, false},
+		{".5 + 0.25", 0.75, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := expr.Eval(lookup)
+			if ok != tt.valid {
+				t.Fatalf("Eval ok = %v, want %v (value %v)", ok, tt.valid, got)
+			}
+			if got != tt.want {
+				t.Errorf("Eval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"1..2", `invalid number "1..2"`},
+		{"col(7) + 1.2.3", `invalid number "1.2.3"`},
+		{`col("AMFMS, 7)`, "unterminated string"},
+		{`col("AMFMS", 7) + "`, "unterminated string"},
+		{"col(7) col(8)", `unexpected "col"`},
+		{"1 2", `unexpected "2"`},
+		{"(1 + 2))", `unexpected ")"`},
+		{"(1 + 2", `expected ")"`},
+		{"col(7", `expected ")"`},
+		{`col("AMFMS" 7)`, `expected ","`},
+		{"col(6)", "value columns start at 7"},
+		{"col(7.5)", "invalid column"},
+		{"col()", "needs a column index"},
+		{"1 +", "unexpected end"},
+		{"", "unexpected end"},
+		{"sum(7)", `unexpected "sum"`},
+		{"col(7) % 2", "unexpected '%'"},
+		{"*2", `unexpected "*"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if err == nil {
+				t.Fatal("Parse succeeded")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %q, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRefs(t *testing.T) {
+	expr, err := Parse(`col(7) / col("AMFMS", 9) + col("AMFMS", 10) - col("Air MAC Packet", 7)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"AMFMS", "Air MAC Packet"}; !reflect.DeepEqual(expr.Families(), want) {
+		t.Errorf("Families = %v, want %v", expr.Families(), want)
+	}
+	want := []Ref{{"", 7}, {"AMFMS", 9}, {"AMFMS", 10}, {"Air MAC Packet", 7}}
+	if !reflect.DeepEqual(expr.Refs(), want) {
+		t.Errorf("Refs = %v, want %v", expr.Refs(), want)
+	}
+}
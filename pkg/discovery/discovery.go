@@ -0,0 +1,264 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/k8sClient"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// informer 재동기화 주기
+const resync = 10 * time.Minute
+
+var routeResource = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+
+// Target app_config 메트릭의 discovery 설정, 찾은 주소로 url 의 scheme, host 를 바꾼다.
+type Target struct {
+	Kind       string // service or route
+	Namespace  string
+	Name       string
+	Port       string // service 포트 이름 또는 번호 (포트가 하나면 생략), route 는 사용 안함
+	Scheme     string // 기본 service: http, route: tls 설정이 있으면 https
+	Kubeconfig string // 비어있으면 in-cluster 설정 (MEC 는 FILE.MEC_CONFIG 경로)
+}
+
+func (t Target) key() string {
+	return strings.Join([]string{strings.ToLower(t.Kind), t.Kubeconfig, t.Namespace, t.Name, t.Port, t.Scheme}, "|")
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s %s/%s", strings.ToLower(t.Kind), t.Namespace, t.Name)
+}
+
+// Validate kind, namespace, name 확인
+func (t Target) Validate() error {
+	switch strings.ToLower(t.Kind) {
+	case "service", "route":
+	default:
+		return fmt.Errorf("discovery kind must be service or route: %q", t.Kind)
+	}
+	if t.Namespace == "" || t.Name == "" {
+		return fmt.Errorf("discovery needs namespace and name")
+	}
+	return nil
+}
+
+// Manager Service, Route 를 감시하여 대상별 주소(scheme://host[:port]) 관리
+type Manager struct {
+	mu        sync.RWMutex
+	endpoints map[string]string
+	watching  map[string]bool
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		endpoints: map[string]string{},
+		watching:  map[string]bool{},
+	}
+}
+
+// Watch target 감시 시작 (같은 target 은 한번만), ctx 가 끝나면 중단
+func (m *Manager) Watch(ctx context.Context, target Target) error {
+	if err := target.Validate(); err != nil {
+		return err
+	}
+	key := target.key()
+	m.mu.RLock()
+	watching := m.watching[key]
+	m.mu.RUnlock()
+	if watching {
+		return nil
+	}
+
+	config, err := k8sClient.NewConfig(target.Kubeconfig)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	client := k8sClient.NewK8sClient(*config, clientset)
+
+	// 이름이 같은 리소스 하나만 조회
+	tweak := func(options *v1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", target.Name).String()
+	}
+
+	var informer cache.SharedIndexInformer
+	var start func(stopCh <-chan struct{})
+	var endpoint func(obj interface{}) (string, error)
+	switch strings.ToLower(target.Kind) {
+	case "service":
+		factory := informers.NewSharedInformerFactoryWithOptions(client.Clientset, resync,
+			informers.WithNamespace(target.Namespace), informers.WithTweakListOptions(tweak))
+		informer = factory.Core().V1().Services().Informer()
+		endpoint = func(obj interface{}) (string, error) {
+			svc, ok := obj.(*corev1.Service)
+			if !ok {
+				return "", fmt.Errorf("unexpected object %T", obj)
+			}
+			return serviceEndpoint(svc, target)
+		}
+		start = factory.Start
+	case "route":
+		dynamicClient, err := dynamic.NewForConfig(client.Config)
+		if err != nil {
+			return err
+		}
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, resync, target.Namespace, tweak)
+		informer = factory.ForResource(routeResource).Informer()
+		endpoint = func(obj interface{}) (string, error) {
+			route, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return "", fmt.Errorf("unexpected object %T", obj)
+			}
+			return routeEndpoint(route, target)
+		}
+		start = factory.Start
+	}
+
+	update := func(obj interface{}) {
+		addr, err := endpoint(obj)
+		if err != nil {
+			logger.LogErr(target.String()+" discovery", err)
+			m.set(target, "")
+			return
+		}
+		m.set(target, addr)
+	}
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    update,
+		UpdateFunc: func(_, obj interface{}) { update(obj) },
+		DeleteFunc: func(interface{}) { m.set(target, "") },
+	}); err != nil {
+		return err
+	}
+	start(ctx.Done())
+	m.mu.Lock()
+	m.watching[key] = true
+	m.mu.Unlock()
+	return nil
+}
+
+// set 주소 변경 기록, "" 이면 static url 사용
+func (m *Manager) set(target Target, addr string) {
+	key := target.key()
+	m.mu.Lock()
+	prev := m.endpoints[key]
+	if addr == "" {
+		delete(m.endpoints, key)
+	} else {
+		m.endpoints[key] = addr
+	}
+	m.mu.Unlock()
+
+	switch {
+	case prev == addr:
+	case addr == "":
+		logger.LogWarn("discovery target removed, using static url", zap.String("target", target.String()))
+	default:
+		logger.LogInfo("discovery target changed", zap.String("target", target.String()), zap.String("endpoint", addr))
+	}
+}
+
+// URL static 의 scheme, host 를 찾은 주소로 바꾼 URL (찾지 못했으면 static)
+func (m *Manager) URL(target Target, static string) string {
+	if m == nil {
+		return static
+	}
+	m.mu.RLock()
+	addr, ok := m.endpoints[target.key()]
+	m.mu.RUnlock()
+	if !ok {
+		return static
+	}
+	u, err := url.Parse(static)
+	if err != nil {
+		return static
+	}
+	found, err := url.Parse(addr)
+	if err != nil {
+		return static
+	}
+	u.Scheme, u.Host = found.Scheme, found.Host
+	return u.String()
+}
+
+// serviceEndpoint 클러스터 DNS 주소 (name.namespace.svc:port)
+func serviceEndpoint(svc *corev1.Service, target Target) (string, error) {
+	var port int32
+	switch {
+	case target.Port == "" && len(svc.Spec.Ports) == 1:
+		port = svc.Spec.Ports[0].Port
+	case target.Port == "":
+		return "", fmt.Errorf("service has %d ports, set discovery port", len(svc.Spec.Ports))
+	default:
+		number, _ := strconv.Atoi(target.Port)
+		for _, p := range svc.Spec.Ports {
+			if p.Name == target.Port || int(p.Port) == number {
+				port = p.Port
+				break
+			}
+		}
+		if port == 0 {
+			return "", fmt.Errorf("service has no port %q", target.Port)
+		}
+	}
+	scheme := target.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s.%s.svc:%d", scheme, svc.Name, svc.Namespace, port), nil
+}
+
+// routeEndpoint route host (spec.host, 없으면 status.ingress[0].host), tls 설정이 있으면 https
+func routeEndpoint(route *unstructured.Unstructured, target Target) (string, error) {
+	host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+	if host == "" {
+		ingress, _, _ := unstructured.NestedSlice(route.Object, "status", "ingress")
+		if len(ingress) > 0 {
+			if first, ok := ingress[0].(map[string]interface{}); ok {
+				host, _, _ = unstructured.NestedString(first, "host")
+			}
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("route has no host")
+	}
+	scheme := target.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if tls, ok, _ := unstructured.NestedMap(route.Object, "spec", "tls"); ok && tls != nil {
+			scheme = "https"
+		}
+	}
+	return scheme + "://" + host, nil
+}
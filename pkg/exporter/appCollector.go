@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"github.com/pkg/errors"
@@ -9,8 +10,10 @@ import (
 	"io"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/discovery"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/k8sClient"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,12 +21,16 @@ import (
 
 // Collector metric groups
 type Collector struct {
+	// 같은 이름의 메트릭을 collect 간에 하나의 descriptor 로 합치고 const_labels 를 라벨로 구분
+	Merge    bool
 	Collects []Collect
 }
 
 // Collect collect structure
 type Collect struct {
 	Metrics Metrics
+	// collect 의 모든 메트릭에 붙이는 라벨 (cluster, site ...)
+	Const_Labels map[string]string
 }
 
 // Metrics metric map
@@ -37,12 +44,162 @@ type Metric struct {
 	Description string
 	Url         string
 	Labels      []string
-	MetricDesc  *prometheus.Desc
+	// 메트릭 전체 이름 (prefix 를 붙이지 않음), 없으면 prefix_메트릭키
+	Rename_To string
+	// url 의 scheme, host 를 Service, Route 에서 찾기 (찾기 전이나 실패시 url 사용)
+	Discovery  *discovery.Target
+	MetricDesc *prometheus.Desc
+	// MetricDesc 의 Labels 뒤에 추가된 라벨 값 (merge 시 const_labels)
+	LabelValues []string `yaml:"-"`
+}
+
+// FQName 내보낼 메트릭 이름
+func (m *Metric) FQName(key string) string {
+	if m.Rename_To != "" {
+		return m.Rename_To
+	}
+	return prometheus.BuildFQName(m.Prefix, "", key)
+}
+
+// BuildDescriptors collect 별 메트릭 descriptor 생성
+// merge 가 아니면 const_labels 를 고정 라벨로 붙이고, merge 면 같은 이름끼리 descriptor 하나를 공유하며
+// collect 들의 const_labels 키를 라벨로 추가한다. (값이 없는 collect 는 빈 값)
+// 라벨 구성이 다른 같은 이름의 메트릭은 합치지 않는다.
+func (c *Collector) BuildDescriptors() {
+	if !c.Merge {
+		for _, collect := range c.Collects {
+			for key, metric := range collect.Metrics {
+				metric.MetricDesc = prometheus.NewDesc(metric.FQName(key), metric.Description, metric.Labels, collect.Const_Labels)
+				metric.LabelValues = nil
+			}
+		}
+		return
+	}
+
+	type group struct {
+		help   string
+		labels []string
+		extra  []string
+		desc   *prometheus.Desc
+	}
+	groups := map[string]*group{}
+	for _, collect := range c.Collects {
+		for key, metric := range collect.Metrics {
+			name := metric.FQName(key)
+			g, ok := groups[name]
+			if !ok {
+				g = &group{help: metric.Description, labels: metric.Labels}
+				groups[name] = g
+			}
+			if !sameLabels(g.labels, metric.Labels) {
+				continue
+			}
+			for label := range collect.Const_Labels {
+				if !containsLabel(g.extra, label) {
+					g.extra = append(g.extra, label)
+				}
+			}
+		}
+	}
+	for name, g := range groups {
+		sort.Strings(g.extra)
+		g.desc = prometheus.NewDesc(name, g.help, append(append([]string{}, g.labels...), g.extra...), nil)
+	}
+
+	for _, collect := range c.Collects {
+		for key, metric := range collect.Metrics {
+			name := metric.FQName(key)
+			g := groups[name]
+			if !sameLabels(g.labels, metric.Labels) {
+				logger.LogWarn("metric labels differ, not merged", zap.String("metric", name))
+				metric.MetricDesc = prometheus.NewDesc(name, metric.Description, metric.Labels, collect.Const_Labels)
+				metric.LabelValues = nil
+				continue
+			}
+			metric.MetricDesc = g.desc
+			metric.LabelValues = make([]string, 0, len(g.extra))
+			for _, label := range g.extra {
+				metric.LabelValues = append(metric.LabelValues, collect.Const_Labels[label])
+			}
+		}
+	}
+}
+
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenSource p5g_mec 경로의 Thanos 요청에 사용하는 bearer token
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// MecTokenSource FILE.MEC_CONFIG kubeconfig 로 openshift-monitoring/prometheus-k8s token 생성
+type MecTokenSource struct{}
+
+func (MecTokenSource) Token(ctx context.Context) (string, error) {
+	ymlConfig := cfg.InitConfig()
+	// openshift mec client
+	k8sclient, _ := k8sClient.CreateCustomClientSet(ymlConfig.File.MEC_CONFIG)
+
+	// service access token 토큰만들기
+	return k8sClient.CreateToken(ctx, k8sclient, "openshift-monitoring", "prometheus-k8s")
+}
+
+// StaticToken 고정 token (fixture 재생, 테스트 등 MEC 접속이 없는 경우)
+type StaticToken string
+
+func (s StaticToken) Token(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+// app_config discovery 감시 (nil 이면 url 그대로 사용)
+var discoverer *discovery.Manager
+
+// SetDiscovery discovery 감시 설정
+func SetDiscovery(m *discovery.Manager) {
+	discoverer = m
+}
+
+// Thanos 요청 transport (nil 이면 NewTransport)
+var transport http.RoundTripper
+
+// SetTransport Thanos 요청 transport 교체 (fixture 기록/재생)
+func SetTransport(rt http.RoundTripper) {
+	transport = rt
+}
+
+// NewTransport 인증서 검증을 하지 않는 기본 transport
+func NewTransport() http.RoundTripper {
+	return &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
 }
 
 type DeviceCollector struct {
 	Collects   []Collect
 	StatusDesc *prometheus.Desc
+	// 종료시 취소되는 context (nil 이면 context.Background)
+	Context context.Context
+	// p5g_mec token (nil 이면 MecTokenSource)
+	Tokens TokenSource
 }
 
 // Describe prometheus describe
@@ -51,26 +208,30 @@ func (c *DeviceCollector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect prometheus collect
 func (c *DeviceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tokens := c.Tokens
+	if tokens == nil {
+		tokens = MecTokenSource{}
+	}
 	for _, instance := range c.Collects {
 		for _, value := range instance.Metrics {
 			switch value.Prefix {
 			case "p5g_mec":
-				ymlConfig := cfg.InitConfig()
-				// openshift mec client
-				k8sclient, _ := k8sClient.CreateCustomClientSet(ymlConfig.File.MEC_CONFIG)
-
 				// service access token 토큰만들기
-				token, err := k8sClient.CreateToken(k8sclient, "openshift-monitoring", "prometheus-k8s")
+				token, err := tokens.Token(ctx)
 				if err != nil {
 					logger.LogErr("mecCPU k8s client Token error", err)
 				}
-				err = c.scrape(value, ch, token)
+				err = c.scrape(ctx, value, ch, token)
 				if err != nil {
 					logger.LogErr("Scrpe Error : ", err)
 					continue
 				}
 			default:
-				err := c.scrape(value, ch, "")
+				err := c.scrape(ctx, value, ch, "")
 				if err != nil {
 					logger.LogErr("Scrpe Error : ", err)
 					continue
@@ -82,17 +243,22 @@ func (c *DeviceCollector) Collect(ch chan<- prometheus.Metric) {
 }
 
 // scrape connnect to database and gather query result
-func (c *DeviceCollector) scrape(m *Metric, ch chan<- prometheus.Metric, token string) error {
+func (c *DeviceCollector) scrape(ctx context.Context, m *Metric, ch chan<- prometheus.Metric, token string) error {
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	tr := transport
+	if tr == nil {
+		tr = NewTransport()
 	}
 
 	client := &http.Client{
 		Transport: tr,
 		Timeout:   5 * time.Second,
 	}
-	req, err := http.NewRequest("GET", m.Url, nil)
+	queryURL := m.Url
+	if m.Discovery != nil {
+		queryURL = discoverer.URL(*m.Discovery, m.Url)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", queryURL, nil)
 	if err != nil {
 		logger.LogErr("request Error ", err)
 	}
@@ -126,6 +292,8 @@ func (c *DeviceCollector) scrape(m *Metric, ch chan<- prometheus.Metric, token s
 			labelVals = []string{metric.Container, metric.Namespace, metric.Node, metric.Pod}
 		}
 
+		labelVals = append(labelVals, m.LabelValues...)
+
 		// value 파싱
 		data, err := strconv.ParseFloat(value, 64)
 		if err != nil {
@@ -0,0 +1,44 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sync"
+)
+
+// Snapshot 마지막 수집 주기에서 만든 메트릭을 보관하는 collector
+// Collect 시 다시 수집하지 않고 보관된 메트릭만 내보낸다. (push 전송용)
+type Snapshot struct {
+	mu      sync.RWMutex
+	metrics []prometheus.Metric
+}
+
+// Set 수집 주기가 끝나면 메트릭 교체
+func (s *Snapshot) Set(metrics []prometheus.Metric) {
+	s.mu.Lock()
+	s.metrics = metrics
+	s.mu.Unlock()
+}
+
+// Describe prometheus describe
+func (s *Snapshot) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect prometheus collect
+func (s *Snapshot) Collect(ch chan<- prometheus.Metric) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, metric := range s.metrics {
+		ch <- metric
+	}
+}
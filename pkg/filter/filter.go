@@ -0,0 +1,212 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package filter
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CSV 라벨 컬럼 (0~6)
+var labelColumns = map[string]int{
+	"ne_id":       0,
+	"system_id":   1,
+	"ne_name":     2,
+	"init_name":   3,
+	"time_offset": 4,
+	"gran_period": 5,
+	"location":    6,
+}
+
+// Match 라벨 조건, regex 또는 values(정확히 일치) 중 하나라도 맞으면 일치
+type Match struct {
+	Regex  string
+	Values []string
+
+	re *regexp.Regexp
+}
+
+// Rule cnf_config.yml 의 filters 항목
+// Families 가 비어있으면 모든 Family 에 적용한다.
+// Include 의 모든 라벨 조건을 만족하고 Exclude 의 라벨 조건에 하나도 맞지 않는 행만 남긴다.
+type Rule struct {
+	Families  []string
+	Include   map[string]*Match
+	Exclude   map[string]*Match
+	Drop_Zero bool // 모든 값 컬럼이 0 인 행 제외
+}
+
+// Filter 수집시 행 필터와 제외한 행 수 메트릭
+type Filter struct {
+	rules []Rule
+
+	mu      sync.Mutex
+	dropped map[string]float64
+	desc    *prometheus.Desc
+}
+
+func New(rules []Rule) (*Filter, error) {
+	for i := range rules {
+		for _, matches := range []map[string]*Match{rules[i].Include, rules[i].Exclude} {
+			for label, match := range matches {
+				if _, ok := labelColumns[label]; !ok {
+					return nil, fmt.Errorf("filters[%d]: unknown label %q", i, label)
+				}
+				if match == nil {
+					return nil, fmt.Errorf("filters[%d]: %s needs regex or values", i, label)
+				}
+				if match.Regex != "" {
+					re, err := regexp.Compile(match.Regex)
+					if err != nil {
+						return nil, fmt.Errorf("filters[%d]: %s: %v", i, label, err)
+					}
+					match.re = re
+				}
+			}
+		}
+	}
+	return &Filter{
+		rules:   rules,
+		dropped: map[string]float64{},
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "filter", "dropped_rows_total"),
+			"OSS CSV rows dropped by the cnf_config filters",
+			[]string{"family"}, nil,
+		),
+	}, nil
+}
+
+// Apply 헤더(0~2행)와 조건에 맞는 행만 남긴 데이터와 제외한 행 수를 반환 (원본은 변경하지 않음)
+func (f *Filter) Apply(family string, csvData [][]string) ([][]string, int) {
+	if f == nil || len(csvData) <= 3 {
+		return csvData, 0
+	}
+	var rules []Rule
+	for _, rule := range f.rules {
+		if rule.applies(family) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return csvData, 0
+	}
+
+	kept := append(make([][]string, 0, len(csvData)), csvData[:3]...)
+	for _, row := range csvData[3:] {
+		keep := true
+		for _, rule := range rules {
+			if !rule.keep(row) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, row)
+		}
+	}
+	return kept, len(csvData) - len(kept)
+}
+
+// Count 수집 주기에서 제외한 행 수 기록
+func (f *Filter) Count(family string, dropped int) {
+	if f == nil || dropped == 0 {
+		return
+	}
+	f.mu.Lock()
+	f.dropped[family] += float64(dropped)
+	f.mu.Unlock()
+}
+
+// Family 이름의 공백은 파일명과 같이 '_' 로 보고 비교 ("Air MAC Packet" == "Air_MAC_Packet")
+func (r Rule) applies(family string) bool {
+	if len(r.Families) == 0 {
+		return true
+	}
+	family = strings.ReplaceAll(family, " ", "_")
+	for _, f := range r.Families {
+		if strings.ReplaceAll(f, " ", "_") == family {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) keep(row []string) bool {
+	for label, match := range r.Include {
+		if !match.matches(value(row, label)) {
+			return false
+		}
+	}
+	for label, match := range r.Exclude {
+		if match.matches(value(row, label)) {
+			return false
+		}
+	}
+	if r.Drop_Zero && allZero(row) {
+		return false
+	}
+	return true
+}
+
+func (m *Match) matches(value string) bool {
+	for _, v := range m.Values {
+		if v == value {
+			return true
+		}
+	}
+	return m.re != nil && m.re.MatchString(value)
+}
+
+func value(row []string, label string) string {
+	column := labelColumns[label]
+	if column >= len(row) {
+		return ""
+	}
+	return row[column]
+}
+
+// 숫자 값 컬럼이 하나 이상 있고 모두 0 이면 true
+func allZero(row []string) bool {
+	if len(row) <= 7 {
+		return false
+	}
+	numeric := false
+	for _, cell := range row[7:] {
+		val, err := strconv.ParseFloat(strings.TrimSpace(cell), 64)
+		if err != nil {
+			continue
+		}
+		if val != 0 {
+			return false
+		}
+		numeric = true
+	}
+	return numeric
+}
+
+// Describe prometheus describe
+func (f *Filter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- f.desc
+}
+
+// Collect prometheus collect
+func (f *Filter) Collect(ch chan<- prometheus.Metric) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for family, dropped := range f.dropped {
+		ch <- prometheus.MustNewConstMetric(f.desc, prometheus.CounterValue, dropped, family)
+	}
+}
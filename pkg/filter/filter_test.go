@@ -0,0 +1,213 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package filter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func row(ne, location string, values ...string) []string {
+	return append([]string{ne, "1", "NE-" + ne, "2023-11-08 13:15:00", "+09:00", "900", location}, values...)
+}
+
+var data = [][]string{
+	{"Family name : Air MAC Packet"},
+	{"Period : 15min"},
+	{"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "UL", "DL"},
+	row("ne101", "DU_01", "10", "20"),
+	row("ne101", "DU_02", "0", "0"),
+	row("ne102", "DU_03", "5", "0"),
+	row("ne102", "TEST_DU_04", "1", "1"),
+	row("ne103", "DU_05", "0", "-"),
+	row("ne103", "DU_06"),
+}
+
+// locations Apply 결과 행의 location 목록
+func locations(data [][]string) []string {
+	var result []string
+	for _, row := range data[3:] {
+		result = append(result, row[6])
+	}
+	return result
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		family  string
+		want    []string
+		dropped int
+	}{
+		{
+			name:   "no rules",
+			family: "Air_MAC_Packet",
+			want:   []string{"DU_01", "DU_02", "DU_03", "TEST_DU_04", "DU_05", "DU_06"},
+		},
+		{
+			name:    "include values",
+			rules:   []Rule{{Include: map[string]*Match{"ne_id": {Values: []string{"ne101", "ne103"}}}}},
+			family:  "Air_MAC_Packet",
+			want:    []string{"DU_01", "DU_02", "DU_05", "DU_06"},
+			dropped: 2,
+		},
+		{
+			name:    "include every label",
+			rules:   []Rule{{Include: map[string]*Match{"ne_id": {Values: []string{"ne102"}}, "location": {Regex: "^DU_"}}}},
+			family:  "Air_MAC_Packet",
+			want:    []string{"DU_03"},
+			dropped: 5,
+		},
+		{
+			// include 와 exclude 에 모두 맞으면 exclude 가 우선
+			name: "exclude wins over include",
+			rules: []Rule{{
+				Include: map[string]*Match{"location": {Regex: "DU_0[1-4]"}},
+				Exclude: map[string]*Match{"location": {Regex: "^TEST_"}, "ne_id": {Values: []string{"ne101"}}},
+			}},
+			family:  "Air_MAC_Packet",
+			want:    []string{"DU_03"},
+			dropped: 5,
+		},
+		{
+			name:    "regex or values",
+			rules:   []Rule{{Include: map[string]*Match{"location": {Regex: "_0[12]$", Values: []string{"DU_05"}}}}},
+			family:  "Air_MAC_Packet",
+			want:    []string{"DU_01", "DU_02", "DU_05"},
+			dropped: 3,
+		},
+		{
+			// 값 컬럼이 모두 0 인 행만 제외 (숫자가 아닌 값은 무시, 값 컬럼이 없으면 유지)
+			name:    "drop zero",
+			rules:   []Rule{{Drop_Zero: true}},
+			family:  "Air_MAC_Packet",
+			want:    []string{"DU_01", "DU_03", "TEST_DU_04", "DU_06"},
+			dropped: 2,
+		},
+		{
+			name:    "family with spaces in rule",
+			rules:   []Rule{{Families: []string{"Air MAC Packet"}, Exclude: map[string]*Match{"ne_id": {Values: []string{"ne101"}}}}},
+			family:  "Air_MAC_Packet",
+			want:    []string{"DU_03", "TEST_DU_04", "DU_05", "DU_06"},
+			dropped: 2,
+		},
+		{
+			name:    "family with spaces in name",
+			rules:   []Rule{{Families: []string{"Air_MAC_Packet"}, Exclude: map[string]*Match{"ne_id": {Values: []string{"ne101"}}}}},
+			family:  "Air MAC Packet",
+			want:    []string{"DU_03", "TEST_DU_04", "DU_05", "DU_06"},
+			dropped: 2,
+		},
+		{
+			name:   "other family",
+			rules:  []Rule{{Families: []string{"AMFTPS"}, Drop_Zero: true}},
+			family: "Air_MAC_Packet",
+			want:   []string{"DU_01", "DU_02", "DU_03", "TEST_DU_04", "DU_05", "DU_06"},
+		},
+		{
+			// 여러 rule 은 모두 만족해야 유지
+			name: "rules combined",
+			rules: []Rule{
+				{Exclude: map[string]*Match{"location": {Regex: "^TEST_"}}},
+				{Families: []string{"Air MAC Packet"}, Drop_Zero: true},
+				{Families: []string{"AMFTPS"}, Include: map[string]*Match{"ne_id": {Values: []string{"none"}}}},
+			},
+			family:  "Air_MAC_Packet",
+			want:    []string{"DU_01", "DU_03", "DU_06"},
+			dropped: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kept, dropped := f.Apply(tt.family, data)
+			if got := locations(kept); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+			if dropped != tt.dropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.dropped)
+			}
+			if !reflect.DeepEqual(kept[:3], data[:3]) {
+				t.Errorf("header = %v", kept[:3])
+			}
+		})
+	}
+}
+
+func TestApplyNil(t *testing.T) {
+	var f *Filter
+	kept, dropped := f.Apply("AMFTPS", data)
+	if len(kept) != len(data) || dropped != 0 {
+		t.Errorf("nil filter Apply = %d rows, %d dropped", len(kept), dropped)
+	}
+	f.Count("AMFTPS", 3)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		err   string
+	}{
+		{"unknown label", []Rule{{Include: map[string]*Match{"cell_id": {Values: []string{"1"}}}}}, `filters[0]: unknown label "cell_id"`},
+		{"empty match", []Rule{{}, {Exclude: map[string]*Match{"location": nil}}}, "filters[1]: location needs regex or values"},
+		{"invalid regex", []Rule{{Include: map[string]*Match{"ne_id": {Regex: "("}}}}, "filters[0]: ne_id: error parsing regexp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rules)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// 제외한 행 수는 Family 별로 누적
+func TestDroppedCounter(t *testing.T) {
+	f, err := New([]Rule{{Drop_Zero: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		_, dropped := f.Apply("Air_MAC_Packet", data)
+		f.Count("Air_MAC_Packet", dropped)
+	}
+	f.Count("AMFTPS", 1)
+	f.Count("UECON_AMF", 0)
+
+	ch := make(chan prometheus.Metric, 10)
+	f.Collect(ch)
+	close(ch)
+
+	got := map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		got[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+		if desc := metric.Desc().String(); !strings.Contains(desc, "cnf_exporter_filter_dropped_rows_total") {
+			t.Errorf("desc = %s", desc)
+		}
+	}
+	if want := map[string]float64{"Air_MAC_Packet": 4, "AMFTPS": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dropped = %v, want %v", got, want)
+	}
+}
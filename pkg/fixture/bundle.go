@@ -0,0 +1,118 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fixture bundle 구성
+//
+//	<dir>/manifest.json   요청 메타데이터 (기록 순서)
+//	<dir>/oss/0001.csv    OSS pod 에서 가져온 CSV
+//	<dir>/promql/0001.json Thanos 응답
+const manifestFile = "manifest.json"
+
+// Manifest fixture bundle 의 요청 목록
+type Manifest struct {
+	Created    time.Time     `json:"created"`
+	Anonymized bool          `json:"anonymized"`
+	OSS        []OSSEntry    `json:"oss"`
+	PromQL     []PromQLEntry `json:"promql"`
+}
+
+// OSSEntry OSS performanceData 요청 한 건
+type OSSEntry struct {
+	Site      string    `json:"site,omitempty"`
+	Family    string    `json:"family"`
+	StartTime string    `json:"startTime"`
+	EndTime   string    `json:"endTime"`
+	Response  string    `json:"response"`       // curl 응답 (OSS pod 안의 CSV 경로)
+	File      string    `json:"file,omitempty"` // bundle 안의 CSV (복사 실패시 "")
+	Error     string    `json:"error,omitempty"`
+	Recorded  time.Time `json:"recorded"`
+}
+
+// PromQLEntry Thanos 요청 한 건 (Authorization 헤더는 기록하지 않음)
+type PromQLEntry struct {
+	URL      string    `json:"url"`
+	Query    string    `json:"query"`
+	Status   int       `json:"status"`
+	File     string    `json:"file,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Recorded time.Time `json:"recorded"`
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeManifest 임시 파일에 쓴 뒤 rename (기록 중 종료되어도 읽을 수 있는 manifest 유지)
+func writeManifest(dir string, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, manifestFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, manifestFile))
+}
+
+// anonymize 데이터 행(3행부터)의 ne_name(2), location(6) 컬럼을 hash 값으로 바꾼다.
+// 같은 값은 항상 같은 hash 가 되므로 Family 간 location join 은 유지된다.
+func anonymize(data []byte) []byte {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return data
+	}
+	for i := 3; i < len(rows); i++ {
+		if len(rows[i]) > 2 {
+			rows[i][2] = pseudonym("ne", rows[i][2])
+		}
+		if len(rows[i]) > 6 {
+			rows[i][6] = pseudonym("loc", rows[i][6])
+		}
+	}
+	var out bytes.Buffer
+	writer := csv.NewWriter(&out)
+	if err := writer.WriteAll(rows); err != nil {
+		return data
+	}
+	return out.Bytes()
+}
+
+func pseudonym(prefix, value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return prefix + "-" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,194 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/curl"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recorder OSS 응답, CSV, Thanos 응답을 fixture bundle 에 기록
+// 기존 bundle 이 있으면 이어서 기록한다.
+type Recorder struct {
+	dir       string
+	anonymize bool
+
+	mu       sync.Mutex
+	manifest *Manifest
+	// OSS 응답 경로 → manifest.OSS index (Retrieve 시 CSV 연결)
+	pending map[string]int
+}
+
+func NewRecorder(dir string, anonymize bool) (*Recorder, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("FIXTURE.DIR is empty")
+	}
+	for _, sub := range []string{"oss", "promql"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	manifest, err := readManifest(dir)
+	if os.IsNotExist(err) {
+		manifest, err = &Manifest{Created: time.Now(), Anonymized: anonymize}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Anonymized != anonymize {
+		return nil, fmt.Errorf("%s was recorded with anonymize=%t", dir, manifest.Anonymized)
+	}
+	return &Recorder{dir: dir, anonymize: anonymize, manifest: manifest, pending: map[string]int{}}, nil
+}
+
+// Client inner 의 OSS 요청과 응답 기록
+func (r *Recorder) Client(inner curl.OssClient) curl.OssClient {
+	return &recordClient{r: r, inner: inner}
+}
+
+// Retriever inner 로 가져온 CSV 기록 (inner 가 nil 이면 처음 사용할 때 PodRetriever 생성)
+func (r *Recorder) Retriever(inner curl.Retriever) curl.Retriever {
+	return &recordRetriever{r: r, inner: inner}
+}
+
+// Transport inner 의 Thanos 요청과 응답 기록
+func (r *Recorder) Transport(inner http.RoundTripper) http.RoundTripper {
+	return &recordTransport{r: r, inner: inner}
+}
+
+type recordClient struct {
+	r     *Recorder
+	inner curl.OssClient
+}
+
+func (c *recordClient) PerformanceData(ctx context.Context, config cfg.Config, familyName, startTime, endTime string) ([]byte, error) {
+	output, err := c.inner.PerformanceData(ctx, config, familyName, startTime, endTime)
+	entry := OSSEntry{
+		Site:      config.Site,
+		Family:    familyName,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Response:  string(output),
+		Recorded:  time.Now(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.manifest.OSS = append(c.r.manifest.OSS, entry)
+	if err == nil {
+		c.r.pending[string(output)] = len(c.r.manifest.OSS) - 1
+	}
+	c.r.save()
+	return output, err
+}
+
+type recordRetriever struct {
+	r     *Recorder
+	inner curl.Retriever
+	once  sync.Once
+}
+
+func (rr *recordRetriever) Retrieve(ctx context.Context, config cfg.Config, srcPath, destDir string) error {
+	rr.once.Do(func() {
+		if rr.inner == nil {
+			rr.inner = curl.NewPodRetriever()
+		}
+	})
+	if err := rr.inner.Retrieve(ctx, config, srcPath, destDir); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, path.Base(srcPath)))
+	if err != nil {
+		logger.LogErr("fixture csv read failed : "+srcPath, err)
+		return nil
+	}
+	if rr.r.anonymize {
+		data = anonymize(data)
+	}
+
+	rr.r.mu.Lock()
+	defer rr.r.mu.Unlock()
+	index, ok := rr.r.pending[srcPath]
+	if !ok {
+		return nil
+	}
+	delete(rr.r.pending, srcPath)
+	file := fmt.Sprintf("oss/%04d.csv", index+1)
+	if err := os.WriteFile(filepath.Join(rr.r.dir, file), data, 0644); err != nil {
+		logger.LogErr("fixture csv write failed : "+file, err)
+		return nil
+	}
+	rr.r.manifest.OSS[index].File = file
+	rr.r.save()
+	return nil
+}
+
+type recordTransport struct {
+	r     *Recorder
+	inner http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.inner.RoundTrip(req)
+	entry := PromQLEntry{
+		URL:      req.URL.String(),
+		Query:    req.URL.Query().Get("query"),
+		Recorded: time.Now(),
+	}
+	var body []byte
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	t.r.mu.Lock()
+	defer t.r.mu.Unlock()
+	if body != nil {
+		entry.File = fmt.Sprintf("promql/%04d.json", len(t.r.manifest.PromQL)+1)
+		if werr := os.WriteFile(filepath.Join(t.r.dir, entry.File), body, 0644); werr != nil {
+			logger.LogErr("fixture promql write failed : "+entry.File, werr)
+			entry.File = ""
+		}
+	}
+	t.r.manifest.PromQL = append(t.r.manifest.PromQL, entry)
+	t.r.save()
+	return resp, err
+}
+
+// save mu 를 잡은 상태에서 호출
+func (r *Recorder) save() {
+	if err := writeManifest(r.dir, r.manifest); err != nil {
+		logger.LogErr("fixture manifest write failed", err)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Replayer fixture bundle 로 OSS, pod 파일 복사, Thanos 응답을 대신한다.
+// curl.OssClient, curl.Retriever, http.RoundTripper 를 구현한다.
+// 요청마다 같은 사이트/Family (Thanos 는 같은 URL) 의 다음 기록을 순서대로 응답하고,
+// 기록을 모두 사용하면 마지막 기록을 계속 응답한다. 수집 기간은 비교하지 않는다.
+type Replayer struct {
+	dir      string
+	manifest *Manifest
+
+	mu     sync.Mutex
+	oss    map[string][]int // site|family → manifest.OSS index
+	promql map[string][]int // path?query → manifest.PromQL index
+	cursor map[string]int
+	files  map[string]string // OSS 응답 경로 → bundle 파일
+}
+
+// Open fixture bundle 열기
+func Open(dir string) (*Replayer, error) {
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{
+		dir:      dir,
+		manifest: manifest,
+		oss:      map[string][]int{},
+		promql:   map[string][]int{},
+		cursor:   map[string]int{},
+		files:    map[string]string{},
+	}
+	for i, entry := range manifest.OSS {
+		key := ossKey(entry.Site, entry.Family)
+		r.oss[key] = append(r.oss[key], i)
+	}
+	for i, entry := range manifest.PromQL {
+		key, err := urlKey(entry.URL)
+		if err != nil {
+			return nil, err
+		}
+		r.promql[key] = append(r.promql[key], i)
+	}
+	return r, nil
+}
+
+// Manifest bundle 요청 목록
+func (r *Replayer) Manifest() *Manifest {
+	return r.manifest
+}
+
+func (r *Replayer) PerformanceData(ctx context.Context, config cfg.Config, familyName, startTime, endTime string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := ossKey(config.Site, familyName)
+	index, ok := r.next("oss|"+key, r.oss[key])
+	if !ok {
+		return nil, fmt.Errorf("fixture has no OSS response for %s", key)
+	}
+	entry := r.manifest.OSS[index]
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	if entry.File != "" {
+		r.files[entry.Response] = entry.File
+	}
+	return []byte(entry.Response), nil
+}
+
+func (r *Replayer) Retrieve(ctx context.Context, config cfg.Config, srcPath, destDir string) error {
+	r.mu.Lock()
+	file, ok := r.files[srcPath]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("fixture has no file for %s", srcPath)
+	}
+	data, err := os.ReadFile(filepath.Join(r.dir, file))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(destDir, path.Base(srcPath)), data, 0644)
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, _ := urlKey(req.URL.String())
+	r.mu.Lock()
+	index, ok := r.next("promql|"+key, r.promql[key])
+	r.mu.Unlock()
+	if !ok {
+		return response(req, http.StatusNotFound, []byte("fixture has no response for "+key)), nil
+	}
+	entry := r.manifest.PromQL[index]
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	var body []byte
+	if entry.File != "" {
+		var err error
+		if body, err = os.ReadFile(filepath.Join(r.dir, entry.File)); err != nil {
+			return nil, err
+		}
+	}
+	return response(req, entry.Status, body), nil
+}
+
+// next mu 를 잡은 상태에서 호출
+func (r *Replayer) next(key string, indexes []int) (int, bool) {
+	if len(indexes) == 0 {
+		return 0, false
+	}
+	cursor := r.cursor[key]
+	if cursor >= len(indexes) {
+		cursor = len(indexes) - 1
+	}
+	r.cursor[key] = cursor + 1
+	return indexes[cursor], true
+}
+
+func response(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func ossKey(site, family string) string {
+	return site + "|" + family
+}
+
+// urlKey host 를 제외한 경로와 query (재생 환경의 Thanos 주소가 달라도 일치)
+func urlKey(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return u.Path + "?" + u.Query().Encode(), nil
+}
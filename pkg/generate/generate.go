@@ -0,0 +1,135 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package generate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Metric CSV 값 컬럼 하나로 제안하는 cnf_config.yml 메트릭
+type Metric struct {
+	Name   string
+	Type   string // counter or gauge
+	Family string
+	Column int
+	Header string
+}
+
+// 평균, 비율, 현재값 등은 gauge
+var gaugeWords = map[string]bool{
+	"avg": true, "average": true, "mean": true, "ratio": true, "rate": true, "percent": true, "pct": true,
+	"max": true, "min": true, "cur": true, "current": true, "time": true, "delay": true, "latency": true,
+	"util": true, "usage": true, "load": true,
+}
+
+// 건수, 바이트, 패킷 등 누적값은 counter
+var counterWords = map[string]bool{
+	"count": true, "cnt": true, "num": true, "byte": true, "bytes": true, "packet": true, "packets": true,
+	"pkt": true, "attempt": true, "att": true, "success": true, "succ": true, "fail": true, "failure": true,
+	"total": true, "tot": true, "msg": true, "sum": true, "drop": true, "error": true, "err": true,
+}
+
+// Family CSV 0행의 Family 이름 ("Family name : AMFTPS" 형식이 아니면 "")
+func Family(data [][]string) string {
+	if len(data) == 0 || len(data[0]) == 0 {
+		return ""
+	}
+	cell := strings.TrimSpace(data[0][0])
+	index := strings.LastIndex(cell, ":")
+	if index < 0 || !strings.Contains(strings.ToLower(cell[:index]), "family") {
+		return ""
+	}
+	return strings.TrimSpace(cell[index+1:])
+}
+
+// Propose 2행 컬럼명으로 값 컬럼(7~)마다 메트릭 제안, 이름은 prefix_컬럼명
+func Propose(family, prefix string, header []string) []Metric {
+	family = strings.ReplaceAll(family, " ", "_")
+	if prefix == "" {
+		prefix = SnakeCase(family)
+	}
+	var metrics []Metric
+	names := map[string]bool{}
+	for column := 7; column < len(header); column++ {
+		name := SnakeCase(header[column])
+		if name == "" {
+			continue
+		}
+		name = prefix + "_" + name
+		if names[name] {
+			name = fmt.Sprintf("%s_%d", name, column)
+		}
+		names[name] = true
+		metrics = append(metrics, Metric{
+			Name:   name,
+			Type:   GuessType(header[column]),
+			Family: family,
+			Column: column,
+			Header: strings.TrimSpace(header[column]),
+		})
+	}
+	return metrics
+}
+
+// SnakeCase 컬럼명을 메트릭 이름으로 (UEActiveDLAvg(count) => ue_active_dl_avg_count)
+// ASCII 영문자와 숫자만 남기고 나머지 문자 (공백, 괄호, 한글 등) 는 모두 단어 구분자로 본다.
+func SnakeCase(s string) string {
+	runes := []rune(strings.TrimSpace(strings.ReplaceAll(s, "%", " percent ")))
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case isUpper(r):
+			// aB => a_b, ABc => a_bc
+			if i > 0 && (isLower(runes[i-1]) || isDigit(runes[i-1]) ||
+				(isUpper(runes[i-1]) && i+1 < len(runes) && isLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case isLower(r) || isDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := strings.Join(words(b.String()), "_")
+	if name != "" && isDigit(rune(name[0])) {
+		name = "_" + name
+	}
+	return name
+}
+
+func isUpper(r rune) bool { return 'A' <= r && r <= 'Z' }
+func isLower(r rune) bool { return 'a' <= r && r <= 'z' }
+func isDigit(r rune) bool { return '0' <= r && r <= '9' }
+
+// words SnakeCase 결과를 단어로 (빈 단어 제외)
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool { return r == '_' })
+}
+
+// GuessType 컬럼명으로 타입 추정, 평균/비율 단어가 있으면 gauge, 건수/바이트 단어가 있으면 counter (기본 gauge)
+func GuessType(header string) string {
+	parts := words(SnakeCase(header))
+	for _, word := range parts {
+		if gaugeWords[word] {
+			return "gauge"
+		}
+	}
+	for _, word := range parts {
+		if counterWords[word] {
+			return "counter"
+		}
+	}
+	return "gauge"
+}
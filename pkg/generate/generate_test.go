@@ -0,0 +1,58 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package generate
+
+import "testing"
+
+func TestSnakeCase(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"UEActiveDLAvg(count)", "ue_active_dl_avg_count"},
+		{"TPS_Avg", "tps_avg"},
+		{"  TotalMsg ", "total_msg"},
+		{"CPU Usage(%)", "cpu_usage_percent"},
+		{"RegAtt/RegSucc", "reg_att_reg_succ"},
+		{"Attach-Fail.Cnt", "attach_fail_cnt"},
+		{"5GMM Reg", "_5_gmm_reg"},
+		{"HTTPRequest", "http_request"},
+		{"평균Delay", "delay"},
+		{"Délai Moyen", "d_lai_moyen"},
+		{"ÉTAT", "tat"},
+		{"(전체)", ""},
+	} {
+		if got := SnakeCase(tc.in); got != tc.want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestGuessType(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"UEActiveDLAvg(count)", "gauge"},
+		{"TotalMsg", "counter"},
+		{"RegAtt/RegSucc", "counter"},
+		{"Attach-Fail.Cnt", "counter"},
+		{"CPU Usage(%)", "gauge"},
+		{"Succ(%)", "gauge"},
+		{"ActiveUE", "gauge"},
+		{"평균Delay", "gauge"},
+		{"성공Cnt", "counter"},
+		{"Countdown", "gauge"},
+	} {
+		if got := GuessType(tc.in); got != tc.want {
+			t.Errorf("GuessType(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package generate
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+// Skip 추가하지 않은 제안과 이유
+type Skip struct {
+	Metric Metric
+	Reason string
+}
+
+// Merge 기존 cnf_config.yml 의 metrics 끝에 제안 메트릭을 추가한다.
+// 기존 내용(주석, 서식 포함)은 그대로 두고, 이름이 같거나 같은 Family/컬럼을 읽는 메트릭이 있으면 추가하지 않는다.
+func Merge(config []byte, metrics []Metric, labels []string) ([]byte, []Metric, []Skip, error) {
+	var existing struct {
+		Metrics map[string]struct {
+			Description    string
+			Value_Sequence int `yaml:"value_sequence"`
+		}
+	}
+	if err := yaml.Unmarshal(config, &existing); err != nil {
+		return nil, nil, nil, err
+	}
+	columns := map[string]string{}
+	for name, metric := range existing.Metrics {
+		columns[fmt.Sprintf("%s|%d", metric.Description, metric.Value_Sequence)] = name
+	}
+
+	var added []Metric
+	var skipped []Skip
+	for _, metric := range metrics {
+		if _, ok := existing.Metrics[metric.Name]; ok {
+			skipped = append(skipped, Skip{metric, "name exists"})
+			continue
+		}
+		if name, ok := columns[fmt.Sprintf("%s|%d", metric.Family, metric.Column)]; ok {
+			skipped = append(skipped, Skip{metric, "column mapped by " + name})
+			continue
+		}
+		added = append(added, metric)
+	}
+	if len(added) == 0 {
+		return config, nil, skipped, nil
+	}
+
+	lines := strings.SplitAfter(string(config), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	at, indent, found, err := insertPoint(config, lines)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var block strings.Builder
+	if !found {
+		block.WriteString("metrics:\n")
+	}
+	if at > 0 && !strings.HasSuffix(lines[at-1], "\n") {
+		lines[at-1] += "\n"
+	}
+	quoted := make([]string, len(labels))
+	for i, label := range labels {
+		quoted[i] = fmt.Sprintf("%q", label)
+	}
+	family := ""
+	for _, metric := range added {
+		if metric.Family != family {
+			family = metric.Family
+			fmt.Fprintf(&block, "%s### %s (generate-config)\n", indent, family)
+		}
+		fmt.Fprintf(&block, "%s%s:\n", indent, metric.Name)
+		fmt.Fprintf(&block, "%s  type: %s # generated from %q\n", indent, metric.Type, metric.Header)
+		fmt.Fprintf(&block, "%s  description: %q\n", indent, metric.Family)
+		fmt.Fprintf(&block, "%s  labels: [%s]\n", indent, strings.Join(quoted, ","))
+		fmt.Fprintf(&block, "%s  value_sequence: %d\n", indent, metric.Column)
+	}
+
+	out := strings.Join(lines[:at], "") + block.String() + strings.Join(lines[at:], "")
+	return []byte(out), added, skipped, nil
+}
+
+// insertPoint metrics 블록 끝 줄 번호(다음 최상위 키와 그 주석 앞)와 메트릭 키 들여쓰기
+// metrics 키가 없으면 파일 끝, found false
+func insertPoint(config []byte, lines []string) (int, string, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return 0, "", false, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return len(lines), "  ", false, nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "metrics" {
+			continue
+		}
+		value := root.Content[i+1]
+		indent := "  "
+		switch {
+		case value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle != 0:
+			return 0, "", false, fmt.Errorf("metrics must be a block mapping")
+		case value.Kind == yaml.MappingNode && len(value.Content) > 0:
+			indent = strings.Repeat(" ", value.Content[0].Column-1)
+		case value.Kind == yaml.ScalarNode && value.Tag != "!!null":
+			return 0, "", false, fmt.Errorf("metrics must be a mapping")
+		}
+		if i+2 >= len(root.Content) {
+			return len(lines), indent, true, nil
+		}
+		// 다음 최상위 키 앞의 빈 줄, 최상위 주석은 다음 키에 속한다.
+		at := root.Content[i+2].Line - 1
+		for at > 0 {
+			line := strings.TrimRight(lines[at-1], "\r\n")
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+				break
+			}
+			at--
+		}
+		return at, indent, true, nil
+	}
+	return len(lines), "  ", false, nil
+}
@@ -0,0 +1,168 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package generate
+
+import (
+	"bytes"
+	"gopkg.in/yaml.v3"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/harness"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var labels = []string{"ne_id", "system_id", "ne_name", "init_name", "time_offset", "gran_period", "location"}
+
+func TestMerge(t *testing.T) {
+	uecon := []Metric{
+		// 7 (Attempt) 는 amf_ue_connect_attempt_count 가 읽는 컬럼
+		{Name: "uecon_amf_attempt", Type: "counter", Family: "UECON_AMF", Column: 7, Header: "Attempt"},
+		{Name: "amf_ue_connect_success_ratio", Type: "gauge", Family: "UECON_AMF", Column: 19, Header: "CacheHitRatio"},
+		{Name: "uecon_amf_success", Type: "counter", Family: "UECON_AMF", Column: 8, Header: "Success"},
+		{Name: "uecon_amf_resp_time", Type: "gauge", Family: "UECON_AMF", Column: 17, Header: "RespTime"},
+	}
+	amfms := []Metric{
+		{Name: "amfms_reg_avg", Type: "gauge", Family: "AMFMS", Column: 7, Header: "Reg_Avg"},
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		metrics []Metric
+		added   []string
+		skipped []string
+		golden  string // 비어있으면 입력과 같아야 한다.
+		err     string
+	}{
+		{
+			name:    "existing config",
+			config:  "cnf_config.yml",
+			metrics: append(append([]Metric{}, uecon...), amfms...),
+			added:   []string{"uecon_amf_success", "uecon_amf_resp_time", "amfms_reg_avg"},
+			skipped: []string{"uecon_amf_attempt: column mapped by amf_ue_connect_attempt_count", "amf_ue_connect_success_ratio: name exists"},
+			golden:  "cnf_config.golden",
+		},
+		{
+			name:    "nothing to add",
+			config:  "cnf_config.yml",
+			metrics: uecon[:2],
+			skipped: []string{"uecon_amf_attempt: column mapped by amf_ue_connect_attempt_count", "amf_ue_connect_success_ratio: name exists"},
+		},
+		{
+			name:    "no metrics key",
+			config:  "no_metrics.yml",
+			metrics: amfms,
+			added:   []string{"amfms_reg_avg"},
+			golden:  "no_metrics.golden",
+		},
+		{
+			name:    "empty metrics",
+			config:  "empty_metrics.yml",
+			metrics: amfms,
+			added:   []string{"amfms_reg_avg"},
+			golden:  "empty_metrics.golden",
+		},
+		{
+			name:    "flow mapping",
+			config:  "flow_metrics.yml",
+			metrics: amfms,
+			err:     "metrics must be a block mapping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := os.ReadFile(filepath.Join("testdata", tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, added, skipped, err := Merge(config, tt.metrics, labels)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var addedNames, skipReasons []string
+			for _, metric := range added {
+				addedNames = append(addedNames, metric.Name)
+			}
+			for _, skip := range skipped {
+				skipReasons = append(skipReasons, skip.Metric.Name+": "+skip.Reason)
+			}
+			if !reflect.DeepEqual(addedNames, tt.added) {
+				t.Errorf("added = %v, want %v", addedNames, tt.added)
+			}
+			if !reflect.DeepEqual(skipReasons, tt.skipped) {
+				t.Errorf("skipped = %v, want %v", skipReasons, tt.skipped)
+			}
+
+			if tt.golden == "" {
+				if !bytes.Equal(out, config) {
+					t.Errorf("config changed:\n%s", out)
+				}
+				return
+			}
+			compareGolden(t, tt.golden, out)
+			checkPreserved(t, config, out, tt.added)
+		})
+	}
+}
+
+// 기존 최상위 키와 메트릭(손으로 고친 값 포함)은 그대로, 추가한 메트릭은 생성한 값으로 읽혀야 한다.
+func checkPreserved(t *testing.T, before, after []byte, added []string) {
+	t.Helper()
+	var old, merged map[string]interface{}
+	if err := yaml.Unmarshal(before, &old); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(after, &merged); err != nil {
+		t.Fatalf("merged config is not valid yaml: %v", err)
+	}
+	for key, value := range old {
+		if key == "metrics" {
+			continue
+		}
+		if !reflect.DeepEqual(merged[key], value) {
+			t.Errorf("%s = %v, want %v", key, merged[key], value)
+		}
+	}
+
+	oldMetrics, _ := old["metrics"].(map[string]interface{})
+	mergedMetrics, _ := merged["metrics"].(map[string]interface{})
+	for name, value := range oldMetrics {
+		if !reflect.DeepEqual(mergedMetrics[name], value) {
+			t.Errorf("metrics.%s = %v, want %v", name, mergedMetrics[name], value)
+		}
+	}
+	if len(mergedMetrics) != len(oldMetrics)+len(added) {
+		t.Errorf("metrics = %d, want %d", len(mergedMetrics), len(oldMetrics)+len(added))
+	}
+	for _, name := range added {
+		metric, _ := mergedMetrics[name].(map[string]interface{})
+		if len(metric["labels"].([]interface{})) != len(labels) || metric["value_sequence"] == nil {
+			t.Errorf("metrics.%s = %v", name, metric)
+		}
+	}
+}
+
+func compareGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	harness.CompareGolden(t, filepath.Join("testdata", name), got)
+}
@@ -0,0 +1,40 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package harness
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+// go test <패키지> -update 로 golden 파일 갱신
+var update = flag.Bool("update", false, "update golden files")
+
+// CompareGolden got 을 golden 파일과 비교, -update 면 golden 파일을 got 으로 갱신
+func CompareGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch (-update 로 갱신)\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package harness
+
+import (
+	"context"
+	"fmt"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// OSS 안에서 performanceData CSV 가 만들어지는 경로 (curl 응답)
+const ossDir = "/home/vsm/aceman/web_oss/var/pm"
+
+// OSSRequest fake OSS 가 받은 요청
+type OSSRequest struct {
+	Family    string
+	StartTime string
+	EndTime   string
+}
+
+// OSS performanceData API 대역 (httptest)
+// Family/수집기간 별 CSV 를 등록해두면 요청마다 OSS pod 안의 파일 경로를 응답하고,
+// Retriever 가 그 경로로 CSV 내용을 가져간다.
+type OSS struct {
+	*httptest.Server
+	Username string
+	Password string
+
+	mu       sync.Mutex
+	csv      map[string][]byte
+	files    map[string][]byte
+	requests []OSSRequest
+}
+
+func NewOSS(username, password string) *OSS {
+	o := &OSS{
+		Username: username,
+		Password: password,
+		csv:      map[string][]byte{},
+		files:    map[string][]byte{},
+	}
+	o.Server = httptest.NewServer(http.HandlerFunc(o.serve))
+	return o
+}
+
+// AddCSV Family 의 CSV 등록, startTime 이 "" 이면 모든 수집기간에 응답
+func (o *OSS) AddCSV(family, startTime string, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.csv[csvKey(family, startTime)] = data
+}
+
+// LoadDir dir 의 <Family>.csv 를 모두 등록 (파일명의 "_" 는 Family 이름의 공백과 같게 취급)
+func (o *OSS) LoadDir(dir, startTime string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		o.AddCSV(strings.TrimSuffix(filepath.Base(file), ".csv"), startTime, data)
+	}
+	return nil
+}
+
+// Requests 받은 요청 목록
+func (o *OSS) Requests() []OSSRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OSSRequest{}, o.requests...)
+}
+
+// File 응답한 경로의 CSV 내용
+func (o *OSS) File(name string) ([]byte, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	data, ok := o.files[name]
+	return data, ok
+}
+
+func (o *OSS) serve(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != o.Username || password != o.Password {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	query := r.URL.Query()
+	req := OSSRequest{
+		Family:    query.Get("Family name"),
+		StartTime: query.Get("startTime"),
+		EndTime:   query.Get("endTime"),
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.requests = append(o.requests, req)
+
+	data, ok := o.csv[csvKey(req.Family, req.StartTime)]
+	if !ok {
+		data, ok = o.csv[csvKey(req.Family, "")]
+	}
+	if !ok {
+		http.Error(w, "no performance data", http.StatusNotFound)
+		return
+	}
+
+	name := fmt.Sprintf("%s/performanceData_%d.csv", ossDir, len(o.requests))
+	o.files[name] = data
+	_, _ = w.Write([]byte(name))
+}
+
+func csvKey(family, startTime string) string {
+	return strings.ReplaceAll(family, " ", "_") + "|" + startTime
+}
+
+// Retriever OSS 가 응답한 경로의 CSV 를 destDir 로 복사 (k8s exec 대역)
+type Retriever struct {
+	OSS *OSS
+}
+
+func (r Retriever) Retrieve(ctx context.Context, config cfg.Config, srcPath, destDir string) error {
+	data, ok := r.OSS.File(srcPath)
+	if !ok {
+		return fmt.Errorf("no such file in pod %s/%s: %s", config.Exporter.Namespace, config.Exporter.Pod, srcPath)
+	}
+	return os.WriteFile(filepath.Join(destDir, path.Base(srcPath)), data, 0644)
+}
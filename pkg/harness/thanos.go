@@ -0,0 +1,85 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package harness
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Sample Thanos instant query 결과 한 건
+type Sample struct {
+	Labels map[string]string
+	Value  string
+}
+
+// Thanos /api/v1/query 대역 (httptest), query 별로 등록한 vector 를 응답
+type Thanos struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	results map[string][]Sample
+	tokens  []string
+}
+
+// 응답 sample 의 timestamp (고정)
+const sampleTime = 1699417800
+
+func NewThanos() *Thanos {
+	t := &Thanos{results: map[string][]Sample{}}
+	t.Server = httptest.NewServer(http.HandlerFunc(t.serve))
+	return t
+}
+
+// Set query 의 결과 등록
+func (t *Thanos) Set(query string, samples ...Sample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.results[query] = samples
+}
+
+// Tokens 요청의 Authorization 헤더 목록
+func (t *Thanos) Tokens() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string{}, t.tokens...)
+}
+
+func (t *Thanos) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1/query" {
+		http.NotFound(w, r)
+		return
+	}
+
+	t.mu.Lock()
+	t.tokens = append(t.tokens, r.Header.Get("Authorization"))
+	samples := t.results[r.URL.Query().Get("query")]
+	t.mu.Unlock()
+
+	result := []map[string]interface{}{}
+	for _, sample := range samples {
+		result = append(result, map[string]interface{}{
+			"metric": sample.Labels,
+			"value":  []interface{}{sampleTime, sample.Value},
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "vector",
+			"result":     result,
+		},
+	})
+}
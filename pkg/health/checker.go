@@ -0,0 +1,235 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package health
+
+import (
+	"context"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"sync"
+	"time"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+// CheckFunc 의존성 확인 (k8s client, OSS 인증 ...)
+type CheckFunc func(ctx context.Context) error
+
+// Checker readiness 판단에 쓰는 의존성 확인 결과와 마지막 수집 주기 기록
+// 의존성 확인은 CHECK_INTERVAL 마다 백그라운드에서 실행하고, probe 는 저장된 결과만 읽는다.
+type Checker struct {
+	start    time.Time
+	interval time.Duration
+	stopping func() bool
+	now      func() time.Time
+
+	mu        sync.RWMutex
+	staleness time.Duration
+	checks    map[string]CheckFunc
+	results   map[string]DependencyStatus
+	lastCycle time.Time
+	cycleErr  error
+}
+
+// DependencyStatus 의존성별 확인 결과
+type DependencyStatus struct {
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// NewChecker stopping 이 true 를 반환하면 (종료 시작) not ready
+// 마지막 수집 시간은 SetStaleness 를 호출한 경우에만 readiness 에 반영한다.
+func NewChecker(interval time.Duration, stopping func() bool) *Checker {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Checker{
+		start:    time.Now(),
+		interval: interval,
+		stopping: stopping,
+		now:      time.Now,
+		checks:   map[string]CheckFunc{},
+		results:  map[string]DependencyStatus{},
+	}
+}
+
+// SetStaleness 마지막 수집이 staleness 보다 오래되면 not ready (0 이면 미확인)
+// scrape 가 없으면 수집도 없으므로, scrape 와 무관하게 수집하는 주기 실행(sink)이 있을 때만 설정한다.
+func (c *Checker) SetStaleness(staleness time.Duration) {
+	c.mu.Lock()
+	c.staleness = staleness
+	c.mu.Unlock()
+}
+
+// AddCheck 의존성 확인 등록, Start 전에 호출
+func (c *Checker) AddCheck(name string, check CheckFunc) {
+	c.mu.Lock()
+	c.checks[name] = check
+	c.mu.Unlock()
+}
+
+// Set 한번만 확인하는 의존성 결과 기록 (config 로드 등)
+func (c *Checker) Set(name string, err error) {
+	c.mu.Lock()
+	c.results[name] = result(err, c.now())
+	c.mu.Unlock()
+}
+
+// CycleFinished 수집 주기가 끝날때 호출
+func (c *Checker) CycleFinished(err error) {
+	c.mu.Lock()
+	c.lastCycle = c.now()
+	c.cycleErr = err
+	c.mu.Unlock()
+}
+
+// Start 등록된 의존성을 CHECK_INTERVAL 마다 확인, ctx 가 취소되면 중지
+func (c *Checker) Start(ctx context.Context) {
+	go func() {
+		defer Stop("health_checker")
+		for {
+			Beat("health_checker", c.interval)
+			c.runChecks(ctx)
+			timer := time.NewTimer(c.interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+func (c *Checker) runChecks(ctx context.Context) {
+	c.mu.RLock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	for name, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := check(checkCtx)
+		cancel()
+		if err != nil {
+			logger.LogErr("health check failed : "+name, err)
+		}
+		c.Set(name, err)
+	}
+}
+
+func result(err error, now time.Time) DependencyStatus {
+	status := DependencyStatus{Status: statusOK, CheckedAt: &now}
+	if err != nil {
+		status.Status = statusFail
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// ReadyReport /-/ready 응답
+type ReadyReport struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+	Collection   CollectionStatus            `json:"collection"`
+	ShuttingDown bool                        `json:"shutting_down"`
+}
+
+// CollectionStatus 마지막 수집 주기 (STALENESS 설정시 수집 전이면 시작 시간 기준으로 STALENESS 동안 ready)
+type CollectionStatus struct {
+	Status       string     `json:"status"`
+	LastFinished *time.Time `json:"last_finished,omitempty"`
+	AgeSeconds   float64    `json:"age_seconds"`
+	Staleness    float64    `json:"staleness_seconds"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// Ready readiness 판단
+func (c *Checker) Ready() (ReadyReport, bool) {
+	now := c.now()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ready := true
+	report := ReadyReport{Dependencies: map[string]DependencyStatus{}}
+	for name := range c.checks {
+		status, ok := c.results[name]
+		if !ok {
+			// 아직 확인 전
+			status = DependencyStatus{Status: "pending"}
+		}
+		report.Dependencies[name] = status
+	}
+	for name, status := range c.results {
+		report.Dependencies[name] = status
+	}
+	for _, status := range report.Dependencies {
+		if status.Status != statusOK {
+			ready = false
+		}
+	}
+
+	since := c.start
+	collection := CollectionStatus{Status: statusOK, Staleness: c.staleness.Seconds()}
+	if !c.lastCycle.IsZero() {
+		since = c.lastCycle
+		last := c.lastCycle
+		collection.LastFinished = &last
+	}
+	if c.cycleErr != nil {
+		collection.LastError = c.cycleErr.Error()
+	}
+	collection.AgeSeconds = now.Sub(since).Seconds()
+	if c.staleness > 0 && now.Sub(since) > c.staleness {
+		collection.Status = "stale"
+		ready = false
+	}
+	report.Collection = collection
+
+	if c.stopping != nil && c.stopping() {
+		report.ShuttingDown = true
+		ready = false
+	}
+
+	report.Status = statusOK
+	if !ready {
+		report.Status = statusFail
+	}
+	return report, ready
+}
+
+// HealthyReport /-/healthy 응답
+type HealthyReport struct {
+	Status        string                `json:"status"`
+	UptimeSeconds float64               `json:"uptime_seconds"`
+	Goroutines    map[string]LoopStatus `json:"goroutines"`
+}
+
+// Healthy 프로세스와 주기 실행 goroutine 이 살아있는지
+func (c *Checker) Healthy() (HealthyReport, bool) {
+	now := c.now()
+	goroutines, healthy := loops(now)
+	report := HealthyReport{
+		Status:        statusOK,
+		UptimeSeconds: now.Sub(c.start).Seconds(),
+		Goroutines:    goroutines,
+	}
+	if !healthy {
+		report.Status = statusFail
+	}
+	return report, healthy
+}
@@ -0,0 +1,280 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2023, 11, 8, 13, 0, 0, 0, time.Local)
+
+// start 에 시작한 Checker, 반환한 함수로 현재 시간 변경
+func newChecker(stopping func() bool) (*Checker, func(time.Time)) {
+	c := NewChecker(time.Minute, stopping)
+	c.start = start
+	now := start
+	c.now = func() time.Time { return now }
+	return c, func(t time.Time) { now = t }
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(c *Checker, setNow func(time.Time))
+		stopping   bool
+		ready      bool
+		collection string
+		dependency map[string]string
+	}{
+		{
+			name:       "dependencies ok",
+			setup:      func(c *Checker, _ func(time.Time)) { c.Set("config", nil) },
+			ready:      true,
+			collection: statusOK,
+			dependency: map[string]string{"config": statusOK},
+		},
+		{
+			name: "check pending",
+			setup: func(c *Checker, _ func(time.Time)) {
+				c.Set("config", nil)
+				c.AddCheck("oss", func(ctx context.Context) error { return nil })
+			},
+			collection: statusOK,
+			dependency: map[string]string{"config": statusOK, "oss": "pending"},
+		},
+		{
+			name:       "dependency failed",
+			setup:      func(c *Checker, _ func(time.Time)) { c.Set("k8s", errors.New("forbidden")) },
+			collection: statusOK,
+			dependency: map[string]string{"k8s": statusFail},
+		},
+		{
+			name:       "shutting down",
+			setup:      func(c *Checker, _ func(time.Time)) { c.Set("config", nil) },
+			stopping:   true,
+			collection: statusOK,
+			dependency: map[string]string{"config": statusOK},
+		},
+		{
+			// staleness 미설정(기본)이면 scrape 가 없어도 ready
+			name: "staleness off",
+			setup: func(c *Checker, setNow func(time.Time)) {
+				c.Set("config", nil)
+				setNow(start.Add(24 * time.Hour))
+			},
+			ready:      true,
+			collection: statusOK,
+			dependency: map[string]string{"config": statusOK},
+		},
+		{
+			name: "before first cycle",
+			setup: func(c *Checker, setNow func(time.Time)) {
+				c.SetStaleness(30 * time.Minute)
+				setNow(start.Add(29 * time.Minute))
+			},
+			ready:      true,
+			collection: statusOK,
+			dependency: map[string]string{},
+		},
+		{
+			name: "no cycle since start",
+			setup: func(c *Checker, setNow func(time.Time)) {
+				c.SetStaleness(30 * time.Minute)
+				setNow(start.Add(31 * time.Minute))
+			},
+			collection: "stale",
+			dependency: map[string]string{},
+		},
+		{
+			name: "recent cycle",
+			setup: func(c *Checker, setNow func(time.Time)) {
+				c.SetStaleness(30 * time.Minute)
+				setNow(start.Add(40 * time.Minute))
+				c.CycleFinished(errors.New("oss timeout"))
+				setNow(start.Add(69 * time.Minute))
+			},
+			ready:      true,
+			collection: statusOK,
+			dependency: map[string]string{},
+		},
+		{
+			name: "stale cycle",
+			setup: func(c *Checker, setNow func(time.Time)) {
+				c.SetStaleness(30 * time.Minute)
+				setNow(start.Add(40 * time.Minute))
+				c.CycleFinished(nil)
+				setNow(start.Add(71 * time.Minute))
+			},
+			collection: "stale",
+			dependency: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, setNow := newChecker(func() bool { return tt.stopping })
+			tt.setup(c, setNow)
+
+			report, ready := c.Ready()
+			if ready != tt.ready {
+				t.Errorf("ready = %v, want %v (%+v)", ready, tt.ready, report)
+			}
+			if want := map[bool]string{true: statusOK, false: statusFail}[tt.ready]; report.Status != want {
+				t.Errorf("status = %q, want %q", report.Status, want)
+			}
+			if report.Collection.Status != tt.collection {
+				t.Errorf("collection = %q, want %q", report.Collection.Status, tt.collection)
+			}
+			if report.ShuttingDown != tt.stopping {
+				t.Errorf("shutting_down = %v, want %v", report.ShuttingDown, tt.stopping)
+			}
+			if len(report.Dependencies) != len(tt.dependency) {
+				t.Errorf("dependencies = %+v, want %v", report.Dependencies, tt.dependency)
+			}
+			for name, status := range tt.dependency {
+				if report.Dependencies[name].Status != status {
+					t.Errorf("%s = %q, want %q", name, report.Dependencies[name].Status, status)
+				}
+			}
+		})
+	}
+}
+
+// 수집 오류는 보고하지만 readiness 는 내리지 않는다.
+func TestReadyCycleError(t *testing.T) {
+	c, setNow := newChecker(nil)
+	setNow(start.Add(10 * time.Minute))
+	c.CycleFinished(errors.New("oss timeout"))
+	setNow(start.Add(15 * time.Minute))
+
+	report, ready := c.Ready()
+	if !ready {
+		t.Fatalf("not ready: %+v", report)
+	}
+	if report.Collection.LastError != "oss timeout" || report.Collection.LastFinished == nil {
+		t.Errorf("collection = %+v", report.Collection)
+	}
+	if report.Collection.AgeSeconds != 300 {
+		t.Errorf("age = %v, want 300", report.Collection.AgeSeconds)
+	}
+}
+
+func TestRunChecks(t *testing.T) {
+	c, _ := newChecker(nil)
+	c.AddCheck("k8s", func(ctx context.Context) error { return nil })
+	c.AddCheck("oss", func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return errors.New("401 unauthorized")
+	})
+
+	if _, ready := c.Ready(); ready {
+		t.Fatal("ready before the first check")
+	}
+	c.runChecks(context.Background())
+
+	report, ready := c.Ready()
+	if ready {
+		t.Fatal("ready with a failed check")
+	}
+	if got := report.Dependencies["k8s"]; got.Status != statusOK || got.CheckedAt == nil {
+		t.Errorf("k8s = %+v", got)
+	}
+	if got := report.Dependencies["oss"]; got.Status != statusFail || got.Error != "401 unauthorized" {
+		t.Errorf("oss = %+v", got)
+	}
+}
+
+func resetBeats() {
+	beatMu.Lock()
+	beats = map[string]*heartbeat{}
+	beatMu.Unlock()
+}
+
+// 주기의 3배(최소 1분) 동안 Beat 가 없으면 fail, Stop 한 loop 는 제외
+func TestHeartbeat(t *testing.T) {
+	resetBeats()
+	defer resetBeats()
+
+	Beat("sink:metrics", 5*time.Minute)
+	Beat("health_checker", 10*time.Second)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		before  func()
+		healthy bool
+		status  map[string]string
+	}{
+		{"running", now, nil, true, map[string]string{"sink:metrics": statusOK, "health_checker": statusOK}},
+		{"minimum limit", now.Add(59 * time.Second), nil, true, map[string]string{"sink:metrics": statusOK, "health_checker": statusOK}},
+		{"short loop stuck", now.Add(61 * time.Second), nil, false, map[string]string{"sink:metrics": statusOK, "health_checker": statusFail}},
+		{"long loop stuck", now.Add(16 * time.Minute), nil, false, map[string]string{"sink:metrics": statusFail, "health_checker": statusFail}},
+		{"stopped", now.Add(16 * time.Minute), func() {
+			Stop("sink:metrics")
+			Stop("health_checker")
+		}, true, map[string]string{"sink:metrics": "stopped", "health_checker": "stopped"}},
+		{"restarted", time.Now(), func() { Beat("sink:metrics", 5*time.Minute) }, true, map[string]string{"sink:metrics": statusOK, "health_checker": "stopped"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				tt.before()
+			}
+			result, healthy := loops(tt.at)
+			if healthy != tt.healthy {
+				t.Errorf("healthy = %v, want %v (%+v)", healthy, tt.healthy, result)
+			}
+			for name, status := range tt.status {
+				if result[name].Status != status {
+					t.Errorf("%s = %q, want %q", name, result[name].Status, status)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	resetBeats()
+	defer resetBeats()
+	gin.SetMode(gin.TestMode)
+
+	c, _ := newChecker(nil)
+	router := gin.New()
+	router.GET("/-/healthy", HealthyHandler(c))
+	router.GET("/-/ready", ReadyHandler(c))
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	c.Set("oss", errors.New("401 unauthorized"))
+	if code := get("/-/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("ready = %d, want 503", code)
+	}
+	c.Set("oss", nil)
+	if code := get("/-/ready"); code != http.StatusOK {
+		t.Errorf("ready = %d, want 200", code)
+	}
+	if code := get("/-/healthy"); code != http.StatusOK {
+		t.Errorf("healthy = %d, want 200", code)
+	}
+}
@@ -0,0 +1,40 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package health
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// HealthyHandler GET /-/healthy (liveness)
+func HealthyHandler(c *Checker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report, ok := c.Healthy()
+		ctx.JSON(code(ok), report)
+	}
+}
+
+// ReadyHandler GET /-/ready (readiness)
+func ReadyHandler(c *Checker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report, ok := c.Ready()
+		ctx.JSON(code(ok), report)
+	}
+}
+
+func code(ok bool) int {
+	if ok {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,85 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 주기 실행 goroutine 의 마지막 실행 시간
+// 주기의 3배(최소 1분) 동안 실행 기록이 없으면 멈춘 것으로 본다.
+var (
+	beatMu sync.Mutex
+	beats  = map[string]*heartbeat{}
+)
+
+type heartbeat struct {
+	interval time.Duration
+	last     time.Time
+	stopped  bool
+}
+
+// Beat 주기 실행 goroutine 이 한 주기를 시작할 때 호출
+func Beat(name string, interval time.Duration) {
+	beatMu.Lock()
+	beats[name] = &heartbeat{interval: interval, last: time.Now()}
+	beatMu.Unlock()
+}
+
+// Stop goroutine 이 정상 종료됨 (종료중 healthy 판단에서 제외)
+func Stop(name string) {
+	beatMu.Lock()
+	if beat, ok := beats[name]; ok {
+		beat.stopped = true
+	}
+	beatMu.Unlock()
+}
+
+// LoopStatus goroutine 상태
+type LoopStatus struct {
+	Status   string    `json:"status"`
+	LastBeat time.Time `json:"last_beat"`
+	Interval float64   `json:"interval_seconds"`
+}
+
+func loops(now time.Time) (map[string]LoopStatus, bool) {
+	beatMu.Lock()
+	defer beatMu.Unlock()
+
+	names := make([]string, 0, len(beats))
+	for name := range beats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	healthy := true
+	result := map[string]LoopStatus{}
+	for _, name := range names {
+		beat := beats[name]
+		limit := 3 * beat.interval
+		if limit < time.Minute {
+			limit = time.Minute
+		}
+		status := statusOK
+		switch {
+		case beat.stopped:
+			status = "stopped"
+		case now.Sub(beat.last) > limit:
+			status = statusFail
+			healthy = false
+		}
+		result[name] = LoopStatus{Status: status, LastBeat: beat.last, Interval: beat.interval.Seconds()}
+	}
+	return result, healthy
+}
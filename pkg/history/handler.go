@@ -0,0 +1,84 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package history
+
+import (
+	"github.com/gin-gonic/gin"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"net/http"
+	"time"
+)
+
+// HistoryHandler 백업 폴더 기간 조회 API
+// GET /api/history?family=AMFTPS&column=9&group_by=location&agg=sum&start=...&end=...&format=csv
+// SITES 설정시 site 파라미터로 조회할 사이트를 지정한다.
+func HistoryHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ymlConfig, ok := cfg.InitConfig().SiteConfig(c.Query("site"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"data": "site is not find", "site": c.Query("site")})
+			return
+		}
+
+		// 기본 조회 기간은 최근 24시간
+		to := time.Now()
+		from := to.Add(-24 * time.Hour)
+		var err error
+		if v := c.Query("start"); v != "" {
+			if from, err = ParseTime(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"data": "start is not valid", "error": err.Error()})
+				return
+			}
+		}
+		if v := c.Query("end"); v != "" {
+			if to, err = ParseTime(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"data": "end is not valid", "error": err.Error()})
+				return
+			}
+		}
+
+		result, err := Run(ymlConfig.File.CSV_Path, Query{
+			Family:  c.Query("family"),
+			Column:  c.Query("column"),
+			GroupBy: c.DefaultQuery("group_by", "location"),
+			Agg:     c.DefaultQuery("agg", "sum"),
+			From:    from,
+			To:      to,
+		})
+		if err != nil {
+			logger.LogErr("history query failed", err)
+			c.JSON(http.StatusBadRequest, gin.H{"data": "history query failed", "error": err.Error()})
+			return
+		}
+
+		if c.Query("format") == "csv" {
+			c.Header("Content-Type", "text/csv")
+			c.Status(http.StatusOK)
+			if err := WriteCSV(c.Writer, result); err != nil {
+				logger.LogErr("history csv write failed", err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// ParseTime "2006-01-02 15:04:05", "2006-01-02 15:04" 또는 RFC3339 형식을 지원
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range []string{TimeLayout, "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse(time.RFC3339, value)
+}
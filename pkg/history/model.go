@@ -0,0 +1,60 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package history
+
+import "time"
+
+// Window 백업 구간 폴더(CSV_PATH/YYYY-MM-DD/hhmm-hhmm) 한개
+type Window struct {
+	Day   string
+	Name  string
+	Start time.Time
+	End   time.Time
+	Path  string
+}
+
+// Entry 백업 폴더(CSV_PATH/YYYY-MM-DD/hhmm-hhmm)에 저장된 Family CSV 한개
+type Entry struct {
+	Family string
+	Start  time.Time
+	End    time.Time
+	Path   string
+}
+
+// Query 기간 조회 조건
+type Query struct {
+	Family  string
+	Column  string
+	GroupBy string
+	Agg     string
+	From    time.Time
+	To      time.Time
+}
+
+type Result struct {
+	Family  string  `json:"family"`
+	Column  string  `json:"column"`
+	GroupBy string  `json:"groupBy"`
+	Agg     string  `json:"agg"`
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Points  []Point `json:"points"`
+}
+
+// Point 백업 구간 하나, 그룹 하나에 대한 집계값
+type Point struct {
+	Start string  `json:"start"`
+	End   string  `json:"end"`
+	Group string  `json:"group"`
+	Value float64 `json:"value"`
+	Rows  int     `json:"rows"`
+}
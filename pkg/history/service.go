@@ -0,0 +1,298 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package history
+
+import (
+	encodingCsv "encoding/csv"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/metricApi"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TimeLayout   = "2006-01-02 15:04:05"
+	DayLayout    = "2006-01-02"
+	windowLayout = "1504"
+)
+
+// OSS CSV 공통 라벨 컬럼 순서 (cnf_config.yml labels와 동일)
+var labelColumns = map[string]int{
+	"ne_id":       0,
+	"system_id":   1,
+	"ne_name":     2,
+	"init_name":   3,
+	"time_offset": 4,
+	"gran_period": 5,
+	"location":    6,
+}
+
+// ListWindows CSV_PATH 아래의 YYYY-MM-DD/hhmm-hhmm 백업 폴더 목록을 시작시간 순으로 반환한다.
+// 날짜/시간 형식이 아닌 폴더(API_PATH 등)는 무시한다.
+func ListWindows(root string) ([]Window, error) {
+	days, err := os.ReadDir(root)
+	if err != nil {
+		return nil, errors.Cause(err)
+	}
+
+	var windows []Window
+	for _, day := range days {
+		if !day.IsDir() {
+			continue
+		}
+		date, err := time.ParseInLocation(DayLayout, day.Name(), time.Local)
+		if err != nil {
+			continue
+		}
+
+		dirs, err := os.ReadDir(filepath.Join(root, day.Name()))
+		if err != nil {
+			logger.LogErr("backup folder read failed", err)
+			continue
+		}
+		for _, dir := range dirs {
+			if !dir.IsDir() {
+				continue
+			}
+			start, end, ok := parseWindow(date, dir.Name())
+			if !ok {
+				continue
+			}
+			windows = append(windows, Window{
+				Day:   day.Name(),
+				Name:  dir.Name(),
+				Start: start,
+				End:   end,
+				Path:  filepath.Join(root, day.Name(), dir.Name()),
+			})
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	return windows, nil
+}
+
+// BuildIndex 백업 폴더의 Family CSV 파일을 읽어 Entry 목록을 만든다.
+// retention 에서 압축한 .csv.gz, .csv.zst 파일도 포함한다.
+func BuildIndex(root string) ([]Entry, error) {
+	windows, err := ListWindows(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, window := range windows {
+		files, err := os.ReadDir(window.Path)
+		if err != nil {
+			logger.LogErr("backup folder read failed", err)
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			family, ok := csv.FamilyName(file.Name())
+			if !ok {
+				continue
+			}
+			entries = append(entries, Entry{
+				Family: family,
+				Start:  window.Start,
+				End:    window.End,
+				Path:   filepath.Join(window.Path, file.Name()),
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Start.Equal(entries[j].Start) {
+			return entries[i].Family < entries[j].Family
+		}
+		return entries[i].Start.Before(entries[j].Start)
+	})
+
+	return entries, nil
+}
+
+// hhmm-hhmm 폴더명을 시작/종료 시간으로 변환
+// 종료시간이 시작시간보다 빠르면 자정을 넘긴 구간으로 본다.
+func parseWindow(date time.Time, name string) (time.Time, time.Time, bool) {
+	parts := strings.Split(name, "-")
+	if len(parts) != 2 {
+		return time.Time{}, time.Time{}, false
+	}
+	s, err := time.Parse(windowLayout, parts[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	e, err := time.Parse(windowLayout, parts[1])
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start := date.Add(time.Duration(s.Hour())*time.Hour + time.Duration(s.Minute())*time.Minute)
+	end := date.Add(time.Duration(e.Hour())*time.Hour + time.Duration(e.Minute())*time.Minute)
+	if end.Before(start) {
+		end = end.Add(24 * time.Hour)
+	}
+	return start, end, true
+}
+
+// Find family 의 백업중 시작시간이 [from, to) 에 포함되는 Entry 반환
+func Find(entries []Entry, family string, from, to time.Time) []Entry {
+	family = strings.ReplaceAll(family, " ", "_")
+
+	var found []Entry
+	for _, entry := range entries {
+		if entry.Family != family {
+			continue
+		}
+		if entry.Start.Before(from) || !entry.Start.Before(to) {
+			continue
+		}
+		found = append(found, entry)
+	}
+	return found
+}
+
+// Run 백업 폴더를 인덱싱하고 Query 조건으로 집계한다.
+// 집계는 /api/metrics 와 같은 metricApi.AggregateBy 로 백업 구간별 그룹(location 등)의 행을 합산/평균/최대/최소값으로 계산한다.
+func Run(root string, q Query) (*Result, error) {
+	if q.Family == "" || q.Column == "" {
+		return nil, errors.New("family and column are required")
+	}
+	if q.Agg == "" {
+		q.Agg = "sum"
+	}
+	q.Agg = strings.ToLower(q.Agg)
+	switch q.Agg {
+	case "sum", "avg", "max", "min":
+	default:
+		return nil, fmt.Errorf("unsupported agg %q (sum|avg|max|min)", q.Agg)
+	}
+
+	groupIndex := -1
+	if q.GroupBy != "" && q.GroupBy != "none" {
+		idx, ok := labelColumns[q.GroupBy]
+		if !ok {
+			return nil, fmt.Errorf("unsupported group_by %q", q.GroupBy)
+		}
+		groupIndex = idx
+	}
+
+	entries, err := BuildIndex(root)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Family:  q.Family,
+		Column:  q.Column,
+		GroupBy: q.GroupBy,
+		Agg:     q.Agg,
+		From:    q.From.Format(TimeLayout),
+		To:      q.To.Format(TimeLayout),
+		Points:  []Point{},
+	}
+
+	for _, entry := range Find(entries, q.Family, q.From, q.To) {
+		data, err := csv.LoadCsv(entry.Path)
+		if err != nil {
+			logger.LogErr(entry.Path+" CSV File is not Open", err)
+			continue
+		}
+		// /api/metrics 와 같이 cnf_config.yml filters 적용
+		data = metricApi.FilterRows(entry.Family, data)
+		// 데이터가 없을경우 넘어감
+		if len(data) <= 3 {
+			continue
+		}
+
+		column, err := ResolveColumn(data[2], q.Column)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Points = append(result.Points, aggregate(entry, data, column, groupIndex, q.Agg)...)
+	}
+
+	return result, nil
+}
+
+// ResolveColumn 컬럼 번호 또는 헤더명(단위 "(count)" 제외 가능)으로 컬럼 위치를 찾는다.
+func ResolveColumn(header []string, column string) (int, error) {
+	if idx, err := strconv.Atoi(column); err == nil {
+		if idx < 0 || idx >= len(header) {
+			return 0, fmt.Errorf("column %d out of range", idx)
+		}
+		return idx, nil
+	}
+
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if strings.EqualFold(name, column) {
+			return i, nil
+		}
+		if p := strings.Index(name, "("); p > 0 && strings.EqualFold(name[:p], column) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("column %q is not found", column)
+}
+
+// aggregate 백업 구간 하나의 데이터를 metricApi.AggregateBy 로 그룹별 집계해 Point 로 변환
+func aggregate(entry Entry, data [][]string, column, groupIndex int, agg string) []Point {
+	groups := metricApi.AggregateBy(data, column, groupIndex, agg)
+	points := make([]Point, 0, len(groups))
+	for _, group := range groups {
+		points = append(points, Point{
+			Start: entry.Start.Format(TimeLayout),
+			End:   entry.End.Format(TimeLayout),
+			Group: group.Name,
+			Value: group.Value,
+			Rows:  group.Rows,
+		})
+	}
+	return points
+}
+
+// WriteCSV 결과를 start,end,group,value,rows 형식의 CSV로 출력
+func WriteCSV(w io.Writer, result *Result) error {
+	writer := encodingCsv.NewWriter(w)
+	if err := writer.Write([]string{"start", "end", "group", "value", "rows"}); err != nil {
+		return err
+	}
+	for _, point := range result.Points {
+		record := []string{
+			point.Start,
+			point.End,
+			point.Group,
+			strconv.FormatFloat(point.Value, 'f', -1, 64),
+			strconv.Itoa(point.Rows),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
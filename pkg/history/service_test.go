@@ -0,0 +1,223 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package history
+
+import (
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/metricApi"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var header = []string{"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "RecvMsg(count)", "SendMsg(count)", "TotalMsg(count)"}
+
+func amftps(rows ...[]string) [][]string {
+	data := [][]string{{"Family name : AMFTPS"}, {"Period : 15min"}, header}
+	return append(data, rows...)
+}
+
+func row(location, total string) []string {
+	return []string{"ne101", "1", "NE-101", "2023-11-08 13:15:00", "+09:00", "900", location, "1", "1", total}
+}
+
+func writeCSV(t *testing.T, path string, data [][]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, r := range data {
+		lines = append(lines, strings.Join(r, ","))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func at(value string) time.Time {
+	t, err := time.ParseInLocation(TimeLayout, value, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseWindow(t *testing.T) {
+	date := at("2023-11-08 00:00:00")
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		ok    bool
+	}{
+		{"1315-1330", "2023-11-08 13:15:00", "2023-11-08 13:30:00", true},
+		{"2345-0000", "2023-11-08 23:45:00", "2023-11-09 00:00:00", true},
+		{"2350-0005", "2023-11-08 23:50:00", "2023-11-09 00:05:00", true},
+		{"0000-0015", "2023-11-08 00:00:00", "2023-11-08 00:15:00", true},
+		{"1315", "", "", false},
+		{"13-15", "", "", false},
+		{"api-path", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := parseWindow(date, tt.name)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if !start.Equal(at(tt.start)) || !end.Equal(at(tt.end)) {
+				t.Errorf("window = %v ~ %v, want %s ~ %s", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	entries := []Entry{
+		{Family: "AMFTPS", Start: at("2023-11-08 23:30:00")},
+		{Family: "AMFTPS", Start: at("2023-11-08 23:45:00")},
+		{Family: "AMFTPS", Start: at("2023-11-09 00:00:00")},
+		{Family: "AMFTPS", Start: at("2023-11-09 00:15:00")},
+		{Family: "Air_MAC_Packet", Start: at("2023-11-08 23:45:00")},
+	}
+	tests := []struct {
+		name   string
+		family string
+		from   string
+		to     string
+		want   []string
+	}{
+		{"from inclusive to exclusive", "AMFTPS", "2023-11-08 23:45:00", "2023-11-09 00:15:00", []string{"2023-11-08 23:45:00", "2023-11-09 00:00:00"}},
+		{"between windows", "AMFTPS", "2023-11-08 23:40:00", "2023-11-09 00:10:00", []string{"2023-11-08 23:45:00", "2023-11-09 00:00:00"}},
+		{"empty range", "AMFTPS", "2023-11-09 00:00:00", "2023-11-09 00:00:00", nil},
+		{"family with spaces", "Air MAC Packet", "2023-11-08 00:00:00", "2023-11-10 00:00:00", []string{"2023-11-08 23:45:00"}},
+		{"unknown family", "AMFMS", "2023-11-08 00:00:00", "2023-11-10 00:00:00", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range Find(entries, tt.family, at(tt.from), at(tt.to)) {
+				got = append(got, entry.Start.Format(TimeLayout))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveColumn(t *testing.T) {
+	tests := []struct {
+		column string
+		want   int
+		err    bool
+	}{
+		{"9", 9, false},
+		{"TotalMsg(count)", 9, false},
+		{"TotalMsg", 9, false},
+		{"totalmsg", 9, false},
+		{"LOCATION", 6, false},
+		{"10", 0, true},
+		{"-1", 0, true},
+		{"Total", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			got, err := ResolveColumn(header, tt.column)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveColumn = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	entry := Entry{Family: "AMFTPS", Start: at("2023-11-08 13:15:00"), End: at("2023-11-08 13:30:00")}
+	// 빈 값, 숫자가 아닌 값은 /api/metrics 와 같이 0 으로 계산하고 컬럼이 없는 행은 제외한다.
+	data := amftps(
+		row("DU_1", "10"),
+		row("DU_1", ""),
+		row("DU_1", "40"),
+		row("DU_2", "-"),
+		row("DU_2", "5"),
+		[]string{"ne101", "1", "NE-101", "2023-11-08 13:15:00", "+09:00", "900", "DU_2"},
+	)
+	tests := []struct {
+		agg        string
+		groupIndex int
+		want       []Point
+	}{
+		{"sum", 6, []Point{{Group: "DU_1", Value: 50, Rows: 3}, {Group: "DU_2", Value: 5, Rows: 2}}},
+		{"avg", 6, []Point{{Group: "DU_1", Value: 50.0 / 3, Rows: 3}, {Group: "DU_2", Value: 2.5, Rows: 2}}},
+		{"max", 6, []Point{{Group: "DU_1", Value: 40, Rows: 3}, {Group: "DU_2", Value: 5, Rows: 2}}},
+		{"min", 6, []Point{{Group: "DU_1", Value: 0, Rows: 3}, {Group: "DU_2", Value: 0, Rows: 2}}},
+		{"sum", -1, []Point{{Group: "", Value: 55, Rows: 5}}},
+		{"avg", -1, []Point{{Group: "", Value: 11, Rows: 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.agg, func(t *testing.T) {
+			got := aggregate(entry, data, 9, tt.groupIndex, tt.agg)
+			for i := range tt.want {
+				tt.want[i].Start = "2023-11-08 13:15:00"
+				tt.want[i].End = "2023-11-08 13:30:00"
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("aggregate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 같은 CSV 에 대해 history 집계와 /api/metrics(AMFTPS TotalMsg 합계)가 같은 값을 반환해야 한다.
+func TestRunMatchesMetricApi(t *testing.T) {
+	root := t.TempDir()
+	data := amftps(row("AMF_01", "120"), row("AMF_02", ""), row("AMF_02", "30"))
+	writeCSV(t, filepath.Join(root, "2023-11-08", "2345-0000", "AMFTPS.csv"), data)
+	writeCSV(t, filepath.Join(root, "2023-11-09", "0000-0015", "AMFTPS.csv"), amftps(row("AMF_01", "7")))
+	writeCSV(t, filepath.Join(root, "api", "AMFTPS.csv"), data)
+
+	result, err := Run(root, Query{
+		Family: "AMFTPS",
+		Column: "TotalMsg",
+		From:   at("2023-11-08 23:45:00"),
+		To:     at("2023-11-09 00:15:00"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Point{
+		{Start: "2023-11-08 23:45:00", End: "2023-11-09 00:00:00", Value: 150, Rows: 3},
+		{Start: "2023-11-09 00:00:00", End: "2023-11-09 00:15:00", Value: 7, Rows: 1},
+	}
+	if !reflect.DeepEqual(result.Points, want) {
+		t.Fatalf("points = %+v, want %+v", result.Points, want)
+	}
+
+	var ymlConfig cfg.Config
+	ymlConfig.File.API_Path = filepath.Join(root, "api")
+	_, totMsg, err := metricApi.FindAMFTPSAppDetail([]string{"AMFTPS"}, ymlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if float64(totMsg) != result.Points[0].Value {
+		t.Errorf("/api/metrics = %d, history = %v", totMsg, result.Points[0].Value)
+	}
+}
@@ -0,0 +1,465 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName CSV_PATH 아래 journal 파일명
+const FileName = ".journal.json"
+
+// QuarantineDir 복구시 출처를 알 수 없는 파일을 옮기는 폴더 (CSV_PATH/quarantine/YYYYMMDD-hhmmss/)
+const QuarantineDir = "quarantine"
+
+// 수집 주기 단계
+const (
+	PhaseRunning     = "running"
+	PhaseCompleted   = "completed"
+	PhaseInterrupted = "interrupted" // 복구하지 못하고 다음 수집이 시작됨
+)
+
+// Family 상태
+const (
+	StatusPending  = "pending"
+	StatusFetched  = "fetched"
+	StatusFailed   = "failed"
+	StatusStored   = "stored" // 저장소, 메시지 버스 기록 완료
+	StatusBackedUp = "backed_up"
+)
+
+// Family 수집 주기 안의 Family 하나의 상태
+type Family struct {
+	File   string `json:"file"` // CSV_PATH 의 파일명 (공백은 _)
+	Status string `json:"status"`
+	Sha256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	Backup string `json:"backup,omitempty"` // 백업 파일 경로
+	Error  string `json:"error,omitempty"`
+}
+
+// Cycle 수집 주기 하나 (OSS 요청 window 와 백업 폴더)
+type Cycle struct {
+	Start    string             `json:"start"`
+	End      string             `json:"end"`
+	Folder   string             `json:"folder"` // YYYY-MM-DD
+	Window   string             `json:"window"` // hhmm-hhmm
+	Phase    string             `json:"phase"`
+	Started  time.Time          `json:"started"`
+	Finished *time.Time         `json:"finished,omitempty"`
+	API      bool               `json:"api"` // API_PATH 갱신 여부
+	Families map[string]*Family `json:"families"`
+}
+
+// Names status 중 하나인 Family 이름 (config 순서)
+func (c *Cycle) Names(order []string, status ...string) []string {
+	var names []string
+	for _, name := range order {
+		family, ok := c.Families[name]
+		if !ok {
+			continue
+		}
+		for _, s := range status {
+			if family.Status == s {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+func (c *Cycle) clone() *Cycle {
+	copied := *c
+	copied.Families = make(map[string]*Family, len(c.Families))
+	for name, family := range c.Families {
+		f := *family
+		copied.Families[name] = &f
+	}
+	return &copied
+}
+
+// Journal CSV_PATH 의 수집 상태 기록, 변경마다 파일에 저장 (사이트별 하나)
+type Journal struct {
+	dir  string
+	keep int
+
+	mu          sync.Mutex
+	cycles      []*Cycle // 오래된 순, 마지막이 현재 수집
+	resumed     float64
+	quarantined float64
+
+	lastCompletedDesc *prometheus.Desc
+	familyStatusDesc  *prometheus.Desc
+	resumedDesc       *prometheus.Desc
+	quarantinedDesc   *prometheus.Desc
+}
+
+// Open dir(CSV_PATH) 의 journal 을 읽는다. 파일이 없으면 빈 journal
+func Open(dir, site string, keep int) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var labels prometheus.Labels
+	if site != "" {
+		labels = prometheus.Labels{"site": site}
+	}
+	j := &Journal{
+		dir:  dir,
+		keep: keep,
+		lastCompletedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "journal", "last_completed_timestamp_seconds"),
+			"time the last collection cycle completed",
+			nil, labels,
+		),
+		familyStatusDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "journal", "family_status"),
+			"status of each family in the latest collection cycle",
+			[]string{"family", "status"}, labels,
+		),
+		resumedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "journal", "resumed_cycles_total"),
+			"interrupted collection cycles resumed after a restart",
+			nil, labels,
+		),
+		quarantinedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "journal", "quarantined_files_total"),
+			"orphaned CSV files moved to the quarantine folder",
+			nil, labels,
+		),
+	}
+	if err := j.Reload(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Reload 파일에서 다시 읽기 (leader 가 바뀐 경우 다른 replica 가 기록한 상태 사용)
+// 파일이 깨졌으면 옆에 보관하고 빈 journal 로 시작한다.
+func (j *Journal) Reload() error {
+	path := filepath.Join(j.dir, FileName)
+	var cycles []*Cycle
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &cycles); err != nil {
+			corrupt := path + ".corrupt-" + time.Now().Format("20060102-150405")
+			logger.LogWarn("journal corrupted, starting empty", zap.String("path", path), zap.String("moved", corrupt), zap.Error(err))
+			_ = os.Rename(path, corrupt)
+			cycles = nil
+		}
+	}
+	j.mu.Lock()
+	j.cycles = cycles
+	j.mu.Unlock()
+	return nil
+}
+
+// Current 마지막 수집 주기 (없으면 nil)
+func (j *Journal) Current() *Cycle {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if cycle := j.current(); cycle != nil {
+		return cycle.clone()
+	}
+	return nil
+}
+
+func (j *Journal) current() *Cycle {
+	if len(j.cycles) == 0 {
+		return nil
+	}
+	return j.cycles[len(j.cycles)-1]
+}
+
+// Begin start~end 수집 시작, 같은 window 의 수집이 중단된 상태면 이어서 진행한다.
+func (j *Journal) Begin(start, end, folder, window string, families []string) (*Cycle, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cycle := j.current()
+	if cycle == nil || cycle.Phase != PhaseRunning || cycle.Start != start || cycle.End != end {
+		if cycle != nil && cycle.Phase == PhaseRunning {
+			cycle.Phase = PhaseInterrupted
+		}
+		cycle = &Cycle{
+			Start:    start,
+			End:      end,
+			Folder:   folder,
+			Window:   window,
+			Phase:    PhaseRunning,
+			Started:  time.Now(),
+			Families: map[string]*Family{},
+		}
+		j.cycles = append(j.cycles, cycle)
+	}
+	// config 에 추가된 Family 반영
+	for _, name := range families {
+		if _, ok := cycle.Families[name]; !ok {
+			cycle.Families[name] = &Family{File: FileOf(name), Status: StatusPending}
+		}
+	}
+	return cycle.clone(), j.save()
+}
+
+// Fetched OSS 에서 받은 파일의 크기, sha256 기록
+func (j *Journal) Fetched(name, path string) error {
+	sum, size, err := checksum(path)
+	if err != nil {
+		return err
+	}
+	return j.update(name, func(family *Family) {
+		family.Status = StatusFetched
+		family.Sha256 = sum
+		family.Size = size
+		family.Error = ""
+	})
+}
+
+// Failed 아직 받지 못한 Family 를 실패로 기록
+func (j *Journal) Failed(names []string, cause error) error {
+	for _, name := range names {
+		if err := j.update(name, func(family *Family) {
+			family.Status = StatusFailed
+			family.Error = cause.Error()
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stored 저장소, 메시지 버스 기록 완료 (재개시 중복 기록 방지)
+func (j *Journal) Stored(name string) error {
+	return j.update(name, func(family *Family) {
+		family.Status = StatusStored
+	})
+}
+
+// BackedUp 백업 폴더로 옮긴 위치 기록
+func (j *Journal) BackedUp(name, path string) error {
+	return j.update(name, func(family *Family) {
+		family.Status = StatusBackedUp
+		family.Backup = path
+	})
+}
+
+// APIUpdated API_PATH 갱신 기록
+func (j *Journal) APIUpdated() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if cycle := j.current(); cycle != nil {
+		cycle.API = true
+	}
+	return j.save()
+}
+
+// Complete 현재 수집 완료
+func (j *Journal) Complete() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cycle := j.current()
+	if cycle == nil {
+		return nil
+	}
+	now := time.Now()
+	cycle.Phase = PhaseCompleted
+	cycle.Finished = &now
+	return j.save()
+}
+
+func (j *Journal) update(name string, apply func(family *Family)) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cycle := j.current()
+	if cycle == nil {
+		return fmt.Errorf("journal has no cycle")
+	}
+	family, ok := cycle.Families[name]
+	if !ok {
+		family = &Family{File: FileOf(name)}
+		cycle.Families[name] = family
+	}
+	apply(family)
+	return j.save()
+}
+
+// Recover 다시 읽은 상태로 CSV_PATH 의 파일 확인
+// 중단된 수집이면 checksum 이 맞는 받은 파일만 남기고(나머지는 다시 받도록 pending) 그 수집을 반환한다.
+// 남길 파일이 아닌 Family CSV, CopyFromPod 원본 파일은 quarantine 폴더로 옮긴다.
+func (j *Journal) Recover(families []string) (*Cycle, error) {
+	if err := j.Reload(); err != nil {
+		return nil, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	keep := map[string]bool{}
+	var interrupted *Cycle
+	if cycle := j.current(); cycle != nil && cycle.Phase == PhaseRunning {
+		interrupted = cycle
+		for name, family := range cycle.Families {
+			if family.Status != StatusFetched && family.Status != StatusStored {
+				continue
+			}
+			path := filepath.Join(j.dir, family.File)
+			sum, _, err := checksum(path)
+			if err == nil && sum == family.Sha256 {
+				keep[family.File] = true
+				continue
+			}
+			logger.LogWarn("journal file missing or changed, fetching again", zap.String("family", name), zap.String("path", path))
+			family.Status = StatusPending
+			family.Sha256, family.Size = "", 0
+		}
+	}
+
+	if err := j.quarantine(families, keep); err != nil {
+		return nil, err
+	}
+	if err := j.save(); err != nil {
+		return nil, err
+	}
+	if interrupted == nil {
+		return nil, nil
+	}
+	return interrupted.clone(), nil
+}
+
+// Resumed 중단된 수집 재개 횟수 증가
+func (j *Journal) Resumed() {
+	j.mu.Lock()
+	j.resumed++
+	j.mu.Unlock()
+}
+
+// quarantine keep 에 없는 Family CSV, performanceData_*.csv 를 QuarantineDir 로 옮긴다.
+func (j *Journal) quarantine(families []string, keep map[string]bool) error {
+	candidates := map[string]bool{}
+	for _, name := range families {
+		candidates[FileOf(name)] = true
+	}
+	raw, _ := filepath.Glob(filepath.Join(j.dir, "performanceData_*.csv"))
+	for _, path := range raw {
+		candidates[filepath.Base(path)] = true
+	}
+
+	dest := filepath.Join(j.dir, QuarantineDir, time.Now().Format("20060102-150405"))
+	for file := range candidates {
+		if keep[file] {
+			continue
+		}
+		path := filepath.Join(j.dir, file)
+		if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(path, filepath.Join(dest, file)); err != nil {
+			return err
+		}
+		j.quarantined++
+		logger.LogWarn("orphaned file quarantined", zap.String("file", path), zap.String("dest", dest))
+	}
+	return nil
+}
+
+// save keep 개만 남기고 임시 파일에 쓴 뒤 rename (잠금 상태에서 호출)
+func (j *Journal) save() error {
+	if j.keep > 0 && len(j.cycles) > j.keep {
+		j.cycles = j.cycles[len(j.cycles)-j.keep:]
+	}
+	data, err := json.MarshalIndent(j.cycles, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(j.dir, FileName+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(j.dir, FileName))
+}
+
+// FileOf Family 이름의 CSV_PATH 파일명 (공백은 _)
+func FileOf(name string) string {
+	return strings.ReplaceAll(name, " ", "_") + ".csv"
+}
+
+func checksum(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// Describe prometheus describe
+func (j *Journal) Describe(ch chan<- *prometheus.Desc) {
+	ch <- j.lastCompletedDesc
+	ch <- j.familyStatusDesc
+	ch <- j.resumedDesc
+	ch <- j.quarantinedDesc
+}
+
+// Collect prometheus collect
+func (j *Journal) Collect(ch chan<- prometheus.Metric) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := len(j.cycles) - 1; i >= 0; i-- {
+		if cycle := j.cycles[i]; cycle.Phase == PhaseCompleted && cycle.Finished != nil {
+			ch <- prometheus.MustNewConstMetric(j.lastCompletedDesc, prometheus.GaugeValue, float64(cycle.Finished.Unix()))
+			break
+		}
+	}
+	if cycle := j.current(); cycle != nil {
+		for name, family := range cycle.Families {
+			ch <- prometheus.MustNewConstMetric(j.familyStatusDesc, prometheus.GaugeValue, 1, name, family.Status)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(j.resumedDesc, prometheus.CounterValue, j.resumed)
+	ch <- prometheus.MustNewConstMetric(j.quarantinedDesc, prometheus.CounterValue, j.quarantined)
+}
@@ -12,7 +12,9 @@
 package k8sClient
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	authenticationv1 "k8s.io/api/authentication/v1"
@@ -23,12 +25,12 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
 	"k8s.io/client-go/util/homedir"
-	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
 	"os"
-	"path"
 	"path/filepath"
+	"strings"
 )
 
 type Client struct {
@@ -57,6 +59,24 @@ func CreateClientSet() (*kubernetes.Clientset, *rest.Config) {
 	return clientset, config
 }
 
+// Ping CreateClientSet 과 같은 kubeconfig 로 API 서버에 접속하여 pod 조회 (panic 없이 오류 반환)
+func Ping(ctx context.Context, namespace, podName string) error {
+	home := homedir.HomeDir()
+	if home == "" {
+		return fmt.Errorf("kubeconfig not found")
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", filepath.Join(home, ".kube", "config"))
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Pods(namespace).Get(ctx, podName, v1.GetOptions{})
+	return err
+}
+
 func CreateCustomClientSet(configPath string) (*kubernetes.Clientset, *rest.Config) {
 	var kubeconfig *string
 
@@ -74,6 +94,21 @@ func CreateCustomClientSet(configPath string) (*kubernetes.Clientset, *rest.Conf
 	return clientset, config
 }
 
+// NewConfig kubeconfig 경로로 설정 생성, 경로가 없으면 in-cluster 설정 (pod 밖이면 ~/.kube/config) (panic 없이 오류 반환)
+func NewConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfig)
+	}
+	if config, err := rest.InClusterConfig(); err == nil {
+		return config, nil
+	}
+	home := homedir.HomeDir()
+	if home == "" {
+		return nil, fmt.Errorf("kubeconfig not found")
+	}
+	return clientcmd.BuildConfigFromFlags("", filepath.Join(home, ".kube", "config"))
+}
+
 func NewK8sClient(config rest.Config, clientset *kubernetes.Clientset) *Client {
 	return &Client{
 		Config:    &config,
@@ -89,13 +124,13 @@ func (c *Client) PodList(Namespace string) {
 	}
 }
 
-func CreateToken(client *kubernetes.Clientset, namespace, serviceAccount string) (string, error) {
+func CreateToken(ctx context.Context, client *kubernetes.Clientset, namespace, serviceAccount string) (string, error) {
 	treq := &authenticationv1.TokenRequest{
 		Spec: authenticationv1.TokenRequestSpec{
 			Audiences: []string{"https://kubernetes.default.svc"},
 		},
 	}
-	tokenResp, err := client.CoreV1().ServiceAccounts(namespace).CreateToken(context.TODO(), serviceAccount, treq, v1.CreateOptions{})
+	tokenResp, err := client.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, serviceAccount, treq, v1.CreateOptions{})
 	if err != nil {
 		logger.LogErr("crate not serviceAccount Token", err)
 		return "", err
@@ -109,54 +144,107 @@ func CreateToken(client *kubernetes.Clientset, namespace, serviceAccount string)
 	return tokenResp.Status.Token, nil
 }
 
-func (c *Client) CopyFromPod(podName, Namespace, containerName string, srcPath string, destPath string) error {
-	var stdin io.Reader
+// ErrCommandNotFound 컨테이너에 tar, sha256sum 명령이 없음
+var ErrCommandNotFound = errors.New("command not found in container")
 
+// CopyFromPod 컨테이너의 srcPath 를 tar 로 받아 destPath 에 풀기, ctx 가 취소되면 스트림을 중단한다.
+// tar 의 오류(stderr, 종료 코드)와 untar 오류를 함께 반환하고, opts.Checksum 이면 컨테이너의 sha256sum 과 비교한다.
+func (c *Client) CopyFromPod(ctx context.Context, podName, Namespace, containerName string, srcPath string, destPath string, opts CopyOptions) error {
 	reader, outStream := io.Pipe()
-	//todo some containers failed : tar: Refusing to write archive contents to terminal (missing -f option?) when execute `tar cf -` in container
-	cmdArr := []string{"tar", "cf", "-", srcPath}
-
-	//pods, _ := c.Clientset.CoreV1().Pods(podName).List(context.TODO(), v1.ListOptions{})
+	stderr := &limitedBuffer{max: 4096}
+	streamErr := make(chan error, 1)
+	go func() {
+		err := c.exec(ctx, podName, Namespace, containerName, []string{"tar", "cf", "-", srcPath}, outStream, stderr)
+		// 스트림 오류(취소 포함)는 untar 쪽 오류로 전달
+		outStream.CloseWithError(err)
+		streamErr <- err
+	}()
 
-	req := c.Clientset.CoreV1().RESTClient().Post().Resource("pods").Name(podName).Namespace(Namespace).SubResource("exec")
+	files, err := untarAll(reader, destPath, srcPath, opts)
+	// untar 가 먼저 실패하면 스트림 쪽 쓰기도 중단
+	reader.CloseWithError(err)
+	// 스트림 오류가 원인이면 tar 오류(stderr 포함)로 반환
+	if serr := <-streamErr; serr != nil && (err == nil || errors.Is(err, serr)) {
+		return commandError("tar", srcPath, serr, stderr.String())
+	}
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("tar %s: no files copied", srcPath)
+	}
+	if !opts.Checksum {
+		return nil
+	}
+	return c.verifyChecksum(ctx, podName, Namespace, containerName, files)
+}
 
-	option := &corev1.PodExecOptions{
-		Container: containerName,
-		Command:   cmdArr,
-		Stdin:     true,
-		Stdout:    true,
-		Stderr:    true,
-		TTY:       false,
+// verifyChecksum 복사한 파일과 컨테이너의 sha256sum 비교, 다르면 복사한 파일을 삭제한다.
+func (c *Client) verifyChecksum(ctx context.Context, podName, Namespace, containerName string, files []copiedFile) error {
+	command := []string{"sha256sum"}
+	for _, file := range files {
+		command = append(command, file.Remote)
 	}
 
-	if stdin == nil {
-		option.Stdin = false
+	var stdout bytes.Buffer
+	stderr := &limitedBuffer{max: 4096}
+	if err := c.exec(ctx, podName, Namespace, containerName, command, &stdout, stderr); err != nil {
+		return commandError("sha256sum", strings.Join(command[1:], " "), err, stderr.String())
 	}
+	remote := parseSha256sum(stdout.String())
+	for _, file := range files {
+		if remote[file.Remote] != file.Sum {
+			for _, f := range files {
+				_ = os.Remove(f.Local)
+			}
+			return fmt.Errorf("checksum mismatch for %s: copied %s, pod %q", file.Remote, file.Sum, remote[file.Remote])
+		}
+	}
+	return nil
+}
 
+// exec 컨테이너에서 command 실행 (stdin 없음)
+func (c *Client) exec(ctx context.Context, podName, Namespace, containerName string, command []string, stdout, stderr io.Writer) error {
+	req := c.Clientset.CoreV1().RESTClient().Post().Resource("pods").Name(podName).Namespace(Namespace).SubResource("exec")
 	req.VersionedParams(
-		option,
+		&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+			TTY:       false,
+		},
 		scheme.ParameterCodec,
 	)
 
 	exec, err := remotecommand.NewSPDYExecutor(c.Config, "POST", req.URL())
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
-	go func() {
-		defer outStream.Close()
-		err = exec.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
-			Stdin:  stdin,
-			Stdout: outStream,
-			Stderr: os.Stderr,
-			Tty:    false,
-		})
-		cmdutil.CheckErr(err)
-	}()
-
-	prefix := getPrefix(srcPath)
-	prefix = path.Clean(prefix)
-	destPath = path.Join(destPath, path.Base(prefix))
-	err = untarAll(reader, destPath, prefix)
+	return exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: stderr,
+		Tty:    false,
+	})
+}
 
-	return err
+// commandError 명령이 없으면 ErrCommandNotFound, 그 외에는 stderr 를 붙인 오류
+func commandError(command, args string, err error, stderr string) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var exitErr utilexec.ExitError
+	notFound := errors.As(err, &exitErr) && exitErr.ExitStatus() == 127
+	for _, msg := range []string{err.Error(), stderr} {
+		if strings.Contains(msg, "executable file not found") || strings.Contains(msg, command+": not found") {
+			notFound = true
+		}
+	}
+	if notFound {
+		return fmt.Errorf("%s: %w", command, ErrCommandNotFound)
+	}
+	if stderr != "" {
+		return fmt.Errorf("%s %s: %v: %s", command, args, err, stderr)
+	}
+	return fmt.Errorf("%s %s: %v", command, args, err)
 }
@@ -13,77 +13,242 @@ package k8sClient
 
 import (
 	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	_ "k8s.io/kubectl/pkg/cmd/cp"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
-	_ "unsafe"
+	"sync"
 )
 
-func untarAll(reader io.Reader, destDir, prefix string) error {
+// CopyOptions CopyFromPod 제한 (0 이면 제한 없음)
+type CopyOptions struct {
+	MaxFileSize int64 // 파일 하나 최대 크기(byte)
+	MaxEntries  int   // 최대 tar 항목 수
+	Checksum    bool  // 복사 후 컨테이너의 sha256sum 과 비교
+}
+
+// copiedFile 복사한 일반 파일의 컨테이너 경로, 로컬 경로, sha256
+type copiedFile struct {
+	Remote string
+	Local  string
+	Sum    string
+}
+
+// untarAll srcPath 아래 항목을 destDir/base(srcPath) 에 풀고, 복사한 일반 파일 목록을 반환한다.
+// 파일의 컨테이너 경로는 srcPath 기준이므로 상대경로면 tar 를 실행한 작업 디렉토리 기준이다.
+// destDir 밖을 가리키는 경로, 절대경로 및 destDir 밖으로 나가는 symlink 는 거부한다.
+func untarAll(reader io.Reader, destDir, srcPath string, opts CopyOptions) ([]copiedFile, error) {
+	prefix := path.Clean(getPrefix(srcPath))
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []copiedFile
 	tarReader := tar.NewReader(reader)
-	for {
+	for entries := 1; ; entries++ {
 		header, err := tarReader.Next()
 		if err != nil {
 			if err != io.EOF {
-				return err
+				return nil, err
 			}
 			break
 		}
-
-		if !strings.HasPrefix(header.Name, prefix) {
-			return fmt.Errorf("tar contents corrupted")
+		if opts.MaxEntries > 0 && entries > opts.MaxEntries {
+			return nil, fmt.Errorf("tar has more than %d entries", opts.MaxEntries)
 		}
 
-		mode := header.FileInfo().Mode()
-		destFileName := filepath.Join(destDir, header.Name[len(prefix):])
-
-		baseName := filepath.Dir(destFileName)
-		if err := os.MkdirAll(baseName, 0755); err != nil {
-			return err
-		}
-		if header.FileInfo().IsDir() {
-			if err := os.MkdirAll(destFileName, 0755); err != nil {
-				return err
-			}
-			continue
+		name := path.Clean(strings.TrimLeft(header.Name, "/"))
+		if name != prefix && !strings.HasPrefix(name, prefix+"/") {
+			return nil, fmt.Errorf("tar contents corrupted: %q is outside %q", header.Name, prefix)
 		}
-
-		evaledPath, err := filepath.EvalSymlinks(baseName)
+		destFileName, err := within(root, filepath.FromSlash(path.Base(prefix)+name[len(prefix):]))
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("tar entry %q: %v", header.Name, err)
 		}
 
-		if mode&os.ModeSymlink != 0 {
-			linkname := header.Linkname
-
-			if !filepath.IsAbs(linkname) {
-				_ = filepath.Join(evaledPath, linkname)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirWithin(root, destFileName); err != nil {
+				return nil, err
 			}
-
-			if err := os.Symlink(linkname, destFileName); err != nil {
-				return err
+		case tar.TypeSymlink:
+			if err := symlinkWithin(root, destFileName, header.Linkname); err != nil {
+				return nil, fmt.Errorf("tar entry %q: %v", header.Name, err)
 			}
-		} else {
-			outFile, err := os.Create(destFileName)
-			if err != nil {
-				return err
+		case tar.TypeReg, tar.TypeRegA:
+			if opts.MaxFileSize > 0 && header.Size > opts.MaxFileSize {
+				return nil, fmt.Errorf("tar entry %q: size %d exceeds %d bytes", header.Name, header.Size, opts.MaxFileSize)
 			}
-			defer outFile.Close()
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				return err
+			if err := mkdirWithin(root, filepath.Dir(destFileName)); err != nil {
+				return nil, err
 			}
-			if err := outFile.Close(); err != nil {
-				return err
+			sum, err := writeFile(destFileName, tarReader, header.FileInfo().Mode().Perm(), opts.MaxFileSize)
+			if err != nil {
+				return nil, fmt.Errorf("tar entry %q: %w", header.Name, err)
 			}
+			files = append(files, copiedFile{Remote: path.Join(srcPath, name[len(prefix):]), Local: destFileName, Sum: sum})
+		default:
+			// hard link, device 등은 수집 대상이 아니므로 건너뛴다.
+			logger.LogWarn(fmt.Sprintf("tar entry %q skipped: unsupported type %c", header.Name, header.Typeflag))
 		}
 	}
+	return files, nil
+}
+
+// within root 아래 rel 경로 (root 밖이면 오류)
+func within(root, rel string) (string, error) {
+	if filepath.IsAbs(rel) {
+		return "", fmt.Errorf("absolute path")
+	}
+	dest := filepath.Join(root, rel)
+	if !isWithin(root, dest) {
+		return "", fmt.Errorf("path escapes destination")
+	}
+	return dest, nil
+}
+
+func isWithin(root, dest string) bool {
+	rel, err := filepath.Rel(root, dest)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// mkdirWithin 이미 풀린 symlink 를 따라 root 밖에 폴더를 만들지 않도록 실제 경로 확인
+// 폴더를 만들기 전에 이미 있는 가장 가까운 상위 경로가 root 안으로 풀리는지 먼저 확인한다.
+func mkdirWithin(root, dir string) error {
+	existing := dir
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	if err := resolvesWithin(root, existing); err != nil {
+		return fmt.Errorf("%s: %v", dir, err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := resolvesWithin(root, dir); err != nil {
+		return fmt.Errorf("%s: %v", dir, err)
+	}
+	return nil
+}
 
+// resolvesWithin symlink 를 모두 풀었을 때 root 안인지 확인
+func resolvesWithin(root, name string) error {
+	real, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return err
+	}
+	if !isWithin(root, real) {
+		return fmt.Errorf("resolves outside destination")
+	}
 	return nil
 }
 
+// symlinkWithin root 안을 가리키는 상대경로 symlink 만 만든다.
+func symlinkWithin(root, dest, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("absolute symlink target %q", linkname)
+	}
+	if !isWithin(root, filepath.Join(filepath.Dir(dest), linkname)) {
+		return fmt.Errorf("symlink target %q escapes destination", linkname)
+	}
+	if err := mkdirWithin(root, filepath.Dir(dest)); err != nil {
+		return err
+	}
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(linkname, dest)
+}
+
+// writeFile tar 항목을 파일로 쓰고 sha256 반환, 실패하면 파일을 남기지 않는다.
+func writeFile(dest string, r io.Reader, mode os.FileMode, maxSize int64) (string, error) {
+	// 앞 항목이 만든 symlink 를 따라 쓰지 않도록 먼저 삭제
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if mode == 0 {
+		mode = 0644
+	}
+	outFile, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return "", err
+	}
+	if maxSize > 0 {
+		// header 크기와 다르게 더 많이 읽히는 경우 대비
+		r = io.LimitReader(r, maxSize+1)
+	}
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(outFile, hash), r)
+	if err == nil && maxSize > 0 && n > maxSize {
+		err = fmt.Errorf("size exceeds %d bytes", maxSize)
+	}
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// 중단된 파일은 남기지 않는다.
+		_ = os.Remove(dest)
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// parseSha256sum sha256sum 출력 ("hash  path" 또는 "hash *path") 을 경로별 hash 로
+func parseSha256sum(out string) map[string]string {
+	sums := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimLeft(strings.TrimSpace(fields[1]), "*")
+		sums["/"+path.Clean(strings.TrimLeft(name, "/"))] = strings.ToLower(fields[0])
+	}
+	return sums
+}
+
+// limitedBuffer 처음 max 바이트만 보관하는 stderr 버퍼
+type limitedBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - len(b.buf); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		b.buf = append(b.buf, p[:room]...)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(string(b.buf))
+}
+
 func getPrefix(file string) string {
 	return strings.TrimLeft(file, "/")
 }
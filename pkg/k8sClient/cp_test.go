@@ -0,0 +1,254 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package k8sClient
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry 테스트 tar 항목 (typ 기본 일반 파일)
+type entry struct {
+	name string
+	typ  byte
+	link string
+	body string
+}
+
+func makeTar(t *testing.T, entries ...entry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typ, Linkname: e.link, Mode: 0644}
+		switch e.typ {
+		case 0:
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(e.body))
+		case tar.TypeDir:
+			header.Mode = 0755
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// outsideFiles base 아래 destDir 밖의 항목 (base 의 기존 항목 제외)
+func outsideFiles(t *testing.T, base, destDir string, existing map[string]bool) []string {
+	t.Helper()
+	var found []string
+	err := filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == base || existing[path] {
+			return nil
+		}
+		if path == destDir || strings.HasPrefix(path, destDir+string(filepath.Separator)) {
+			return filepath.SkipDir
+		}
+		found = append(found, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestUntarAllRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CopyOptions
+		entries []entry
+		err     string
+	}{
+		{
+			name:    "parent path",
+			entries: []entry{{name: "../x.csv", body: "x"}},
+			err:     "is outside",
+		},
+		{
+			name:    "parent path inside prefix",
+			entries: []entry{{name: "tmp/csv/../../x.csv", body: "x"}},
+			err:     "is outside",
+		},
+		{
+			name:    "absolute name",
+			entries: []entry{{name: "/etc/cron.d/x", body: "x"}},
+			err:     "is outside",
+		},
+		{
+			name:    "absolute symlink",
+			entries: []entry{{name: "tmp/csv/link", typ: tar.TypeSymlink, link: "/etc"}},
+			err:     "absolute symlink target",
+		},
+		{
+			name:    "escaping symlink",
+			entries: []entry{{name: "tmp/csv/link", typ: tar.TypeSymlink, link: "../../outside"}},
+			err:     "escapes destination",
+		},
+		{
+			// up -> destDir, esc -> up/.. (destDir 의 상위) 를 거쳐 쓰기
+			name: "symlink then write through",
+			entries: []entry{
+				{name: "tmp/csv/up", typ: tar.TypeSymlink, link: ".."},
+				{name: "tmp/csv/esc", typ: tar.TypeSymlink, link: "up/.."},
+				{name: "tmp/csv/esc/pwn/x.csv", body: "x"},
+			},
+			err: "resolves outside destination",
+		},
+		{
+			name: "symlink then write file through",
+			entries: []entry{
+				{name: "tmp/csv/up", typ: tar.TypeSymlink, link: ".."},
+				{name: "tmp/csv/esc", typ: tar.TypeSymlink, link: "up/.."},
+				{name: "tmp/csv/esc/x.csv", body: "x"},
+			},
+			err: "resolves outside destination",
+		},
+		{
+			name: "symlink then symlink through",
+			entries: []entry{
+				{name: "tmp/csv/up", typ: tar.TypeSymlink, link: ".."},
+				{name: "tmp/csv/esc", typ: tar.TypeSymlink, link: "up/.."},
+				{name: "tmp/csv/esc/link", typ: tar.TypeSymlink, link: "."},
+			},
+			err: "resolves outside destination",
+		},
+		{
+			name:    "oversized entry",
+			opts:    CopyOptions{MaxFileSize: 10},
+			entries: []entry{{name: "tmp/csv/AMFTPS.csv", body: strings.Repeat("x", 11)}},
+			err:     "size 11 exceeds 10 bytes",
+		},
+		{
+			name: "too many entries",
+			opts: CopyOptions{MaxEntries: 2},
+			entries: []entry{
+				{name: "tmp/csv/", typ: tar.TypeDir},
+				{name: "tmp/csv/AMFTPS.csv", body: "x"},
+				{name: "tmp/csv/AMFMS.csv", body: "x"},
+			},
+			err: "more than 2 entries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			destDir := filepath.Join(base, "dest")
+			sibling := filepath.Join(base, "outside")
+			if err := os.WriteFile(sibling, []byte("keep"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := untarAll(makeTar(t, tt.entries...), destDir, "tmp/csv", tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+
+			if found := outsideFiles(t, base, destDir, map[string]bool{sibling: true}); len(found) > 0 {
+				t.Errorf("written outside destDir: %v", found)
+			}
+			if b, _ := os.ReadFile(sibling); string(b) != "keep" {
+				t.Errorf("outside file changed: %q", b)
+			}
+			// 크기 초과 파일은 destDir 에도 남지 않는다.
+			if _, err := os.Stat(filepath.Join(destDir, "csv", "AMFTPS.csv")); tt.opts.MaxFileSize > 0 && !os.IsNotExist(err) {
+				t.Errorf("oversized file left in destDir: %v", err)
+			}
+		})
+	}
+}
+
+func TestUntarAll(t *testing.T) {
+	// 컨테이너 경로는 srcPath 기준 (상대경로면 tar 를 실행한 작업 디렉토리 기준)
+	for _, srcPath := range []string{"/tmp/csv", "/tmp/csv/", "tmp/csv"} {
+		t.Run(srcPath, func(t *testing.T) {
+			destDir := t.TempDir()
+			files, err := untarAll(makeTar(t,
+				entry{name: "tmp/csv/", typ: tar.TypeDir},
+				entry{name: "tmp/csv/20231108/AMFTPS.csv", body: "a,b\n"},
+				entry{name: "tmp/csv/latest", typ: tar.TypeSymlink, link: "20231108/AMFTPS.csv"},
+				// 앞 항목의 symlink 를 따라 쓰지 않고 파일로 바꾼다.
+				entry{name: "tmp/csv/latest", body: "c,d\n"},
+			), destDir, srcPath, CopyOptions{MaxFileSize: 10, MaxEntries: 4})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dir := path.Clean(srcPath)
+			if len(files) != 2 || files[0].Remote != dir+"/20231108/AMFTPS.csv" || files[1].Remote != dir+"/latest" {
+				t.Fatalf("files = %+v", files)
+			}
+			if sum := sha256.Sum256([]byte("a,b\n")); files[0].Sum != hex.EncodeToString(sum[:]) {
+				t.Errorf("sum = %s", files[0].Sum)
+			}
+			for local, want := range map[string]string{"20231108/AMFTPS.csv": "a,b\n", "latest": "c,d\n"} {
+				b, err := os.ReadFile(filepath.Join(destDir, "csv", local))
+				if err != nil || string(b) != want {
+					t.Errorf("%s = %q, %v, want %q", local, b, err, want)
+				}
+			}
+			if info, err := os.Lstat(filepath.Join(destDir, "csv", "latest")); err != nil || info.Mode()&os.ModeSymlink != 0 {
+				t.Errorf("latest should be a regular file: %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteFileLimit(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "AMFTPS.csv")
+
+	// header 보다 많이 읽히는 경우에도 MaxFileSize 를 넘기면 파일을 남기지 않는다.
+	if _, err := writeFile(dest, strings.NewReader(strings.Repeat("x", 20)), 0644, 10); err == nil || !strings.Contains(err.Error(), "exceeds 10 bytes") {
+		t.Fatalf("error = %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("partial file left: %v", err)
+	}
+
+	// dest 가 밖을 가리키는 symlink 이면 따라 쓰지 않고 바꾼다.
+	outside := filepath.Join(t.TempDir(), "outside")
+	if err := os.WriteFile(outside, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeFile(dest, strings.NewReader("new"), 0644, 10); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(outside); string(b) != "keep" {
+		t.Errorf("outside file changed: %q", b)
+	}
+	if b, _ := os.ReadFile(dest); string(b) != "new" {
+		t.Errorf("dest = %q", b)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package kubestate
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/k8sClient"
+	"time"
+)
+
+// informer 재동기화 주기
+const resync = 10 * time.Minute
+
+var podPhases = []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown}
+
+// Collector 네임스페이스별 pod informer 캐시로 pod 상태 메트릭 생성 (scrape 마다 API 조회 없음)
+// 메트릭 이름은 kube-state-metrics 와 같게 하여 기존 대시보드를 그대로 사용한다.
+type Collector struct {
+	caches []podCache
+
+	phase       *prometheus.Desc
+	info        *prometheus.Desc
+	restarts    *prometheus.Desc
+	ready       *prometheus.Desc
+	terminated  *prometheus.Desc
+	cacheSynced *prometheus.Desc
+}
+
+// podCache 네임스페이스 하나의 pod informer 캐시
+type podCache struct {
+	namespace string
+	lister    corelisters.PodLister
+	synced    cache.InformerSynced
+}
+
+// NewCollector config.Namespaces 의 pod informer 시작, ctx 가 끝나면 감시 중단
+func NewCollector(ctx context.Context, config cfg.KubeState) (*Collector, error) {
+	if len(config.Namespaces) == 0 {
+		return nil, fmt.Errorf("kube_state.NAMESPACES is empty")
+	}
+	restConfig, err := k8sClient.NewConfig(config.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	client := k8sClient.NewK8sClient(*restConfig, clientset)
+
+	c := &Collector{
+		phase: prometheus.NewDesc("kube_pod_status_phase",
+			"The pods current phase.", []string{"namespace", "pod", "phase"}, nil),
+		info: prometheus.NewDesc("kube_pod_info",
+			"Information about pod.", []string{"namespace", "pod", "node", "host_ip", "pod_ip"}, nil),
+		restarts: prometheus.NewDesc("kube_pod_container_status_restarts_total",
+			"The number of container restarts per container.", []string{"namespace", "pod", "container"}, nil),
+		ready: prometheus.NewDesc("kube_pod_container_status_ready",
+			"Describes whether the containers readiness check succeeded.", []string{"namespace", "pod", "container"}, nil),
+		terminated: prometheus.NewDesc("kube_pod_container_status_last_terminated_reason",
+			"Describes the last reason the container was in terminated state.", []string{"namespace", "pod", "container", "reason"}, nil),
+		cacheSynced: prometheus.NewDesc(prometheus.BuildFQName("cnf_exporter", "kube_state", "synced"),
+			"Whether the pod informer cache of the namespace has synced", []string{"namespace"}, nil),
+	}
+	for _, namespace := range config.Namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(client.Clientset, resync, informers.WithNamespace(namespace))
+		pods := factory.Core().V1().Pods()
+		// 메트릭에 쓰지 않는 필드는 캐시에 보관하지 않는다.
+		if err := pods.Informer().SetTransform(trimPod); err != nil {
+			return nil, err
+		}
+		c.caches = append(c.caches, podCache{namespace: namespace, lister: pods.Lister(), synced: pods.Informer().HasSynced})
+		factory.Start(ctx.Done())
+		logger.LogInfo("kube state informer started : " + namespace)
+	}
+	return c, nil
+}
+
+func trimPod(obj interface{}) (interface{}, error) {
+	if pod, ok := obj.(*corev1.Pod); ok {
+		pod.ManagedFields = nil
+		pod.Annotations = nil
+		pod.Spec.Volumes = nil
+		pod.Spec.InitContainers = nil
+		pod.Spec.Containers = nil
+	}
+	return obj, nil
+}
+
+// Describe prometheus describe
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.phase
+	ch <- c.info
+	ch <- c.restarts
+	ch <- c.ready
+	ch <- c.terminated
+	ch <- c.cacheSynced
+}
+
+// Collect prometheus collect, 동기화 전인 네임스페이스는 synced 0 만 내보낸다.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, podCache := range c.caches {
+		synced := podCache.synced()
+		ch <- prometheus.MustNewConstMetric(c.cacheSynced, prometheus.GaugeValue, boolValue(synced), podCache.namespace)
+		if !synced {
+			continue
+		}
+		pods, err := podCache.lister.List(labels.Everything())
+		if err != nil {
+			logger.LogErr("kube state pod list", err)
+			continue
+		}
+		for _, pod := range pods {
+			c.collectPod(ch, pod)
+		}
+	}
+}
+
+func (c *Collector) collectPod(ch chan<- prometheus.Metric, pod *corev1.Pod) {
+	for _, phase := range podPhases {
+		ch <- prometheus.MustNewConstMetric(c.phase, prometheus.GaugeValue, boolValue(pod.Status.Phase == phase), pod.Namespace, pod.Name, string(phase))
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, pod.Namespace, pod.Name, pod.Spec.NodeName, pod.Status.HostIP, pod.Status.PodIP)
+
+	for _, status := range pod.Status.ContainerStatuses {
+		ch <- prometheus.MustNewConstMetric(c.restarts, prometheus.CounterValue, float64(status.RestartCount), pod.Namespace, pod.Name, status.Name)
+		ch <- prometheus.MustNewConstMetric(c.ready, prometheus.GaugeValue, boolValue(status.Ready), pod.Namespace, pod.Name, status.Name)
+		if last := status.LastTerminationState.Terminated; last != nil && last.Reason != "" {
+			ch <- prometheus.MustNewConstMetric(c.terminated, prometheus.GaugeValue, 1, pod.Namespace, pod.Name, status.Name, last.Reason)
+		}
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
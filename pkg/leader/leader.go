@@ -0,0 +1,147 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package leader
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/k8sClient"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Elector Lease 로 leader 선출, leader 를 잃으면 다시 후보로 참여한다.
+type Elector struct {
+	identity string
+	leading  atomic.Bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	leaderDesc *prometheus.Desc
+}
+
+// Start config.Namespace/config.Name Lease 로 선출 시작, Stop 전까지 계속 참여
+func Start(config cfg.LeaderElection) (*Elector, error) {
+	identity := config.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		identity = hostname
+	}
+	restConfig, err := k8sClient.NewConfig(config.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return start(clientset, config, identity, time.Second)
+}
+
+// start clientset 으로 선출 시작, Lease 시간 설정은 unit 단위 (테스트는 짧은 단위를 쓴다)
+func start(clientset kubernetes.Interface, config cfg.LeaderElection, identity string, unit time.Duration) (*Elector, error) {
+	e := &Elector{
+		identity: identity,
+		done:     make(chan struct{}),
+		leaderDesc: prometheus.NewDesc(prometheus.BuildFQName("cnf_exporter", "", "leader"),
+			"Whether this replica holds the leader lease and runs the OSS collection", []string{"identity"}, nil),
+	}
+	electionConfig := leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  v1.ObjectMeta{Namespace: config.Namespace, Name: config.Name},
+			Client:     clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration: time.Duration(config.LeaseDuration) * unit,
+		RenewDeadline: time.Duration(config.RenewDeadline) * unit,
+		RetryPeriod:   time.Duration(config.RetryPeriod) * unit,
+		// 종료시 Lease 를 반납하여 다른 replica 가 LeaseDuration 을 기다리지 않게 한다.
+		ReleaseOnCancel: true,
+		Name:            config.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				e.leading.Store(true)
+				logger.LogInfo("leader lease acquired", zap.String("identity", identity))
+			},
+			OnStoppedLeading: func() {
+				if e.leading.Swap(false) {
+					logger.LogWarn("leader lease lost", zap.String("identity", identity))
+				}
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					logger.LogInfo("following leader", zap.String("leader", current))
+				}
+			},
+		},
+	}
+	// 설정 오류는 시작 전에 반환
+	elector, err := leaderelection.NewLeaderElector(electionConfig)
+	if err != nil {
+		return nil, fmt.Errorf("leader election %s/%s: %v", config.Namespace, config.Name, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	go func() {
+		defer close(e.done)
+		for {
+			// Run 은 leader 를 잃거나 ctx 가 끝나면 반환
+			elector.Run(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	logger.LogInfo("leader election started", zap.String("lease", config.Namespace+"/"+config.Name), zap.String("identity", identity))
+	return e, nil
+}
+
+// Leading leader 여부, 선출을 사용하지 않으면(nil) 항상 true
+func (e *Elector) Leading() bool {
+	return e == nil || e.leading.Load()
+}
+
+// Stop 선출을 중단하고 leader 이면 Lease 반납 후 반환
+func (e *Elector) Stop() {
+	if e == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+}
+
+// Describe prometheus describe
+func (e *Elector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.leaderDesc
+}
+
+// Collect prometheus collect
+func (e *Elector) Collect(ch chan<- prometheus.Metric) {
+	value := 0.0
+	if e.Leading() {
+		value = 1
+	}
+	ch <- prometheus.MustNewConstMetric(e.leaderDesc, prometheus.GaugeValue, value, e.identity)
+}
@@ -0,0 +1,156 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package leader
+
+import (
+	"context"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"testing"
+	"time"
+)
+
+// 테스트 Lease 시간 단위 (LeaseDuration 2s, RenewDeadline 1s, RetryPeriod 500ms)
+// Lease 에는 LeaseDuration 이 초 단위로 기록되므로 1초보다 짧게 하지 않는다.
+const unit = 500 * time.Millisecond
+
+var testConfig = cfg.LeaderElection{
+	Enable:        true,
+	Namespace:     "p5g",
+	Name:          "cnf-exporter",
+	LeaseDuration: 4,
+	RenewDeadline: 2,
+	RetryPeriod:   1,
+}
+
+func startElector(t *testing.T, clientset kubernetes.Interface, identity string) *Elector {
+	t.Helper()
+	e, err := start(clientset, testConfig, identity, unit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Stop)
+	return e
+}
+
+// holder Lease 의 현재 holder 와 갱신 시각
+func holder(t *testing.T, clientset kubernetes.Interface) (string, time.Time) {
+	t.Helper()
+	lease, err := clientset.CoordinationV1().Leases(testConfig.Namespace).Get(context.Background(), testConfig.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var identity string
+	if lease.Spec.HolderIdentity != nil {
+		identity = *lease.Spec.HolderIdentity
+	}
+	var renewed time.Time
+	if lease.Spec.RenewTime != nil {
+		renewed = lease.Spec.RenewTime.Time
+	}
+	return identity, renewed
+}
+
+// waitFor cond 가 참이 될 때까지 대기
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(4 * time.Duration(testConfig.LeaseDuration) * unit)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(unit / 10)
+	}
+}
+
+func TestAcquireAndRenew(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	e := startElector(t, clientset, "replica-a")
+	waitFor(t, "replica-a leading", e.Leading)
+
+	identity, acquired := holder(t, clientset)
+	if identity != "replica-a" {
+		t.Fatalf("holder = %q, want replica-a", identity)
+	}
+	// LeaseDuration 이 지나도 갱신하여 leader 를 유지한다.
+	time.Sleep(time.Duration(testConfig.LeaseDuration) * unit)
+	identity, renewed := holder(t, clientset)
+	if !e.Leading() || identity != "replica-a" || !renewed.After(acquired) {
+		t.Errorf("leading = %v, holder = %q, renewed %v after %v", e.Leading(), identity, renewed, acquired)
+	}
+}
+
+// holder 가 갱신을 멈추면 LeaseDuration 이후 다른 replica 가 넘겨받는다.
+func TestHandoverOnExpiry(t *testing.T) {
+	ghost := "replica-ghost"
+	now := v1.NewMicroTime(time.Now())
+	duration := int32(time.Duration(testConfig.LeaseDuration) * unit / time.Second)
+	clientset := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: v1.ObjectMeta{Namespace: testConfig.Namespace, Name: testConfig.Name},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &ghost,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+	started := time.Now()
+	e := startElector(t, clientset, "replica-b")
+	time.Sleep(unit)
+	if e.Leading() {
+		t.Fatal("replica-b leading before the ghost lease expired")
+	}
+
+	waitFor(t, "replica-b leading", e.Leading)
+	if elapsed := time.Since(started); elapsed < time.Duration(testConfig.LeaseDuration)*unit {
+		t.Errorf("took over after %v, before LeaseDuration", elapsed)
+	}
+	if identity, _ := holder(t, clientset); identity != "replica-b" {
+		t.Errorf("holder = %q, want replica-b", identity)
+	}
+}
+
+// Stop 은 Lease 를 반납하여 다른 replica 가 LeaseDuration 을 기다리지 않는다.
+func TestReleaseOnStop(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	a := startElector(t, clientset, "replica-a")
+	waitFor(t, "replica-a leading", a.Leading)
+	b := startElector(t, clientset, "replica-b")
+	time.Sleep(2 * time.Duration(testConfig.RetryPeriod) * unit)
+	if b.Leading() {
+		t.Fatal("replica-b leading while replica-a holds the lease")
+	}
+
+	a.Stop()
+	if a.Leading() {
+		t.Error("replica-a still leading after Stop")
+	}
+	if identity, _ := holder(t, clientset); identity != "" && identity != "replica-b" {
+		t.Errorf("holder after Stop = %q, want released", identity)
+	}
+	stopped := time.Now()
+	waitFor(t, "replica-b leading", b.Leading)
+	if elapsed := time.Since(stopped); elapsed >= time.Duration(testConfig.LeaseDuration)*unit {
+		t.Errorf("replica-b took over after %v, want before LeaseDuration", elapsed)
+	}
+}
+
+func TestLeadingNil(t *testing.T) {
+	var e *Elector
+	if !e.Leading() {
+		t.Error("nil elector should always lead")
+	}
+	e.Stop()
+}
@@ -0,0 +1,188 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package lifecycle
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"net/http"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// 강제 취소 후 진행중인 수집이 정리될때까지 기다리는 시간
+const cancelGrace = 5 * time.Second
+
+// Manager HTTP 서버와 수집 주기의 시작/종료 관리
+// SIGTERM(SIGINT) 수신시 readiness 를 내리고 새 수집을 막은 뒤, 진행중인 수집이 끝나기를 기다린다.
+// 종료 제한시간이 지나면 context 를 취소해 curl, CopyFromPod, scrape 요청을 중단시키고 서버를 종료한다.
+type Manager struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	stopCtx    context.Context
+	stopCancel context.CancelFunc
+	timeout    time.Duration
+
+	ready    atomic.Bool
+	draining atomic.Bool
+	cycles   sync.WaitGroup
+	mu       sync.Mutex
+	hooks    []func()
+}
+
+func NewManager(shutdownTimeout time.Duration) *Manager {
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopCtx, stopCancel := context.WithCancel(ctx)
+	return &Manager{ctx: ctx, cancel: cancel, stopCtx: stopCtx, stopCancel: stopCancel, timeout: shutdownTimeout}
+}
+
+// Context 종료 제한시간이 지나면 취소되는 context (진행중인 요청 중단용)
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
+// Stopping 종료가 시작되면 바로 취소되는 context (주기 실행 중지용)
+func (m *Manager) Stopping() context.Context {
+	return m.stopCtx
+}
+
+// Ready 요청을 받을 수 있는 상태 (종료 시작시 false)
+func (m *Manager) Ready() bool {
+	return m.ready.Load() && !m.draining.Load()
+}
+
+// ShuttingDown 종료가 시작되었는지
+func (m *Manager) ShuttingDown() bool {
+	return m.draining.Load()
+}
+
+// BeginCycle 수집 주기 시작, 종료중이면 false
+// 수집이 끝나면 반드시 done 을 호출해야 종료 대기가 풀린다.
+func (m *Manager) BeginCycle() (ctx context.Context, done func(), ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.draining.Load() {
+		return m.ctx, func() {}, false
+	}
+	m.cycles.Add(1)
+	var once sync.Once
+	return m.ctx, func() { once.Do(m.cycles.Done) }, true
+}
+
+// OnShutdown 서버 종료 후 실행할 정리 작업 등록 (등록 역순 실행)
+func (m *Manager) OnShutdown(hook func()) {
+	m.mu.Lock()
+	m.hooks = append(m.hooks, hook)
+	m.mu.Unlock()
+}
+
+// Run 서버를 실행하고 종료 신호를 받을때까지 대기 후 순서대로 종료
+func (m *Manager) Run(server *http.Server, listen func() error) error {
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	// 신호를 받은 뒤 두번째 신호는 기본 동작(즉시 종료)
+	go func() {
+		<-signalCtx.Done()
+		stop()
+	}()
+	return m.run(signalCtx, server, listen)
+}
+
+// run signalCtx 가 끝나면 종료 시작
+func (m *Manager) run(signalCtx context.Context, server *http.Server, listen func() error) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- listen()
+	}()
+	m.ready.Store(true)
+
+	select {
+	case err := <-serverErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.cancel()
+			return err
+		}
+		return nil
+	case <-signalCtx.Done():
+	}
+
+	start := time.Now()
+	logger.LogInfo("shutdown started, draining collection", zap.Duration("timeout", m.timeout))
+	m.mu.Lock()
+	m.draining.Store(true)
+	m.mu.Unlock()
+	m.stopCancel()
+
+	if !m.wait(m.timeout) {
+		logger.LogWarn("shutdown timeout, cancel running collection")
+		m.cancel()
+		if !m.wait(cancelGrace) {
+			logger.LogWarn("collection did not stop after cancel")
+		}
+	}
+	m.cancel()
+
+	remaining := m.timeout - time.Since(start)
+	if remaining < time.Second {
+		remaining = time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), remaining)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.LogErr("http server shutdown", err)
+		_ = server.Close()
+	}
+
+	m.mu.Lock()
+	hooks := m.hooks
+	m.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	logger.LogInfo("shutdown completed", zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// 진행중인 수집이 timeout 안에 끝나면 true
+func (m *Manager) wait(timeout time.Duration) bool {
+	finished := make(chan struct{})
+	go func() {
+		m.cycles.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Sleep ctx 가 취소되면 바로 반환, 취소되었으면 false
+func Sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,203 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 테스트용 HTTP 서버 실행 함수
+func newServer(t *testing.T) (*http.Server, func() error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.NotFoundHandler()}
+	return server, func() error { return server.Serve(ln) }
+}
+
+// run 을 백그라운드로 실행하고 종료 신호용 cancel 과 결과 채널 반환
+func start(t *testing.T, m *Manager) (context.CancelFunc, <-chan error) {
+	t.Helper()
+	server, listen := newServer(t)
+	signalCtx, signal := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- m.run(signalCtx, server, listen)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !m.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("manager is not ready")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return signal, result
+}
+
+func waitResult(t *testing.T, result <-chan error, timeout time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		t.Fatal("run did not return")
+		return nil
+	}
+}
+
+// 종료 신호 후 새 수집은 막고 진행중인 수집이 끝날때까지 기다린다.
+func TestBeginCycleDrain(t *testing.T) {
+	m := NewManager(5 * time.Second)
+	signal, result := start(t, m)
+
+	ctx, done, ok := m.BeginCycle()
+	if !ok {
+		t.Fatal("BeginCycle refused before shutdown")
+	}
+
+	var mu sync.Mutex
+	var events []string
+	m.OnShutdown(func() {
+		mu.Lock()
+		events = append(events, "hook")
+		mu.Unlock()
+	})
+
+	signal()
+	select {
+	case <-m.Stopping().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stopping is not cancelled")
+	}
+	if m.Ready() || !m.ShuttingDown() {
+		t.Errorf("Ready = %v, ShuttingDown = %v after signal", m.Ready(), m.ShuttingDown())
+	}
+	if _, _, ok := m.BeginCycle(); ok {
+		t.Error("BeginCycle accepted while draining")
+	}
+
+	// 진행중인 수집은 제한시간 전까지 취소되지 않고 끝날때까지 대기
+	select {
+	case <-result:
+		t.Fatal("run returned before the cycle finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("cycle context is cancelled before the timeout: %v", ctx.Err())
+	}
+	mu.Lock()
+	events = append(events, "done")
+	mu.Unlock()
+	done()
+	done() // 중복 호출 허용
+
+	if err := waitResult(t, result, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(events, []string{"done", "hook"}) {
+		t.Errorf("events = %v, want hook after the cycle", events)
+	}
+	if m.Context().Err() == nil {
+		t.Error("Context is not cancelled after shutdown")
+	}
+}
+
+// 제한시간이 지나면 수집 context 를 취소하고 종료한다.
+func TestShutdownTimeout(t *testing.T) {
+	m := NewManager(100 * time.Millisecond)
+	signal, result := start(t, m)
+
+	ctx, done, ok := m.BeginCycle()
+	if !ok {
+		t.Fatal("BeginCycle refused")
+	}
+	cancelled := make(chan time.Time, 1)
+	go func() {
+		defer done()
+		<-ctx.Done()
+		cancelled <- time.Now()
+	}()
+
+	began := time.Now()
+	signal()
+	if err := waitResult(t, result, cancelGrace); err != nil {
+		t.Fatal(err)
+	}
+	at := <-cancelled
+	if elapsed := at.Sub(began); elapsed < 100*time.Millisecond {
+		t.Errorf("cycle cancelled after %v, want >= timeout", elapsed)
+	}
+}
+
+// 서버가 시작하지 못하면 종료 대기 없이 오류 반환
+func TestRunServerError(t *testing.T) {
+	m := NewManager(time.Second)
+	listenErr := errors.New("address already in use")
+	err := m.run(context.Background(), &http.Server{}, func() error { return listenErr })
+	if !errors.Is(err, listenErr) {
+		t.Fatalf("run = %v, want %v", err, listenErr)
+	}
+	if m.Context().Err() == nil {
+		t.Error("Context is not cancelled")
+	}
+}
+
+func TestOnShutdownReverseOrder(t *testing.T) {
+	m := NewManager(time.Second)
+	var order []int
+	for i := 1; i <= 3; i++ {
+		i := i
+		m.OnShutdown(func() { order = append(order, i) })
+	}
+	signal, result := start(t, m)
+	signal()
+	if err := waitResult(t, result, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []int{3, 2, 1}) {
+		t.Errorf("hooks = %v, want [3 2 1]", order)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	if !Sleep(context.Background(), time.Millisecond) {
+		t.Error("Sleep returned false without cancel")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	began := time.Now()
+	if Sleep(ctx, time.Hour) {
+		t.Error("Sleep returned true after cancel")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if Sleep(ctx, time.Hour) {
+		t.Error("Sleep returned true when cancelled while sleeping")
+	}
+	if elapsed := time.Since(began); elapsed > 5*time.Second {
+		t.Errorf("Sleep took %v after cancel", elapsed)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package metricApi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSV 의 LOCATION 컬럼
+const locationColumn = 6
+
+// Group 그룹(location 등) 하나에 대한 집계값
+type Group struct {
+	Name  string
+	Value float64
+	Rows  int
+}
+
+// FilterRows LoadFamily 와 같이 cnf_config.yml filters 를 적용한 데이터 반환
+func FilterRows(name string, data [][]string) [][]string {
+	data, _ = rowFilter.Apply(name, data)
+	return data
+}
+
+// Aggregate /api/metrics 와 같은 방식으로 데이터 행(4번째 행부터)의 column 값을 집계한다 (sum|avg|max|min).
+// 숫자가 아니거나 빈 값은 0 으로 계산해 avg 의 행수에 포함하고, column 이 없는 행만 제외한다.
+func Aggregate(data [][]string, column int, agg string) Group {
+	var group Group
+	for i := 3; i < len(data); i++ {
+		if column >= len(data[i]) {
+			continue
+		}
+		val, _ := strconv.ParseFloat(strings.TrimSpace(data[i][column]), 64)
+
+		switch {
+		case group.Rows == 0:
+			group.Value = val
+		case agg == "max":
+			if val > group.Value {
+				group.Value = val
+			}
+		case agg == "min":
+			if val < group.Value {
+				group.Value = val
+			}
+		default:
+			group.Value += val
+		}
+		group.Rows++
+	}
+	if agg == "avg" && group.Rows > 0 {
+		group.Value = group.Value / float64(group.Rows)
+	}
+	return group
+}
+
+// AggregateBy groupIndex 컬럼 값별로 Aggregate 한 결과를 그룹명 순으로 반환한다. groupIndex 가 음수면 전체를 그룹 "" 하나로 집계한다.
+// location 은 ran 상세와 같이 해당 location 을 포함하는 행을 모두 그 그룹으로 집계한다.
+func AggregateBy(data [][]string, column, groupIndex int, agg string) []Group {
+	if len(data) <= 3 {
+		return nil
+	}
+	if groupIndex < 0 {
+		return []Group{Aggregate(data, column, agg)}
+	}
+
+	var names []string
+	unique := make(map[string]struct{})
+	for i := 3; i < len(data); i++ {
+		if groupIndex >= len(data[i]) {
+			continue
+		}
+		if _, exists := unique[data[i][groupIndex]]; !exists {
+			names = append(names, data[i][groupIndex])
+			unique[data[i][groupIndex]] = struct{}{}
+		}
+	}
+	sort.Strings(names)
+
+	groups := make([]Group, 0, len(names))
+	for _, name := range names {
+		rows := [][]string{nil, nil, nil}
+		for i := 3; i < len(data); i++ {
+			if groupIndex >= len(data[i]) {
+				continue
+			}
+			if data[i][groupIndex] == name || (groupIndex == locationColumn && strings.Contains(data[i][groupIndex], name)) {
+				rows = append(rows, data[i])
+			}
+		}
+		group := Aggregate(rows, column, agg)
+		group.Name = name
+		groups = append(groups, group)
+	}
+	return groups
+}
@@ -0,0 +1,49 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package metricApi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateBy(t *testing.T) {
+	data := [][]string{
+		{"Family name : AMFTPS"},
+		{"Period : 15min"},
+		{"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "TotalMsg(count)"},
+		{"ne101", "1", "NE-101", "2023-11-08 13:15:00", "+09:00", "900", "DU_1", "10"},
+		{"ne102", "1", "NE-102", "2023-11-08 13:15:00", "+09:00", "900", "DU_1_A", "20"},
+		{"ne101", "1", "NE-101", "2023-11-08 13:15:00", "+09:00", "900", "DU_2", "x"},
+	}
+	tests := []struct {
+		name       string
+		groupIndex int
+		agg        string
+		want       []Group
+	}{
+		// location 은 ran 상세와 같이 포함 관계로 집계
+		{"location contains", 6, "sum", []Group{{"DU_1", 30, 2}, {"DU_1_A", 20, 1}, {"DU_2", 0, 1}}},
+		{"ne_id exact", 0, "max", []Group{{"ne101", 10, 2}, {"ne102", 20, 1}}},
+		{"none", -1, "avg", []Group{{"", 10, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AggregateBy(data, 7, tt.groupIndex, tt.agg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AggregateBy = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := AggregateBy(data[:3], 7, 6, "sum"); got != nil {
+		t.Errorf("AggregateBy(no rows) = %+v, want nil", got)
+	}
+}
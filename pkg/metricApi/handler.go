@@ -15,102 +15,123 @@ import (
 	"github.com/gin-gonic/gin"
 	g "github.com/gosnmp/gosnmp"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/snapshot"
 	"log"
 	"net/http"
 	"os"
 )
 
+// CnfMetricHandler RAN/Core 요약 API
+// SITES 설정시 ?site= 로 사이트를 선택하고, 없으면 전체 사이트 결과를 sites 아래에 반환한다.
 func CnfMetricHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ymlConfig := cfg.InitConfig()
 
-		// RAN 기준 location 추출
-		airLocations, err := FindCommonLocation("Air_MAC_Packet", ymlConfig)
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"data":  "Ran.location is not find",
-				"error": err,
-			})
-			return
-		}
-		cellLocations, err := FindCommonLocation("Air_MAC_Packet_(PCell)", ymlConfig)
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"data":  "Ran.location is not find",
-				"error": err,
-			})
+		site := c.Query("site")
+		if !ymlConfig.MultiSite() || site != "" {
+			siteConfig, ok := ymlConfig.SiteConfig(site)
+			if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"data": "site is not find", "site": site})
+				return
+			}
+			data, msg, err := BuildMetrics(siteConfig)
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"data":  msg,
+					"error": err,
+				})
+				return
+			}
+			c.JSON(http.StatusOK, data)
 			return
 		}
 
-		data := &Metrics{}
-		appsInfo := []RanAppDetailInfo{}
-		phyInfo := []RanPhysicalDetailInfo{}
-		coreInfo := []CoreAppDetailInfo{}
-
-		// ran.application.detail의 json값 과 ran.application.sum 중 ueActiveDLAvg,ueActiveDLMax 값을 반환
-		totAvgSum, totMaxSum, totAirMacUL, totAirMacDL, err := FincRanAppDetail(airLocations, ymlConfig, appsInfo, data)
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"data":  "Ran.application is not find",
-				"error": err,
-			})
-			return
+		// 한 사이트 실패가 다른 사이트 결과를 막지 않는다.
+		sites := map[string]*Metrics{}
+		errs := map[string]string{}
+		for _, siteConfig := range ymlConfig.SiteConfigs() {
+			data, msg, err := BuildMetrics(siteConfig)
+			if err != nil {
+				errs[siteConfig.Site] = msg + ": " + err.Error()
+				continue
+			}
+			sites[siteConfig.Site] = data
 		}
-		data.Ran.Application.Sum.UeActiveDLAvg = totAvgSum
-		data.Ran.Application.Sum.UeActiveDLMax = totMaxSum
-		data.Ran.Application.Sum.AirMacULByte = totAirMacUL
-		data.Ran.Application.Sum.AirMacDLByte = totAirMacDL
-
-		// ran.physical.detail의 json값과 ran.physical.sum 의 AirMacULByte_PCELL, AirMacDLByte_PCELL, AirMacULByte_SCELL, AirMacDLByte_SCELL 값을 반환
-		totPcellUL, totPcellDL, totScellUL, totScellDL, err := FincRanPhysicalDetail(cellLocations, ymlConfig, phyInfo, data)
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"data":  "ran.physical is not find",
-				"error": err,
-			})
+		if len(sites) == 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"sites": sites, "errors": errs})
 			return
 		}
-		data.Ran.Physical.Sum.AirMacULByte_PCELL = totPcellUL
-		data.Ran.Physical.Sum.AirMacDLByte_PCELL = totPcellDL
-		data.Ran.Physical.Sum.AirMacULByte_SCELL = totScellUL
-		data.Ran.Physical.Sum.AirMacDLByte_SCELL = totScellDL
+		c.JSON(http.StatusOK, gin.H{"sites": sites, "errors": errs})
+	}
+}
 
-		// core.application.detail의 json값과 core.application.sum의 UeconAmfCRatio 값을 반환
-		ueconAvgSum, err := FindUECONAppDetail(ymlConfig.File.CORE_NAME, ymlConfig, coreInfo, data)
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"data":  "Core.UECON_AMF is not find",
-				"error": err,
-			})
-			return
-		}
-		// core.application.sum의 ueidAvgSum 값을 반환
-		ueidAvgSum, err := FindUEIDAppDetail(ymlConfig.File.CORE_NAME, ymlConfig)
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"data":  "Core.UEID_AMF is not find",
-				"error": err,
-			})
-			return
-		}
-		// core.application.sum의 amftpsSum,amfmsSum 값을 반환
-		amftpsSum, amfmsSum, err := FindAMFTPSAppDetail(ymlConfig.File.CORE_NAME, ymlConfig)
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"data":  "CORE.amftpsSum, amfmsSum is not find",
-				"error": err,
-			})
-			return
-		}
+// BuildMetrics 사이트 설정의 Family 데이터로 RAN/Core 요약을 만든다.
+// 실패시 어떤 항목을 찾지 못했는지 메시지를 함께 반환한다.
+func BuildMetrics(ymlConfig cfg.Config) (*Metrics, string, error) {
+	// 요청 동안 같은 snapshot 의 파일만 읽도록 API_PATH 고정
+	snap, err := snapshot.Open(ymlConfig.File.API_Path)
+	if err != nil {
+		return nil, "API snapshot is not find", err
+	}
+	ymlConfig.File.API_Path = snap.Dir
 
-		data.Core.Application.Sum.UeconAmfCRatio = ueconAvgSum
-		data.Core.Application.Sum.UeidAmfCRatio = ueidAvgSum
-		data.Core.Application.Sum.AmftpsTotalMsg = amftpsSum
-		data.Core.Application.Sum.AmfmsCurCmConn = amfmsSum
+	// RAN 기준 location 추출
+	airLocations, err := FindCommonLocation("Air_MAC_Packet", ymlConfig)
+	if err != nil {
+		return nil, "Ran.location is not find", err
+	}
+	cellLocations, err := FindCommonLocation("Air_MAC_Packet_(PCell)", ymlConfig)
+	if err != nil {
+		return nil, "Ran.location is not find", err
+	}
 
-		c.JSON(http.StatusOK, data)
+	data := &Metrics{Period: snap.Period()}
+	appsInfo := []RanAppDetailInfo{}
+	phyInfo := []RanPhysicalDetailInfo{}
+	coreInfo := []CoreAppDetailInfo{}
 
+	// ran.application.detail의 json값 과 ran.application.sum 중 ueActiveDLAvg,ueActiveDLMax 값을 반환
+	totAvgSum, totMaxSum, totAirMacUL, totAirMacDL, err := FincRanAppDetail(airLocations, ymlConfig, appsInfo, data)
+	if err != nil {
+		return nil, "Ran.application is not find", err
+	}
+	data.Ran.Application.Sum.UeActiveDLAvg = totAvgSum
+	data.Ran.Application.Sum.UeActiveDLMax = totMaxSum
+	data.Ran.Application.Sum.AirMacULByte = totAirMacUL
+	data.Ran.Application.Sum.AirMacDLByte = totAirMacDL
+
+	// ran.physical.detail의 json값과 ran.physical.sum 의 AirMacULByte_PCELL, AirMacDLByte_PCELL, AirMacULByte_SCELL, AirMacDLByte_SCELL 값을 반환
+	totPcellUL, totPcellDL, totScellUL, totScellDL, err := FincRanPhysicalDetail(cellLocations, ymlConfig, phyInfo, data)
+	if err != nil {
+		return nil, "ran.physical is not find", err
+	}
+	data.Ran.Physical.Sum.AirMacULByte_PCELL = totPcellUL
+	data.Ran.Physical.Sum.AirMacDLByte_PCELL = totPcellDL
+	data.Ran.Physical.Sum.AirMacULByte_SCELL = totScellUL
+	data.Ran.Physical.Sum.AirMacDLByte_SCELL = totScellDL
+
+	// core.application.detail의 json값과 core.application.sum의 UeconAmfCRatio 값을 반환
+	ueconAvgSum, err := FindUECONAppDetail(ymlConfig.File.CORE_NAME, ymlConfig, coreInfo, data)
+	if err != nil {
+		return nil, "Core.UECON_AMF is not find", err
+	}
+	// core.application.sum의 ueidAvgSum 값을 반환
+	ueidAvgSum, err := FindUEIDAppDetail(ymlConfig.File.CORE_NAME, ymlConfig)
+	if err != nil {
+		return nil, "Core.UEID_AMF is not find", err
 	}
+	// core.application.sum의 amftpsSum,amfmsSum 값을 반환
+	amftpsSum, amfmsSum, err := FindAMFTPSAppDetail(ymlConfig.File.CORE_NAME, ymlConfig)
+	if err != nil {
+		return nil, "CORE.amftpsSum, amfmsSum is not find", err
+	}
+
+	data.Core.Application.Sum.UeconAmfCRatio = ueconAvgSum
+	data.Core.Application.Sum.UeidAmfCRatio = ueidAvgSum
+	data.Core.Application.Sum.AmftpsTotalMsg = amftpsSum
+	data.Core.Application.Sum.AmfmsCurCmConn = amfmsSum
+
+	return data, "", nil
 }
 
 func TrapService() gin.HandlerFunc {
@@ -11,9 +11,13 @@
  */
 package metricApi
 
+import "kt.com/p5g/cnf-exporter/samsung-cpc/pkg/snapshot"
+
 type Metrics struct {
 	Ran  RanStats  `json:"ran"`
 	Core CoreStats `json:"core"`
+	// 응답을 만든 API snapshot 의 수집 기간 (이전 구조의 API_PATH 면 생략)
+	Period *snapshot.Period `json:"period,omitempty"`
 }
 
 type RanStats struct {
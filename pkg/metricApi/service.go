@@ -12,19 +12,52 @@
 package metricApi
 
 import (
-	"fmt"
 	"github.com/pkg/errors"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
 	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/filter"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/snapshot"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/store"
 	"strconv"
 	"strings"
 )
 
+// 사이트별 수집 데이터 저장소 (STORE.ENABLE 시 API_PATH 파일 대신 사용)
+var stores = map[string]*store.Store{}
+
+// SetStore 사이트 저장소 등록 (단일 수집은 site "")
+func SetStore(site string, s *store.Store) {
+	stores[site] = s
+}
+
+// cnf_config.yml filters (수집시와 같은 행만 집계)
+var rowFilter *filter.Filter
+
+func SetFilter(f *filter.Filter) {
+	rowFilter = f
+}
+
+// LoadFamily Family 의 마지막 수집 데이터를 저장소 또는 API_PATH 의 CSV 에서 읽는다.
+// API_PATH 가 고정된 snapshot 폴더면 저장소 대신 snapshot 파일을 읽어, 응답의 period 와 같은 수집 결과만 사용한다.
+func LoadFamily(name string, ymlConfig cfg.Config) ([][]string, error) {
+	if tsdb := stores[ymlConfig.Site]; tsdb != nil && !snapshot.IsSnapshot(ymlConfig.File.API_Path) {
+		if data, err := tsdb.Latest(name); err == nil {
+			data, _ = rowFilter.Apply(name, data)
+			return data, nil
+		}
+	}
+	data, err := csv.LoadCsv(ymlConfig.File.API_Path + "/" + name + ".csv")
+	if err != nil {
+		return nil, err
+	}
+	data, _ = rowFilter.Apply(name, data)
+	return data, nil
+}
+
 // 로케이션 찾는 공통 함수
 func FindCommonLocation(value string, ymlConfig cfg.Config) ([]string, error) {
-	path := fmt.Sprintf(ymlConfig.File.API_Path + "/" + value + ".csv")
-	csvData, err := csv.LoadCsv(path)
+	csvData, err := LoadFamily(value, ymlConfig)
 	if err != nil {
 		logger.LogErr("FindCommonLocation Method error", err)
 		return nil, errors.Cause(err)
@@ -56,16 +89,14 @@ func FincRanAppDetail(locations []string, ymlConfig cfg.Config, detail []RanAppD
 		detailinfo := RanAppDetailInfo{}
 
 		// Downlink_Active_UE_Number 불러오기 위한 csvData
-		downActiveUePath := fmt.Sprintf(ymlConfig.File.API_Path + "/" + "Downlink_Active_UE_Number" + ".csv")
-		UeData, err := csv.LoadCsv(downActiveUePath)
+		UeData, err := LoadFamily("Downlink_Active_UE_Number", ymlConfig)
 		if err != nil {
 			logger.LogErr("Downlink_Active_UE_Number CSV File is not Open", err)
 			return 0, 0, 0, 0, errors.Cause(err)
 		}
 
 		// Air_MAC_Packet 불러오기 위한 csvData
-		airMacPath := fmt.Sprintf(ymlConfig.File.API_Path + "/" + "Air_MAC_Packet" + ".csv")
-		airMacData, err := csv.LoadCsv(airMacPath)
+		airMacData, err := LoadFamily("Air_MAC_Packet", ymlConfig)
 		if err != nil {
 			logger.LogErr("Air_MAC_Packet CSV File is not Open", err)
 			return 0, 0, 0, 0, errors.Cause(err)
@@ -166,16 +197,14 @@ func FincRanPhysicalDetail(locations []string, ymlConfig cfg.Config, detail []Ra
 		detailinfo := RanPhysicalDetailInfo{}
 
 		// Downlink_Active_UE_Number 불러오기 위한 csvData
-		pCellPath := fmt.Sprintf(ymlConfig.File.API_Path + "/" + "Air_MAC_Packet_(PCell)" + ".csv")
-		pCellData, err := csv.LoadCsv(pCellPath)
+		pCellData, err := LoadFamily("Air_MAC_Packet_(PCell)", ymlConfig)
 		if err != nil {
 			logger.LogErr("Air_MAC_Packet_(PCell) CSV File is not Open", err)
 			return 0, 0, 0, 0, errors.Cause(err)
 		}
 
 		// Air_MAC_Packet 불러오기 위한 csvData
-		sCellPath := fmt.Sprintf(ymlConfig.File.API_Path + "/" + "Air_MAC_Packet_(SCell)" + ".csv")
-		sCellData, err := csv.LoadCsv(sCellPath)
+		sCellData, err := LoadFamily("Air_MAC_Packet_(SCell)", ymlConfig)
 		if err != nil {
 			logger.LogErr("Air_MAC_Packet_(SCell) CSV File is not Open", err)
 			return 0, 0, 0, 0, errors.Cause(err)
@@ -267,22 +296,13 @@ func FindUECONAppDetail(coreNames []string, ymlConfig cfg.Config, detail []CoreA
 
 		switch name {
 		case "UECON_AMF":
-			path := fmt.Sprintf(ymlConfig.File.API_Path + "/" + name + ".csv")
-			data, err := csv.LoadCsv(path)
+			data, err := LoadFamily(name, ymlConfig)
 			if err != nil {
 				logger.LogErr(name+" CSV File is not Open", err)
 				return 0, errors.Cause(err)
 			}
-			var count int64
-			var sum float64
-			//var cRatio int64
-
 			if len(data) != 3 {
 				for i := 3; i < len(data); i++ {
-					count++
-					dataStr, _ := strconv.ParseFloat(data[i][18], 10)
-					sum += dataStr
-
 					if strings.Contains(data[0][0], "UECON_AMF") {
 						attempt, _ := strconv.Atoi(data[i][7])
 						success, _ := strconv.Atoi(data[i][8])
@@ -300,7 +320,7 @@ func FindUECONAppDetail(coreNames []string, ymlConfig cfg.Config, detail []CoreA
 						detail = append(detail, detailinfo)
 					}
 				}
-				ueconCratioAvg = sum / float64(count)
+				ueconCratioAvg = Aggregate(data, 18, "avg").Value
 			} else {
 				detailinfo = CoreAppDetailInfo{
 					InitTime: "",
@@ -328,23 +348,13 @@ func FindUEIDAppDetail(coreNames []string, ymlConfig cfg.Config) (float64, error
 	for _, name := range coreNames {
 		switch name {
 		case "UEID_AMF":
-			path := fmt.Sprintf(ymlConfig.File.API_Path + "/" + name + ".csv")
-			data, err := csv.LoadCsv(path)
+			data, err := LoadFamily(name, ymlConfig)
 			if err != nil {
 				logger.LogErr(name+" CSV File is not Open", err)
 				return 0, errors.Cause(err)
 			}
-			var count int64
-			var sum float64
-			//var cRatio int64
-
 			if len(data) != 3 {
-				for i := 3; i < len(data); i++ {
-					count++
-					dataStr, _ := strconv.ParseFloat(data[i][18], 10)
-					sum += dataStr
-				}
-				ueidCratioAvg = sum / float64(count)
+				ueidCratioAvg = Aggregate(data, 18, "avg").Value
 			} else {
 				return 0, nil
 			}
@@ -360,22 +370,16 @@ func FindAMFTPSAppDetail(coreNames []string, ymlConfig cfg.Config) (int, int, er
 	var amfmsCurReg int
 
 	for _, name := range coreNames {
-		path := fmt.Sprintf(ymlConfig.File.API_Path + "/" + name + ".csv")
-		data, err := csv.LoadCsv(path)
+		data, err := LoadFamily(name, ymlConfig)
 		if err != nil {
 			logger.LogErr(name+" CSV File is not Open", err)
 			return 0, 0, errors.Cause(err)
 		}
 
-		var sum int64
-
 		if len(data) == 3 {
 			return 0, 0, nil
 		} else {
-			for i := 3; i < len(data); i++ {
-				data, _ := strconv.ParseInt(data[i][9], 10, 64)
-				sum += data
-			}
+			sum := Aggregate(data, 9, "sum").Value
 			switch name {
 			case "AMFMS":
 				amftpsTotMsg = int(sum)
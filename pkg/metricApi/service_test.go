@@ -0,0 +1,99 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package metricApi
+
+import (
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/snapshot"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/store"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func familyCSV(period, value string) [][]string {
+	return [][]string{
+		{"Family name : UECON_AMF"},
+		{"Period : 15min"},
+		{"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "Attempt"},
+		{"ne101", "1", "NE-101", period, "+09:00", "900", "AMF_01", value},
+	}
+}
+
+func writeCSV(t *testing.T, path string, data [][]string) {
+	t.Helper()
+	var lines []string
+	for _, row := range data {
+		lines = append(lines, strings.Join(row, ","))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// snapshot 이 게시된 뒤에는 저장소에 더 최근 배치가 있어도 고정된 snapshot 의 데이터를 읽는다.
+func TestLoadFamilyPinnedSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "csv")
+	api := filepath.Join(dir, "api")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	published := familyCSV("2023-11-08 13:15:00", "1200")
+	writeCSV(t, filepath.Join(src, "UECON_AMF.csv"), published)
+	if _, err := snapshot.Publish(api, src, []string{"UECON_AMF"}, snapshot.Period{Start: "2023-11-08 13:15:00", End: "2023-11-08 13:32:00"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 다음 주기의 store append 는 끝났지만 아직 게시 전
+	tsdb, err := store.Open(filepath.Join(dir, "store"), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := familyCSV("2023-11-08 13:30:00", "1500")
+	if err := tsdb.Append("UECON_AMF", latest, time.Date(2023, 11, 8, 13, 46, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	SetStore("", tsdb)
+	t.Cleanup(func() { delete(stores, "") })
+
+	snap, err := snapshot.Open(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := cfg.Config{}
+	config.File.API_Path = snap.Dir
+	data, err := LoadFamily("UECON_AMF", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data[3], published[3]) || snap.Period().Start != "2023-11-08 13:15:00" {
+		t.Errorf("pinned row = %v, period = %+v, want %v", data[3], snap.Period(), published[3])
+	}
+
+	// snapshot 게시 전(이전 구조)에는 저장소의 마지막 배치
+	legacy, err := snapshot.Open(filepath.Join(dir, "legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.File.API_Path = legacy.Dir
+	data, err = LoadFamily("UECON_AMF", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data[3], latest[3]) || legacy.Period() != nil {
+		t.Errorf("legacy row = %v, period = %+v, want %v", data[3], legacy.Period(), latest[3])
+	}
+}
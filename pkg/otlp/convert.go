@@ -0,0 +1,165 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package otlp
+
+import (
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"math"
+	"time"
+)
+
+// 누적(counter) 데이터의 시작 시간은 exporter 시작 시간으로 둔다.
+var startTime = time.Now()
+
+// ToResourceMetrics prometheus Gather 결과를 OTLP ResourceMetrics 로 변환
+// counter -> monotonic cumulative Sum, gauge/untyped -> Gauge, histogram/summary 는 같은 타입으로 매핑하고
+// 라벨은 attribute, 메트릭 timestamp(OSS period)는 data point 시간으로 사용한다. timestamp 가 없으면 now.
+func ToResourceMetrics(path string, families []*dto.MetricFamily, now time.Time) *metricspb.ResourceMetrics {
+	scope := &metricspb.ScopeMetrics{
+		Scope: &commonpb.InstrumentationScope{Name: "cnf-exporter"},
+	}
+
+	for _, family := range families {
+		if metric := convertFamily(family, now); metric != nil {
+			scope.Metrics = append(scope.Metrics, metric)
+		}
+	}
+
+	return &metricspb.ResourceMetrics{
+		Resource: &resourcepb.Resource{
+			Attributes: []*commonpb.KeyValue{
+				stringAttr("service.name", "cnf-exporter"),
+				stringAttr("exporter.path", path),
+			},
+		},
+		ScopeMetrics: []*metricspb.ScopeMetrics{scope},
+	}
+}
+
+func convertFamily(family *dto.MetricFamily, now time.Time) *metricspb.Metric {
+	metric := &metricspb.Metric{
+		Name:        family.GetName(),
+		Description: family.GetHelp(),
+	}
+	start := uint64(startTime.UnixNano())
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}
+		for _, m := range family.GetMetric() {
+			sum.DataPoints = append(sum.DataPoints, &metricspb.NumberDataPoint{
+				Attributes:        attributes(m.GetLabel()),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      timestamp(m, now),
+				Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: m.GetCounter().GetValue()},
+			})
+		}
+		metric.Data = &metricspb.Metric_Sum{Sum: sum}
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		gauge := &metricspb.Gauge{}
+		for _, m := range family.GetMetric() {
+			value := m.GetGauge().GetValue()
+			if family.GetType() == dto.MetricType_UNTYPED {
+				value = m.GetUntyped().GetValue()
+			}
+			gauge.DataPoints = append(gauge.DataPoints, &metricspb.NumberDataPoint{
+				Attributes:   attributes(m.GetLabel()),
+				TimeUnixNano: timestamp(m, now),
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+			})
+		}
+		metric.Data = &metricspb.Metric_Gauge{Gauge: gauge}
+	case dto.MetricType_HISTOGRAM:
+		histogram := &metricspb.Histogram{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}
+		for _, m := range family.GetMetric() {
+			h := m.GetHistogram()
+			point := &metricspb.HistogramDataPoint{
+				Attributes:        attributes(m.GetLabel()),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      timestamp(m, now),
+				Count:             h.GetSampleCount(),
+				Sum:               ptr(h.GetSampleSum()),
+			}
+			// prometheus bucket 은 누적값, OTLP 는 구간별 값
+			// OTLP 의 마지막 bucket 이 +Inf 구간이므로 +Inf 경계는 bounds 에 넣지 않는다.
+			var prev uint64
+			for _, bucket := range h.GetBucket() {
+				if math.IsInf(bucket.GetUpperBound(), +1) {
+					continue
+				}
+				point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+				point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-prev)
+				prev = bucket.GetCumulativeCount()
+			}
+			point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-prev)
+			histogram.DataPoints = append(histogram.DataPoints, point)
+		}
+		metric.Data = &metricspb.Metric_Histogram{Histogram: histogram}
+	case dto.MetricType_SUMMARY:
+		summary := &metricspb.Summary{}
+		for _, m := range family.GetMetric() {
+			s := m.GetSummary()
+			point := &metricspb.SummaryDataPoint{
+				Attributes:        attributes(m.GetLabel()),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      timestamp(m, now),
+				Count:             s.GetSampleCount(),
+				Sum:               s.GetSampleSum(),
+			}
+			for _, q := range s.GetQuantile() {
+				point.QuantileValues = append(point.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+					Quantile: q.GetQuantile(),
+					Value:    q.GetValue(),
+				})
+			}
+			summary.DataPoints = append(summary.DataPoints, point)
+		}
+		metric.Data = &metricspb.Metric_Summary{Summary: summary}
+	default:
+		return nil
+	}
+	return metric
+}
+
+func attributes(labels []*dto.LabelPair) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, label := range labels {
+		attrs = append(attrs, stringAttr(label.GetName(), label.GetValue()))
+	}
+	return attrs
+}
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func timestamp(m *dto.Metric, now time.Time) uint64 {
+	if m.TimestampMs != nil {
+		return uint64(m.GetTimestampMs()) * uint64(time.Millisecond)
+	}
+	return uint64(now.UnixNano())
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
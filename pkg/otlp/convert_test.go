@@ -0,0 +1,190 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package otlp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+	"math"
+	"testing"
+	"time"
+)
+
+// 수집 시간과 OSS period
+var (
+	now    = time.Date(2023, 11, 8, 13, 31, 0, 0, time.UTC)
+	period = time.Date(2023, 11, 8, 13, 15, 0, 0, time.UTC)
+)
+
+func desc(name string) *prometheus.Desc {
+	return prometheus.NewDesc(name, name+" help", []string{"ne_id"}, nil)
+}
+
+func ne(id string) []*commonpb.KeyValue {
+	return []*commonpb.KeyValue{stringAttr("ne_id", id)}
+}
+
+func TestToResourceMetrics(t *testing.T) {
+	start := uint64(startTime.UnixNano())
+	tests := []struct {
+		name   string
+		metric prometheus.Metric
+		want   *metricspb.Metric
+	}{
+		{
+			name:   "counter",
+			metric: prometheus.NewMetricWithTimestamp(period, prometheus.MustNewConstMetric(desc("amf_reg_total"), prometheus.CounterValue, 12, "amf-1")),
+			want: &metricspb.Metric{
+				Name:        "amf_reg_total",
+				Description: "amf_reg_total help",
+				Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					IsMonotonic:            true,
+					DataPoints: []*metricspb.NumberDataPoint{{
+						Attributes:        ne("amf-1"),
+						StartTimeUnixNano: start,
+						TimeUnixNano:      uint64(period.UnixNano()),
+						Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: 12},
+					}},
+				}},
+			},
+		},
+		{
+			name:   "gauge",
+			metric: prometheus.MustNewConstMetric(desc("amf_reg_ue"), prometheus.GaugeValue, 3.5, "amf-1"),
+			want: &metricspb.Metric{
+				Name:        "amf_reg_ue",
+				Description: "amf_reg_ue help",
+				Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+					DataPoints: []*metricspb.NumberDataPoint{{
+						Attributes:   ne("amf-1"),
+						TimeUnixNano: uint64(now.UnixNano()),
+						Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: 3.5},
+					}},
+				}},
+			},
+		},
+		{
+			name:   "untyped",
+			metric: prometheus.MustNewConstMetric(desc("amf_ms"), prometheus.UntypedValue, 7, "amf-2"),
+			want: &metricspb.Metric{
+				Name:        "amf_ms",
+				Description: "amf_ms help",
+				Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+					DataPoints: []*metricspb.NumberDataPoint{{
+						Attributes:   ne("amf-2"),
+						TimeUnixNano: uint64(now.UnixNano()),
+						Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: 7},
+					}},
+				}},
+			},
+		},
+		{
+			// 누적 bucket 2, 5 -> 구간별 2, 3 과 +Inf 구간 8-5
+			name:   "histogram",
+			metric: prometheus.MustNewConstHistogram(desc("amf_delay"), 8, 20, map[float64]uint64{1: 2, 5: 5}, "amf-1"),
+			want: &metricspb.Metric{
+				Name:        "amf_delay",
+				Description: "amf_delay help",
+				Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					DataPoints: []*metricspb.HistogramDataPoint{{
+						Attributes:        ne("amf-1"),
+						StartTimeUnixNano: start,
+						TimeUnixNano:      uint64(now.UnixNano()),
+						Count:             8,
+						Sum:               ptr(20),
+						ExplicitBounds:    []float64{1, 5},
+						BucketCounts:      []uint64{2, 3, 3},
+					}},
+				}},
+			},
+		},
+		{
+			// +Inf bucket 이 있어도 경계에는 넣지 않고 마지막 구간으로 센다.
+			name:   "histogram with +Inf bucket",
+			metric: prometheus.MustNewConstHistogram(desc("amf_delay"), 8, 20, map[float64]uint64{1: 2, math.Inf(+1): 8}, "amf-1"),
+			want: &metricspb.Metric{
+				Name:        "amf_delay",
+				Description: "amf_delay help",
+				Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					DataPoints: []*metricspb.HistogramDataPoint{{
+						Attributes:        ne("amf-1"),
+						StartTimeUnixNano: start,
+						TimeUnixNano:      uint64(now.UnixNano()),
+						Count:             8,
+						Sum:               ptr(20),
+						ExplicitBounds:    []float64{1},
+						BucketCounts:      []uint64{2, 6},
+					}},
+				}},
+			},
+		},
+		{
+			name:   "summary",
+			metric: prometheus.MustNewConstSummary(desc("amf_latency"), 4, 10, map[float64]float64{0.5: 2, 0.99: 4}, "amf-1"),
+			want: &metricspb.Metric{
+				Name:        "amf_latency",
+				Description: "amf_latency help",
+				Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+					DataPoints: []*metricspb.SummaryDataPoint{{
+						Attributes:        ne("amf-1"),
+						StartTimeUnixNano: start,
+						TimeUnixNano:      uint64(now.UnixNano()),
+						Count:             4,
+						Sum:               10,
+						QuantileValues: []*metricspb.SummaryDataPoint_ValueAtQuantile{
+							{Quantile: 0.5, Value: 2},
+							{Quantile: 0.99, Value: 4},
+						},
+					}},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(constCollector{tt.metric})
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rm := ToResourceMetrics("metrics", families, now)
+			if got := rm.GetResource().GetAttributes(); !proto.Equal(got[1], stringAttr("exporter.path", "metrics")) {
+				t.Errorf("resource attributes = %v", got)
+			}
+			metrics := rm.GetScopeMetrics()[0].GetMetrics()
+			if len(metrics) != 1 || !proto.Equal(metrics[0], tt.want) {
+				t.Errorf("metrics = %v\nwant %v", metrics, tt.want)
+			}
+		})
+	}
+}
+
+// constCollector 고정 메트릭 하나를 내보내는 collector
+type constCollector struct {
+	metric prometheus.Metric
+}
+
+func (c constCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.metric.Desc()
+}
+
+func (c constCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- c.metric
+}
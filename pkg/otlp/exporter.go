@@ -0,0 +1,171 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/health"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Exporter prometheus registry 의 메트릭을 OTLP/gRPC 또는 OTLP/HTTP 로 push
+type Exporter struct {
+	config  cfg.Otlp
+	timeout time.Duration
+	grpc    collectorpb.MetricsServiceClient
+	http    *http.Client
+}
+
+func NewExporter(config cfg.Otlp) (*Exporter, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("otlp endpoint is empty")
+	}
+	e := &Exporter{
+		config:  config,
+		timeout: time.Duration(config.Timeout) * time.Second,
+	}
+	if e.timeout <= 0 {
+		e.timeout = 10 * time.Second
+	}
+
+	switch strings.ToLower(config.Protocol) {
+	case "grpc", "":
+		creds := credentials.NewTLS(&tls.Config{InsecureSkipVerify: config.InsecureSkipVerify})
+		if config.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.Dial(config.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, errors.Cause(err)
+		}
+		e.grpc = collectorpb.NewMetricsServiceClient(conn)
+	case "http":
+		e.http = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+			},
+			Timeout: e.timeout,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol %q (grpc|http)", config.Protocol)
+	}
+	return e, nil
+}
+
+// Enabled PATHS 에 설정된 경로인지 확인 ("/metrics", "metrics" 모두 허용)
+func (e *Exporter) Enabled(path string) bool {
+	path = strings.TrimPrefix(path, "/")
+	for _, p := range e.config.Paths {
+		if strings.TrimPrefix(p, "/") == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Start INTERVAL(초) 마다 gatherer 를 수집하여 전송, ctx 가 취소되면 중지
+func (e *Exporter) Start(ctx context.Context, path string, gatherer prometheus.Gatherer) {
+	interval := time.Duration(e.config.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		defer health.Stop("otlp:" + path)
+		for {
+			health.Beat("otlp:"+path, interval)
+			if err := e.Push(path, gatherer); err != nil {
+				logger.LogErr("otlp push failed : "+path, err)
+			}
+			if !lifecycle.Sleep(ctx, interval) {
+				return
+			}
+		}
+	}()
+}
+
+// Push gatherer 를 한번 수집하여 전송
+func (e *Exporter) Push(path string, gatherer prometheus.Gatherer) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		// 일부 메트릭 오류는 기록하고 나머지는 전송
+		logger.LogErr("otlp gather error : "+path, err)
+	}
+	if len(families) == 0 {
+		return nil
+	}
+
+	req := &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{ToResourceMetrics(path, families, time.Now())},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	if e.grpc != nil {
+		if len(e.config.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.config.Headers))
+		}
+		_, err = e.grpc.Export(ctx, req)
+	} else {
+		err = e.postHTTP(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+	logger.LogInfo("otlp push", zap.String("path", path), zap.Int("families", len(families)))
+	return nil
+}
+
+func (e *Exporter) postHTTP(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Cause(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range e.config.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := e.http.Do(httpReq)
+	if err != nil {
+		return errors.Cause(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp http %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
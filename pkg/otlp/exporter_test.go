@@ -0,0 +1,132 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package otlp
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// grpcReceiver OTLP/gRPC metrics 수신 서버
+type grpcReceiver struct {
+	collectorpb.UnimplementedMetricsServiceServer
+	requests chan *collectorpb.ExportMetricsServiceRequest
+	headers  chan metadata.MD
+}
+
+func (r *grpcReceiver) Export(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.headers <- md
+	r.requests <- req
+	return &collectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+func testRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(constCollector{prometheus.NewMetricWithTimestamp(period, prometheus.MustNewConstMetric(desc("amf_reg_ue"), prometheus.GaugeValue, 3, "amf-1"))})
+	return registry
+}
+
+// 받은 요청이 registry 한번 수집한 결과와 같은지 확인
+func checkRequest(t *testing.T, req *collectorpb.ExportMetricsServiceRequest) {
+	t.Helper()
+	if len(req.GetResourceMetrics()) != 1 {
+		t.Fatalf("resource metrics = %d, want 1", len(req.GetResourceMetrics()))
+	}
+	metrics := req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if len(metrics) != 1 || metrics[0].GetName() != "amf_reg_ue" {
+		t.Fatalf("metrics = %v", metrics)
+	}
+	point := metrics[0].GetGauge().GetDataPoints()[0]
+	if point.GetAsDouble() != 3 || point.GetTimeUnixNano() != uint64(period.UnixNano()) || !proto.Equal(point.GetAttributes()[0], ne("amf-1")[0]) {
+		t.Errorf("data point = %v", point)
+	}
+}
+
+func TestPushHTTP(t *testing.T) {
+	requests := make(chan *collectorpb.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("X-Tenant") != "p5g" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := &collectorpb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("unmarshal: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	e, err := NewExporter(cfg.Otlp{Protocol: "http", Endpoint: server.URL + "/v1/metrics", Headers: map[string]string{"X-Tenant": "p5g"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Push("metrics", testRegistry()); err != nil {
+		t.Fatal(err)
+	}
+	checkRequest(t, <-requests)
+}
+
+func TestPushHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	e, err := NewExporter(cfg.Otlp{Protocol: "http", Endpoint: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Push("metrics", testRegistry()); err == nil {
+		t.Fatal("expected error for 429")
+	}
+}
+
+func TestPushGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := &grpcReceiver{
+		requests: make(chan *collectorpb.ExportMetricsServiceRequest, 1),
+		headers:  make(chan metadata.MD, 1),
+	}
+	server := grpc.NewServer()
+	collectorpb.RegisterMetricsServiceServer(server, receiver)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	e, err := NewExporter(cfg.Otlp{Protocol: "grpc", Endpoint: listener.Addr().String(), Insecure: true, Headers: map[string]string{"x-tenant": "p5g"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Push("metrics", testRegistry()); err != nil {
+		t.Fatal(err)
+	}
+	if md := <-receiver.headers; len(md.Get("x-tenant")) != 1 || md.Get("x-tenant")[0] != "p5g" {
+		t.Errorf("metadata = %v", md)
+	}
+	checkRequest(t, <-receiver.requests)
+}
@@ -0,0 +1,188 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package publish
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"strings"
+	"time"
+)
+
+// 메시지 헤더에 넣는 중복 제거 키
+const idempotencyHeader = "Idempotency-Key"
+
+// Message 전송 단위 (Key 는 family+ne_id+period 중복 제거 키)
+type Message struct {
+	Topic string `json:"topic"`
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Bus 메시지 버스 (broker 가 받았을때만 nil 반환)
+// 재전송해도 성공할 수 없는 오류는 permanentError 로 감싸서 반환한다.
+type Bus interface {
+	Publish(ctx context.Context, messages []Message) error
+	Close() error
+}
+
+// 재전송 하지 않는 오류 (메시지 크기 초과, 권한 없음, 잘못된 topic 등)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// NewBus BUS 설정에 맞는 메시지 버스 생성 (kafka or nats)
+// 연결은 전송 시점에 맺으므로 broker 가 내려가 있어도 생성은 성공한다.
+func NewBus(config cfg.Publish) (Bus, error) {
+	var tlsConfig *tls.Config
+	if config.Tls {
+		tlsConfig = &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	}
+
+	switch strings.ToLower(config.Bus) {
+	case "kafka":
+		if len(config.Brokers) == 0 {
+			return nil, errors.New("publish brokers is empty")
+		}
+		transport := &kafka.Transport{TLS: tlsConfig}
+		if config.Username != "" {
+			transport.SASL = plain.Mechanism{Username: config.Username, Password: config.Password}
+		}
+		return &kafkaBus{writer: &kafka.Writer{
+			Addr:         kafka.TCP(config.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 100 * time.Millisecond,
+			Transport:    transport,
+		}}, nil
+	case "nats":
+		if len(config.Brokers) == 0 {
+			return nil, errors.New("publish brokers is empty")
+		}
+		options := []nats.Option{
+			nats.Name("cnf-exporter"),
+			nats.RetryOnFailedConnect(true),
+			nats.MaxReconnects(-1),
+		}
+		if tlsConfig != nil {
+			options = append(options, nats.Secure(tlsConfig))
+		}
+		if config.Username != "" {
+			options = append(options, nats.UserInfo(config.Username, config.Password))
+		}
+		conn, err := nats.Connect(strings.Join(config.Brokers, ","), options...)
+		if err != nil {
+			return nil, errors.Cause(err)
+		}
+		js, err := conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return nil, errors.Cause(err)
+		}
+		return &natsBus{conn: conn, js: js}, nil
+	default:
+		return nil, fmt.Errorf("unsupported publish bus %q (kafka|nats)", config.Bus)
+	}
+}
+
+// kafka: 메시지 key 로 partition 을 정하고 모든 replica ack 를 기다린다.
+type kafkaBus struct {
+	writer *kafka.Writer
+}
+
+func (b *kafkaBus) Publish(ctx context.Context, messages []Message) error {
+	list := make([]kafka.Message, 0, len(messages))
+	for _, m := range messages {
+		list = append(list, kafka.Message{
+			Topic:   m.Topic,
+			Key:     []byte(m.Key),
+			Value:   m.Value,
+			Headers: []kafka.Header{{Key: idempotencyHeader, Value: []byte(m.Key)}},
+		})
+	}
+	return kafkaError(b.writer.WriteMessages(ctx, list...))
+}
+
+// broker 가 재시도 불가로 응답한 오류만 permanentError 로 바꾼다.
+// 메시지별 오류(WriteErrors)는 모두 재시도 불가일 때만 permanentError 로 본다.
+func kafkaError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var writeErrors kafka.WriteErrors
+	if errors.As(err, &writeErrors) {
+		for _, e := range writeErrors {
+			if e != nil && kafkaTemporary(e) {
+				return err
+			}
+		}
+		return permanentError{err}
+	}
+	if !kafkaTemporary(err) {
+		return permanentError{err}
+	}
+	return err
+}
+
+// 연결 오류, timeout 등 kafka 프로토콜 오류가 아니면 재시도
+func kafkaTemporary(err error) bool {
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		return kafkaErr.Temporary()
+	}
+	return true
+}
+
+func (b *kafkaBus) Close() error {
+	return b.writer.Close()
+}
+
+// nats: JetStream publish ack 를 기다리고, Nats-Msg-Id 로 stream 중복 제거
+type natsBus struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+func (b *natsBus) Publish(ctx context.Context, messages []Message) error {
+	for _, m := range messages {
+		msg := nats.NewMsg(m.Topic)
+		msg.Data = m.Value
+		msg.Header.Set(nats.MsgIdHdr, m.Key)
+		msg.Header.Set(idempotencyHeader, m.Key)
+		if _, err := b.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+			if errors.Is(err, nats.ErrMaxPayload) || errors.Is(err, nats.ErrBadSubject) || errors.Is(err, nats.ErrAuthorization) {
+				return permanentError{err}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *natsBus) Close() error {
+	b.conn.Close()
+	return nil
+}
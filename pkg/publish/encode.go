@@ -0,0 +1,122 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package publish
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AvroSchema Record 의 Avro 스키마 (schema registry 등록용)
+const AvroSchema = `{"type":"record","name":"OssRecord","namespace":"kt.p5g.cnf","fields":[` +
+	`{"name":"family","type":"string"},` +
+	`{"name":"ne_id","type":"string"},` +
+	`{"name":"system_id","type":"string"},` +
+	`{"name":"ne_name","type":"string"},` +
+	`{"name":"period","type":"string"},` +
+	`{"name":"time_offset","type":"string"},` +
+	`{"name":"gran_period","type":"string"},` +
+	`{"name":"location","type":"string"},` +
+	`{"name":"timestamp","type":"long"},` +
+	`{"name":"values","type":{"type":"map","values":"double"}},` +
+	`{"name":"site","type":"string","default":""}]}`
+
+// Encoder Record 를 메시지 본문으로 변환
+type Encoder func(Record) ([]byte, error)
+
+// EncodeJSON JSON 본문
+func EncodeJSON(r Record) ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// AvroEncoder Confluent wire format (0x00 + 4byte schema id + Avro binary)
+func AvroEncoder(schemaID int) Encoder {
+	return func(r Record) ([]byte, error) {
+		buf := &bytes.Buffer{}
+		buf.WriteByte(0)
+		_ = binary.Write(buf, binary.BigEndian, int32(schemaID))
+
+		for _, s := range []string{r.Family, r.NeID, r.SystemID, r.NeName, r.Period, r.TimeOffset, r.GranPeriod, r.Location} {
+			avroString(buf, s)
+		}
+		avroLong(buf, r.Timestamp)
+
+		// map: block(개수) + entry... + 0, 키 순서를 고정해 같은 행은 같은 본문이 되도록 정렬
+		keys := make([]string, 0, len(r.Values))
+		for key := range r.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if len(keys) > 0 {
+			avroLong(buf, int64(len(keys)))
+			for _, key := range keys {
+				avroString(buf, key)
+				_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(r.Values[key]))
+			}
+		}
+		avroLong(buf, 0)
+		avroString(buf, r.Site)
+		return buf.Bytes(), nil
+	}
+}
+
+// zigzag varint
+func avroLong(buf *bytes.Buffer, v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	buf.Write(tmp[:n])
+}
+
+func avroString(buf *bytes.Buffer, s string) {
+	avroLong(buf, int64(len(s)))
+	buf.WriteString(s)
+}
+
+// RegisterSchema schema registry 에 subject 로 스키마 등록 후 id 반환 (이미 있으면 기존 id)
+func RegisterSchema(registryURL, subject string, insecureSkipVerify bool) (int, error) {
+	body, _ := json.Marshal(map[string]string{"schema": AvroSchema})
+	url := strings.TrimSuffix(registryURL, "/") + "/subjects/" + subject + "/versions"
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
+		},
+		Timeout: 10 * time.Second,
+	}
+	resp, err := client.Post(url, "application/vnd.schemaregistry.v1+json", bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Cause(err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("schema registry %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(msg, &result); err != nil {
+		return 0, errors.Cause(err)
+	}
+	return result.ID, nil
+}
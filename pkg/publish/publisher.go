@@ -0,0 +1,354 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const spoolExt = ".json"
+
+// Publisher OSS 행 데이터를 Family 별 topic 으로 전송
+// 배치는 SPOOL_PATH 에 먼저 저장하고 broker 가 받은 뒤 삭제하므로 (at-least-once)
+// broker 장애나 재시작 중에도 유실되지 않고, 재전송 중복은 메시지 key 로 제거한다.
+// broker 가 재시도 불가로 거절한 배치는 DEAD_LETTER_PATH 로 옮긴다.
+type Publisher struct {
+	config cfg.Publish
+	bus    Bus
+	encode Encoder
+	notify chan struct{}
+	seq    uint64
+
+	mu         sync.Mutex
+	published  float64
+	dropped    float64
+	retries    float64
+	deadLetter float64
+
+	publishedDesc  *prometheus.Desc
+	droppedDesc    *prometheus.Desc
+	retriesDesc    *prometheus.Desc
+	deadLetterDesc *prometheus.Desc
+	pendingDesc    *prometheus.Desc
+}
+
+func NewPublisher(config cfg.Publish, bus Bus) (*Publisher, error) {
+	if config.Topic == "" {
+		config.Topic = "oss.{family}"
+	}
+	if config.DeadLetterPath == "" {
+		config.DeadLetterPath = filepath.Join(config.SpoolPath, "dead-letter")
+	}
+
+	p := &Publisher{
+		config: config,
+		bus:    bus,
+		notify: make(chan struct{}, 1),
+		publishedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "publish", "records_total"),
+			"OSS records published to the message bus",
+			nil, nil,
+		),
+		droppedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "publish", "dropped_records_total"),
+			"OSS records dropped because the spool was full",
+			nil, nil,
+		),
+		retriesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "publish", "retries_total"),
+			"message bus publish retries",
+			nil, nil,
+		),
+		deadLetterDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "publish", "dead_letter_records_total"),
+			"OSS records moved to the dead-letter path because the broker rejected them",
+			nil, nil,
+		),
+		pendingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "publish", "pending_batches"),
+			"batches waiting in the publish spool",
+			nil, nil,
+		),
+	}
+
+	switch strings.ToLower(config.Format) {
+	case "json", "":
+		p.encode = EncodeJSON
+	case "avro":
+		schemaID := config.SchemaID
+		if schemaID <= 0 {
+			if config.SchemaRegistry == "" {
+				return nil, errors.New("avro format needs SCHEMA_ID or SCHEMA_REGISTRY")
+			}
+			id, err := RegisterSchema(config.SchemaRegistry, config.SchemaSubject, config.InsecureSkipVerify)
+			if err != nil {
+				return nil, err
+			}
+			schemaID = id
+		}
+		logger.LogInfo("publish avro schema", zap.Int("id", schemaID))
+		p.encode = AvroEncoder(schemaID)
+	default:
+		return nil, fmt.Errorf("unsupported publish format %q (json|avro)", config.Format)
+	}
+
+	if err := os.MkdirAll(config.SpoolPath, 0755); err != nil {
+		return nil, errors.Cause(err)
+	}
+	// 비정상 종료로 남은 임시파일 정리
+	if tmps, err := filepath.Glob(filepath.Join(config.SpoolPath, ".*"+spoolExt)); err == nil {
+		for _, tmp := range tmps {
+			_ = os.Remove(tmp)
+		}
+	}
+	return p, nil
+}
+
+// Start 전송 worker 실행 (spool 에 남아있는 배치부터 전송)
+// ctx 가 끝나면 worker 는 재전송 대기를 멈추고 종료하며, 남은 배치는 spool 에 그대로 둔다.
+func (p *Publisher) Start(ctx context.Context) {
+	go p.run(ctx)
+	p.wake()
+}
+
+func (p *Publisher) wake() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Topic 사이트/Family 의 topic ("{site}", "{family}" 치환)
+func (p *Publisher) Topic(site, family string) string {
+	return strings.NewReplacer("{site}", site, "{family}", family).Replace(p.config.Topic)
+}
+
+// Publish Family CSV 데이터를 행 단위 메시지로 만들어 spool 에 저장
+func (p *Publisher) Publish(site, family string, csvData [][]string, now time.Time) error {
+	records := Records(site, family, csvData, now)
+	if len(records) == 0 {
+		return nil
+	}
+
+	topic := p.Topic(site, family)
+	messages := make([]Message, 0, len(records))
+	for _, record := range records {
+		value, err := p.encode(record)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, Message{Topic: topic, Key: record.Key(), Value: value})
+	}
+
+	if err := p.writeSpool(messages); err != nil {
+		return err
+	}
+	p.wake()
+	return nil
+}
+
+// spool 파일명: <순번>-<레코드수>.json, 임시파일 작성 후 rename
+func (p *Publisher) writeSpool(messages []Message) error {
+	body, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.seq++
+	name := fmt.Sprintf("%019d%06d-%d%s", time.Now().UnixNano(), p.seq%1000000, len(messages), spoolExt)
+	p.mu.Unlock()
+
+	tmp := filepath.Join(p.config.SpoolPath, "."+name)
+	if err := os.WriteFile(tmp, body, 0644); err != nil {
+		return errors.Cause(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(p.config.SpoolPath, name)); err != nil {
+		return errors.Cause(err)
+	}
+
+	p.trimSpool()
+	return nil
+}
+
+// MAX_SPOOL_FILES 초과시 오래된 배치부터 삭제
+func (p *Publisher) trimSpool() {
+	if p.config.MaxSpoolFiles <= 0 {
+		return
+	}
+	files := spoolFiles(p.config.SpoolPath)
+	for len(files) > p.config.MaxSpoolFiles {
+		if err := os.Remove(files[0]); err == nil {
+			logger.LogWarn("publish spool is full, drop oldest batch", zap.String("file", files[0]))
+			p.count(&p.dropped, spoolRecords(files[0]))
+		}
+		files = files[1:]
+	}
+}
+
+func (p *Publisher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.notify:
+		}
+		for {
+			files := spoolFiles(p.config.SpoolPath)
+			if len(files) == 0 {
+				break
+			}
+			if !p.drain(ctx, files[0]) {
+				return
+			}
+		}
+	}
+}
+
+// 배치 하나를 broker 가 받을때까지(또는 재시도 불가 오류까지) 재전송
+// 재전송 대기 중 ctx 가 끝나면 false 반환
+func (p *Publisher) drain(ctx context.Context, file string) bool {
+	backoff := time.Second
+	maxBackoff := time.Duration(p.config.MaxBackoff) * time.Second
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Minute
+	}
+	timeout := time.Duration(p.config.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	for {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			// trimSpool 로 삭제된 경우
+			return true
+		}
+		var messages []Message
+		if err := json.Unmarshal(body, &messages); err != nil {
+			logger.LogErr("broken publish spool file, drop batch", err)
+			p.count(&p.dropped, spoolRecords(file))
+			_ = os.Remove(file)
+			return true
+		}
+
+		publishCtx, cancel := context.WithTimeout(ctx, timeout)
+		err = p.bus.Publish(publishCtx, messages)
+		cancel()
+		if err == nil {
+			p.count(&p.published, float64(len(messages)))
+			_ = os.Remove(file)
+			return true
+		}
+		if errors.As(err, &permanentError{}) {
+			logger.LogErr("publish rejected, move batch to dead-letter", err)
+			p.moveDeadLetter(file)
+			return true
+		}
+
+		logger.LogErr("publish failed, retry after "+backoff.String(), err)
+		p.count(&p.retries, 1)
+		if !lifecycle.Sleep(ctx, backoff) {
+			return false
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// 배치 파일을 DEAD_LETTER_PATH 로 옮긴다. 옮기지 못하면 삭제하고 dropped 로 센다.
+func (p *Publisher) moveDeadLetter(file string) {
+	err := os.MkdirAll(p.config.DeadLetterPath, 0755)
+	if err == nil {
+		err = os.Rename(file, filepath.Join(p.config.DeadLetterPath, filepath.Base(file)))
+	}
+	if err != nil {
+		logger.LogErr("failed to move batch to dead-letter, drop batch", err)
+		p.count(&p.dropped, spoolRecords(file))
+		_ = os.Remove(file)
+		return
+	}
+	logger.LogWarn("publish batch moved to dead-letter", zap.String("file", filepath.Join(p.config.DeadLetterPath, filepath.Base(file))))
+	p.count(&p.deadLetter, spoolRecords(file))
+}
+
+func (p *Publisher) count(counter *float64, value float64) {
+	p.mu.Lock()
+	*counter += value
+	p.mu.Unlock()
+}
+
+// Describe prometheus describe
+func (p *Publisher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.publishedDesc
+	ch <- p.droppedDesc
+	ch <- p.retriesDesc
+	ch <- p.deadLetterDesc
+	ch <- p.pendingDesc
+}
+
+// Collect prometheus collect
+func (p *Publisher) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	ch <- prometheus.MustNewConstMetric(p.publishedDesc, prometheus.CounterValue, p.published)
+	ch <- prometheus.MustNewConstMetric(p.droppedDesc, prometheus.CounterValue, p.dropped)
+	ch <- prometheus.MustNewConstMetric(p.retriesDesc, prometheus.CounterValue, p.retries)
+	ch <- prometheus.MustNewConstMetric(p.deadLetterDesc, prometheus.CounterValue, p.deadLetter)
+	p.mu.Unlock()
+	ch <- prometheus.MustNewConstMetric(p.pendingDesc, prometheus.GaugeValue, float64(len(spoolFiles(p.config.SpoolPath))))
+}
+
+// spool 배치 파일 목록 (오래된 순)
+func spoolFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, spoolExt) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// 파일명에 기록된 레코드 수
+func spoolRecords(file string) float64 {
+	name := strings.TrimSuffix(filepath.Base(file), spoolExt)
+	if i := strings.LastIndex(name, "-"); i >= 0 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil {
+			return float64(n)
+		}
+	}
+	return 0
+}
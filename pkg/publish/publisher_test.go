@@ -0,0 +1,286 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBus errs 순서대로 실패하고 이후는 받은 메시지를 기록
+type fakeBus struct {
+	mu      sync.Mutex
+	errs    []error
+	calls   int
+	batches [][]Message
+}
+
+func (b *fakeBus) Publish(ctx context.Context, messages []Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	b.batches = append(b.batches, messages)
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func (b *fakeBus) Close() error {
+	return nil
+}
+
+func (b *fakeBus) received() (int, [][]Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls, append([][]Message{}, b.batches...)
+}
+
+var now = time.Date(2023, 11, 8, 13, 31, 0, 0, time.Local)
+
+// UECON_AMF 형식 CSV (header 3줄 + 2행)
+var csvData = [][]string{
+	{"UECON_AMF"},
+	{""},
+	{"NE_ID", "SYSTEM_ID", "NE_NAME", "PERIOD", "TIME_OFFSET", "GRAN_PERIOD", "LOCATION", "RegUe(count)"},
+	{"amf-1", "1", "AMF01", "2023-11-08 13:15:00", "+09:00", "5", "seoul", "10"},
+	{"amf-2", "2", "AMF02", "2023-11-08 13:15:00", "+09:00", "5", "busan", "20"},
+}
+
+func newPublisher(t *testing.T, spool string, bus Bus, maxSpool int) *Publisher {
+	t.Helper()
+	p, err := NewPublisher(cfg.Publish{SpoolPath: spool, MaxSpoolFiles: maxSpool, Timeout: 5, MaxBackoff: 1}, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// counters published, dropped, retries, deadLetter
+func counters(p *Publisher) (float64, float64, float64, float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.published, p.dropped, p.retries, p.deadLetter
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readSpool(file string) ([]Message, error) {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var messages []Message
+	err = json.Unmarshal(body, &messages)
+	return messages, err
+}
+
+func keys(messages []Message) []string {
+	var list []string
+	for _, m := range messages {
+		list = append(list, m.Key)
+	}
+	return list
+}
+
+func TestPublisherSpoolAndRetry(t *testing.T) {
+	spool := t.TempDir()
+	bus := &fakeBus{errs: []error{errors.New("broker not available")}}
+
+	// worker 시작 전 (또는 재시작 전) 배치는 spool 에 남는다.
+	if err := newPublisher(t, spool, bus, 0).Publish("", "UECON_AMF", csvData, now); err != nil {
+		t.Fatal(err)
+	}
+	if files := spoolFiles(spool); len(files) != 1 || spoolRecords(files[0]) != 2 {
+		t.Fatalf("spool = %v", files)
+	}
+
+	p := newPublisher(t, spool, bus, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	waitFor(t, func() bool { published, _, _, _ := counters(p); return published == 2 })
+	calls, batches := bus.received()
+	if _, _, retries, _ := counters(p); calls != 2 || retries != 1 {
+		t.Errorf("calls = %d, retries = %v", calls, retries)
+	}
+	// 재전송은 같은 key 로 보낸다.
+	want := []string{"UECON_AMF|amf-1|2023-11-08 13:15:00", "UECON_AMF|amf-2|2023-11-08 13:15:00"}
+	for _, batch := range batches {
+		if got := keys(batch); !reflect.DeepEqual(got, want) || batch[0].Topic != "oss.UECON_AMF" {
+			t.Errorf("keys = %v, topic = %s", got, batch[0].Topic)
+		}
+	}
+	if files := spoolFiles(spool); len(files) != 0 {
+		t.Errorf("spool = %v, want empty", files)
+	}
+}
+
+func TestPublisherKey(t *testing.T) {
+	spool := t.TempDir()
+	p := newPublisher(t, spool, &fakeBus{}, 0)
+
+	// 같은 데이터를 다시 보내면 같은 key, 사이트가 있으면 key 앞에 붙는다.
+	for _, site := range []string{"", "", "east"} {
+		if err := p.Publish(site, "UECON_AMF", csvData, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got [][]string
+	for _, file := range spoolFiles(spool) {
+		messages, err := readSpool(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, keys(messages))
+	}
+	want := [][]string{
+		{"UECON_AMF|amf-1|2023-11-08 13:15:00", "UECON_AMF|amf-2|2023-11-08 13:15:00"},
+		{"UECON_AMF|amf-1|2023-11-08 13:15:00", "UECON_AMF|amf-2|2023-11-08 13:15:00"},
+		{"east|UECON_AMF|amf-1|2023-11-08 13:15:00", "east|UECON_AMF|amf-2|2023-11-08 13:15:00"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+}
+
+func TestPublisherTrimSpool(t *testing.T) {
+	spool := t.TempDir()
+	p := newPublisher(t, spool, &fakeBus{}, 2)
+
+	for _, family := range []string{"UECON_AMF", "UEID_AMF", "AMFTPS"} {
+		if err := p.Publish("", family, csvData, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := spoolFiles(spool)
+	if len(files) != 2 {
+		t.Fatalf("spool = %v, want 2 batches", files)
+	}
+	if _, dropped, _, _ := counters(p); dropped != 2 {
+		t.Errorf("dropped = %v, want 2", dropped)
+	}
+	// 가장 오래된 UECON_AMF 배치가 삭제된다.
+	for i, topic := range []string{"oss.UEID_AMF", "oss.AMFTPS"} {
+		messages, err := readSpool(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if messages[0].Topic != topic {
+			t.Errorf("batch %d topic = %s, want %s", i, messages[0].Topic, topic)
+		}
+	}
+}
+
+func TestPublisherDeadLetter(t *testing.T) {
+	spool := t.TempDir()
+	bus := &fakeBus{errs: []error{permanentError{errors.New("message too large")}}}
+	p := newPublisher(t, spool, bus, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	if err := p.Publish("", "UECON_AMF", csvData, now); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { _, _, _, deadLetter := counters(p); return deadLetter == 2 })
+
+	if _, _, retries, _ := counters(p); retries != 0 {
+		t.Errorf("retries = %v, want 0", retries)
+	}
+	if files := spoolFiles(spool); len(files) != 0 {
+		t.Errorf("spool = %v, want empty", files)
+	}
+	dead := spoolFiles(filepath.Join(spool, "dead-letter"))
+	if len(dead) != 1 {
+		t.Fatalf("dead-letter = %v, want 1 batch", dead)
+	}
+	if messages, err := readSpool(dead[0]); err != nil || len(messages) != 2 {
+		t.Errorf("dead-letter batch = %v, %v", messages, err)
+	}
+}
+
+func TestPublisherStop(t *testing.T) {
+	spool := t.TempDir()
+	bus := &fakeBus{errs: []error{errors.New("broker not available"), errors.New("broker not available")}}
+	p := newPublisher(t, spool, bus, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		p.run(ctx)
+		close(done)
+	}()
+	if err := p.Publish("", "UECON_AMF", csvData, now); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { calls, _ := bus.received(); return calls == 1 })
+
+	// 재전송 대기 중 종료되면 배치는 다음 실행을 위해 spool 에 남는다.
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop")
+	}
+	if files := spoolFiles(spool); len(files) != 1 {
+		t.Errorf("spool = %v, want 1 batch", files)
+	}
+}
+
+func TestKafkaError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"connection", io.ErrUnexpectedEOF, false},
+		{"temporary", kafka.NotEnoughReplicas, false},
+		{"too large", kafka.MessageSizeTooLarge, true},
+		{"not authorized", kafka.TopicAuthorizationFailed, true},
+		{"partial permanent", kafka.WriteErrors{nil, kafka.MessageSizeTooLarge}, true},
+		{"partial temporary", kafka.WriteErrors{kafka.LeaderNotAvailable, kafka.MessageSizeTooLarge}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := kafkaError(tt.err)
+			if got := errors.As(err, &permanentError{}); got != tt.permanent {
+				t.Errorf("permanent = %v, want %v", got, tt.permanent)
+			}
+			if err.Error() != tt.err.Error() {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
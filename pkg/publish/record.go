@@ -0,0 +1,90 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package publish
+
+import (
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record OSS CSV 한 행 (라벨 컬럼 + 모든 counter 값)
+type Record struct {
+	Family     string             `json:"family"`
+	NeID       string             `json:"ne_id"`
+	SystemID   string             `json:"system_id"`
+	NeName     string             `json:"ne_name"`
+	Period     string             `json:"period"`
+	TimeOffset string             `json:"time_offset"`
+	GranPeriod string             `json:"gran_period"`
+	Location   string             `json:"location"`
+	Timestamp  int64              `json:"timestamp"` // period 시간 (unix ms)
+	Values     map[string]float64 `json:"values"`
+	Site       string             `json:"site,omitempty"` // SITES 설정시 사이트 이름
+}
+
+// Key 중복 제거용 키 (site + family + ne_id + period, 단일 수집은 site 제외)
+func (r Record) Key() string {
+	key := r.Family + "|" + r.NeID + "|" + r.Period
+	if r.Site != "" {
+		key = r.Site + "|" + key
+	}
+	return key
+}
+
+// Records CSV 데이터를 행 단위 Record 로 변환
+// 헤더(2행)의 컬럼명에서 단위 "(count)" 를 뺀 이름을 값의 키로 사용하고, 숫자가 아닌 값은 제외한다.
+func Records(site, family string, csvData [][]string, now time.Time) []Record {
+	if len(csvData) < 3 {
+		return nil
+	}
+	header := csvData[2]
+
+	var records []Record
+	for _, row := range csvData[3:] {
+		if len(row) < 7 {
+			continue
+		}
+		record := Record{
+			Family:     family,
+			NeID:       row[0],
+			SystemID:   row[1],
+			NeName:     row[2],
+			Period:     row[3],
+			TimeOffset: row[4],
+			GranPeriod: row[5],
+			Location:   row[6],
+			Timestamp:  csv.ParsePeriod(row, now).UnixMilli(),
+			Values:     map[string]float64{},
+			Site:       site,
+		}
+		for i := 7; i < len(row) && i < len(header); i++ {
+			val, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+			if err != nil {
+				continue
+			}
+			record.Values[columnName(header[i])] = val
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// "TotalMsg(count)" -> "TotalMsg"
+func columnName(header string) string {
+	name := strings.TrimSpace(header)
+	if i := strings.Index(name, "("); i > 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	return name
+}
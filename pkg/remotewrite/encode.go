@@ -0,0 +1,181 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package remotewrite
+
+import (
+	"fmt"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"sort"
+)
+
+// prometheus prompb.WriteRequest 필드 번호
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+const (
+	fieldTimeSeries  = 1
+	fieldLabels      = 1
+	fieldSamples     = 2
+	fieldLabelName   = 1
+	fieldLabelValue  = 2
+	fieldSampleValue = 1
+	fieldSampleTime  = 2
+)
+
+// Encode WriteRequest 를 protobuf 로 직렬화 후 snappy(block) 압축
+func Encode(series []TimeSeries) []byte {
+	return snappy.Encode(nil, Marshal(series))
+}
+
+// Marshal WriteRequest protobuf 직렬화 (라벨은 이름순 정렬)
+func Marshal(series []TimeSeries) []byte {
+	var buf []byte
+	for _, ts := range series {
+		buf = protowire.AppendTag(buf, fieldTimeSeries, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, marshalSeries(ts))
+	}
+	return buf
+}
+
+func marshalSeries(ts TimeSeries) []byte {
+	labels := append([]Label{}, ts.Labels...)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	var buf []byte
+	for _, label := range labels {
+		var l []byte
+		l = protowire.AppendTag(l, fieldLabelName, protowire.BytesType)
+		l = protowire.AppendString(l, label.Name)
+		l = protowire.AppendTag(l, fieldLabelValue, protowire.BytesType)
+		l = protowire.AppendString(l, label.Value)
+
+		buf = protowire.AppendTag(buf, fieldLabels, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, l)
+	}
+	for _, sample := range ts.Samples {
+		var s []byte
+		s = protowire.AppendTag(s, fieldSampleValue, protowire.Fixed64Type)
+		s = protowire.AppendFixed64(s, math.Float64bits(sample.Value))
+		s = protowire.AppendTag(s, fieldSampleTime, protowire.VarintType)
+		s = protowire.AppendVarint(s, uint64(sample.Timestamp))
+
+		buf = protowire.AppendTag(buf, fieldSamples, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, s)
+	}
+	return buf
+}
+
+// Decode snappy 압축된 WriteRequest 해제 후 역직렬화 (수신 테스트용)
+func Decode(body []byte) ([]TimeSeries, error) {
+	raw, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []TimeSeries
+	err = walk(raw, func(num protowire.Number, b []byte) error {
+		if num != fieldTimeSeries {
+			return nil
+		}
+		var ts TimeSeries
+		err := walk(b, func(num protowire.Number, b []byte) error {
+			switch num {
+			case fieldLabels:
+				var label Label
+				err := walk(b, func(num protowire.Number, v []byte) error {
+					if num == fieldLabelName {
+						label.Name = string(v)
+					} else if num == fieldLabelValue {
+						label.Value = string(v)
+					}
+					return nil
+				})
+				ts.Labels = append(ts.Labels, label)
+				return err
+			case fieldSamples:
+				sample, err := unmarshalSample(b)
+				ts.Samples = append(ts.Samples, sample)
+				return err
+			}
+			return nil
+		})
+		series = append(series, ts)
+		return err
+	})
+	return series, err
+}
+
+// length-delimited 필드만 순회
+func walk(b []byte, fn func(protowire.Number, []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, v); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func unmarshalSample(b []byte) (Sample, error) {
+	var sample Sample
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return sample, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case num == fieldSampleValue && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return sample, protowire.ParseError(n)
+			}
+			sample.Value = math.Float64frombits(v)
+			b = b[n:]
+		case num == fieldSampleTime && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return sample, protowire.ParseError(n)
+			}
+			sample.Timestamp = int64(v)
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return sample, fmt.Errorf("sample field %d: %w", num, protowire.ParseError(n))
+			}
+			b = b[n:]
+		}
+	}
+	return sample, nil
+}
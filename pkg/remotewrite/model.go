@@ -0,0 +1,30 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package remotewrite
+
+// Label prometheus.Label (name, value)
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample 값과 timestamp(ms), OSS 데이터는 period 시간을 사용
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// TimeSeries remote write 의 시계열 하나 (__name__ 라벨 포함)
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
@@ -0,0 +1,372 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"hash/fnv"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const walExt = ".wal"
+
+// Sender OSS 시계열을 shard 별 WAL 폴더에 배치 파일로 저장하고,
+// shard worker 가 순서대로 remote write 전송 후 삭제한다.
+// 전송 실패(네트워크, 5xx, 429)는 backoff 후 같은 배치를 재전송하며 재시작시 WAL 에서 이어서 보낸다.
+type Sender struct {
+	config cfg.RemoteWrite
+	client *http.Client
+	shards []*shard
+	seq    uint64
+
+	mu      sync.Mutex
+	sent    float64
+	dropped float64
+	retries float64
+
+	sentDesc    *prometheus.Desc
+	droppedDesc *prometheus.Desc
+	retriesDesc *prometheus.Desc
+	pendingDesc *prometheus.Desc
+}
+
+type shard struct {
+	id     int
+	dir    string
+	notify chan struct{}
+}
+
+// 재전송 하지 않는 오류 (4xx)
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func NewSender(config cfg.RemoteWrite) (*Sender, error) {
+	if config.Url == "" {
+		return nil, errors.New("remote write url is empty")
+	}
+	if config.Shards <= 0 {
+		config.Shards = 1
+	}
+	if config.MaxSamplesPerSend <= 0 {
+		config.MaxSamplesPerSend = 2000
+	}
+
+	s := &Sender{
+		config: config,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+			},
+			Timeout: time.Duration(config.Timeout) * time.Second,
+		},
+		sentDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "remote_write", "sent_samples_total"),
+			"samples sent by remote write",
+			nil, nil,
+		),
+		droppedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "remote_write", "dropped_samples_total"),
+			"samples dropped by remote write (rejected or WAL full)",
+			nil, nil,
+		),
+		retriesDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "remote_write", "retries_total"),
+			"remote write send retries",
+			nil, nil,
+		),
+		pendingDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "remote_write", "pending_batches"),
+			"batches waiting in the remote write WAL",
+			[]string{"shard"}, nil,
+		),
+	}
+
+	for i := 0; i < config.Shards; i++ {
+		dir := filepath.Join(config.WalPath, fmt.Sprintf("shard-%d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Cause(err)
+		}
+		// 비정상 종료로 남은 임시파일 정리
+		if tmps, err := filepath.Glob(filepath.Join(dir, ".*"+walExt)); err == nil {
+			for _, tmp := range tmps {
+				_ = os.Remove(tmp)
+			}
+		}
+		s.shards = append(s.shards, &shard{id: i, dir: dir, notify: make(chan struct{}, 1)})
+	}
+	return s, nil
+}
+
+// Start shard worker 실행 (WAL 에 남아있는 배치부터 전송)
+// ctx 가 끝나면 worker 는 재전송 대기를 멈추고 종료하며, 남은 배치는 WAL 에 그대로 둔다.
+func (s *Sender) Start(ctx context.Context) {
+	for _, sh := range s.shards {
+		go s.run(ctx, sh)
+		sh.wake()
+	}
+}
+
+func (sh *shard) wake() {
+	select {
+	case sh.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue 시계열을 라벨 hash 로 shard 에 나누고 MAX_SAMPLES_PER_SEND 단위로 WAL 에 저장
+func (s *Sender) Enqueue(series []TimeSeries) error {
+	sharded := make([][]TimeSeries, len(s.shards))
+	for _, ts := range series {
+		i := int(hashLabels(ts.Labels) % uint64(len(s.shards)))
+		sharded[i] = append(sharded[i], ts)
+	}
+
+	for i, list := range sharded {
+		for len(list) > 0 {
+			batch, samples := split(list, s.config.MaxSamplesPerSend)
+			list = list[len(batch):]
+			if err := s.writeWal(s.shards[i], batch, samples); err != nil {
+				return err
+			}
+		}
+		s.shards[i].wake()
+	}
+	return nil
+}
+
+// max 샘플 수 이하가 되도록 앞에서부터 자른다.
+func split(series []TimeSeries, max int) ([]TimeSeries, int) {
+	samples := 0
+	for i, ts := range series {
+		if samples > 0 && samples+len(ts.Samples) > max {
+			return series[:i], samples
+		}
+		samples += len(ts.Samples)
+	}
+	return series, samples
+}
+
+// WAL 파일명: <순번>-<샘플수>.wal, 임시파일 작성 후 rename
+func (s *Sender) writeWal(sh *shard, batch []TimeSeries, samples int) error {
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%019d%06d-%d%s", time.Now().UnixNano(), s.seq%1000000, samples, walExt)
+	s.mu.Unlock()
+
+	tmp := filepath.Join(sh.dir, "."+name)
+	if err := os.WriteFile(tmp, Encode(batch), 0644); err != nil {
+		return errors.Cause(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(sh.dir, name)); err != nil {
+		return errors.Cause(err)
+	}
+
+	s.trimWal(sh)
+	return nil
+}
+
+// MAX_WAL_FILES 초과시 오래된 배치부터 삭제
+func (s *Sender) trimWal(sh *shard) {
+	if s.config.MaxWalFiles <= 0 {
+		return
+	}
+	files := walFiles(sh.dir)
+	for len(files) > s.config.MaxWalFiles {
+		if err := os.Remove(files[0]); err == nil {
+			logger.LogWarn("remote write WAL is full, drop oldest batch", zap.String("file", files[0]))
+			s.count(&s.dropped, walSamples(files[0]))
+		}
+		files = files[1:]
+	}
+}
+
+func (s *Sender) run(ctx context.Context, sh *shard) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sh.notify:
+		}
+		for {
+			files := walFiles(sh.dir)
+			if len(files) == 0 {
+				break
+			}
+			if !s.drain(ctx, files[0]) {
+				return
+			}
+		}
+	}
+}
+
+// 배치 하나를 성공(또는 영구 실패)할 때까지 재전송
+// 재전송 대기 중 ctx 가 끝나면 false 반환
+func (s *Sender) drain(ctx context.Context, file string) bool {
+	backoff := time.Second
+	maxBackoff := time.Duration(s.config.MaxBackoff) * time.Second
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Minute
+	}
+
+	for {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			// trimWal 로 삭제된 경우
+			return true
+		}
+
+		err = s.send(ctx, body)
+		if err == nil {
+			s.count(&s.sent, walSamples(file))
+			_ = os.Remove(file)
+			return true
+		}
+		if _, ok := err.(permanentError); ok {
+			logger.LogErr("remote write rejected, drop batch", err)
+			s.count(&s.dropped, walSamples(file))
+			_ = os.Remove(file)
+			return true
+		}
+
+		logger.LogErr("remote write failed, retry after "+backoff.String(), err)
+		s.count(&s.retries, 1)
+		if !lifecycle.Sleep(ctx, backoff) {
+			return false
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (s *Sender) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.Url, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "cnf-exporter")
+	if s.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BearerToken)
+	} else if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("remote write %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return permanentError{err}
+	}
+	return err
+}
+
+func (s *Sender) count(counter *float64, value float64) {
+	s.mu.Lock()
+	*counter += value
+	s.mu.Unlock()
+}
+
+// Describe prometheus describe
+func (s *Sender) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.sentDesc
+	ch <- s.droppedDesc
+	ch <- s.retriesDesc
+	ch <- s.pendingDesc
+}
+
+// Collect prometheus collect
+func (s *Sender) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	ch <- prometheus.MustNewConstMetric(s.sentDesc, prometheus.CounterValue, s.sent)
+	ch <- prometheus.MustNewConstMetric(s.droppedDesc, prometheus.CounterValue, s.dropped)
+	ch <- prometheus.MustNewConstMetric(s.retriesDesc, prometheus.CounterValue, s.retries)
+	s.mu.Unlock()
+	for _, sh := range s.shards {
+		ch <- prometheus.MustNewConstMetric(s.pendingDesc, prometheus.GaugeValue, float64(len(walFiles(sh.dir))), strconv.Itoa(sh.id))
+	}
+}
+
+// WAL 배치 파일 목록 (오래된 순)
+func walFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, walExt) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// 파일명에 기록된 샘플 수
+func walSamples(file string) float64 {
+	name := strings.TrimSuffix(filepath.Base(file), walExt)
+	if i := strings.LastIndex(name, "-"); i >= 0 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil {
+			return float64(n)
+		}
+	}
+	return 0
+}
+
+func hashLabels(labels []Label) uint64 {
+	sorted := append([]Label{}, labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	h := fnv.New64a()
+	for _, label := range sorted {
+		h.Write([]byte(label.Name))
+		h.Write([]byte{0xff})
+		h.Write([]byte(label.Value))
+		h.Write([]byte{0xff})
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,205 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver remote write 수신 서버, status 순서대로 응답하고 이후는 200
+type receiver struct {
+	*httptest.Server
+	t *testing.T
+
+	mu       sync.Mutex
+	status   []int
+	requests int
+	series   []TimeSeries
+}
+
+func newReceiver(t *testing.T, status ...int) *receiver {
+	r := &receiver{t: t, status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.handle))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) handle(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+
+	if req.Header.Get("Content-Encoding") != "snappy" || req.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		r.t.Errorf("unexpected headers: %v", req.Header)
+	}
+	if len(r.status) > 0 {
+		status := r.status[0]
+		r.status = r.status[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	series, err := Decode(body)
+	if err != nil {
+		r.t.Errorf("decode: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.series = append(r.series, series...)
+}
+
+func (r *receiver) received() (int, []TimeSeries) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests, append([]TimeSeries{}, r.series...)
+}
+
+func newSender(t *testing.T, url, wal string) *Sender {
+	t.Helper()
+	s, err := NewSender(cfg.RemoteWrite{Url: url, WalPath: wal, Shards: 1, MaxSamplesPerSend: 2, Timeout: 5, MaxBackoff: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testSeries() []TimeSeries {
+	var series []TimeSeries
+	for i, ne := range []string{"amf-1", "amf-2", "amf-3"} {
+		series = append(series, TimeSeries{
+			Labels:  []Label{{Name: "__name__", Value: "p5g_exporter_amf_reg_ue"}, {Name: "ne_id", Value: ne}},
+			Samples: []Sample{{Value: float64(i) + 0.5, Timestamp: 1699417800000}},
+		})
+	}
+	return series
+}
+
+// counters sent, dropped, retries
+func counters(s *Sender) (float64, float64, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent, s.dropped, s.retries
+}
+
+// waitFor cond 가 참이 될 때까지 대기
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSenderDecode(t *testing.T) {
+	r := newReceiver(t)
+	s := newSender(t, r.URL, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	want := testSeries()
+	if err := s.Enqueue(want); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { sent, _, _ := counters(s); return sent == 3 })
+
+	// MAX_SAMPLES_PER_SEND 2 이므로 2개 배치로 나뉜다.
+	requests, got := r.received()
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded = %+v, want %+v", got, want)
+	}
+	if len(walFiles(s.shards[0].dir)) != 0 {
+		t.Errorf("wal = %v, want empty", walFiles(s.shards[0].dir))
+	}
+}
+
+func TestSenderReplayAndRetry(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	wal := t.TempDir()
+
+	// 전송 전에 종료된 sender 의 배치는 WAL 에 남는다.
+	if err := newSender(t, r.URL, wal).Enqueue(testSeries()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newSender(t, r.URL, wal)
+	if len(walFiles(s.shards[0].dir)) != 2 {
+		t.Fatalf("wal = %v, want 2 batches", walFiles(s.shards[0].dir))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	waitFor(t, func() bool { sent, _, _ := counters(s); return sent == 3 })
+	requests, got := r.received()
+	if _, _, retries := counters(s); requests != 3 || retries != 1 || len(got) != 3 {
+		t.Errorf("requests = %d, retries = %v, series = %d", requests, retries, len(got))
+	}
+}
+
+func TestSenderDropRejected(t *testing.T) {
+	r := newReceiver(t, http.StatusBadRequest)
+	s := newSender(t, r.URL, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	if err := s.Enqueue(testSeries()[:1]); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { _, dropped, _ := counters(s); return dropped == 1 })
+	if _, _, retries := counters(s); retries != 0 || len(walFiles(s.shards[0].dir)) != 0 {
+		t.Errorf("retries = %v, wal = %v", retries, walFiles(s.shards[0].dir))
+	}
+}
+
+func TestSenderStop(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	s := newSender(t, r.URL, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, s.shards[0])
+		close(done)
+	}()
+	if err := s.Enqueue(testSeries()[:1]); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { requests, _ := r.received(); return requests == 1 })
+
+	// 재전송 대기 중 종료되면 배치는 다음 실행을 위해 WAL 에 남는다.
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop")
+	}
+	if len(walFiles(s.shards[0].dir)) != 1 {
+		t.Errorf("wal = %v, want 1 batch", walFiles(s.shards[0].dir))
+	}
+}
@@ -0,0 +1,122 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package retention
+
+import (
+	"compress/gzip"
+	encodingCsv "encoding/csv"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 압축 방식별 확장자
+func extension(compression string) string {
+	switch strings.ToLower(compression) {
+	case "zstd":
+		return ".zst"
+	default:
+		return ".gz"
+	}
+}
+
+func newWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch strings.ToLower(compression) {
+	case "zstd":
+		return zstd.NewWriter(w)
+	case "gzip", "":
+		return gzip.NewWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q (gzip|zstd)", compression)
+	}
+}
+
+// writeAtomic 임시파일에 압축하여 쓰고 rename 으로 교체한다.
+// 중간에 실패해도 기존 파일이나 절반만 쓰인 파일이 남지 않는다.
+func writeAtomic(dest, compression string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return errors.Cause(err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".tmp-"+filepath.Base(dest))
+	if err != nil {
+		return errors.Cause(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	cw, err := newWriter(tmp, compression)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := write(cw); err != nil {
+		cw.Close()
+		tmp.Close()
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		tmp.Close()
+		return errors.Cause(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Cause(err)
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// compressFile Family.csv 를 Family.csv.gz(.zst) 로 압축하고 원본을 삭제
+func compressFile(path, compression string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return errors.Cause(err)
+	}
+	defer src.Close()
+
+	err = writeAtomic(path+extension(compression), compression, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// writeRollup 하루치 구간 데이터를 헤더 3줄 + 데이터 행 형식의 한 파일로 저장
+func writeRollup(dest, compression string, files [][][]string) error {
+	return writeAtomic(dest, compression, func(w io.Writer) error {
+		writer := encodingCsv.NewWriter(w)
+		header := false
+		for _, data := range files {
+			if len(data) < 3 {
+				continue
+			}
+			if !header {
+				if err := writer.WriteAll(data[:3]); err != nil {
+					return err
+				}
+				header = true
+			}
+			for _, row := range data[3:] {
+				if err := writer.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+}
@@ -0,0 +1,106 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package retention
+
+import (
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	for _, compression := range []string{"gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			root := t.TempDir()
+			old := backup(t, root, "2023-11-08", "1000-1015", family("ne101,AMF_01,10"))
+			recent := backup(t, root, "2023-11-08", "1300-1315", family("ne101,AMF_01,20"))
+			backup(t, root, "2023-11-08", "1315-1330", family("ne101,AMF_01,30"))
+
+			m := newManager(root, cfg.Retention{CompressAfter: 1, Compression: compression})
+			if err := m.RunOnce(); err != nil {
+				t.Fatal(err)
+			}
+
+			dest := filepath.Join(old, "AMFTPS.csv"+extension(compression))
+			data, err := csv.LoadCsv(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data) != 4 || data[3][2] != "10" {
+				t.Errorf("compressed data = %v", data)
+			}
+			if _, err := os.Stat(filepath.Join(old, "AMFTPS.csv")); !os.IsNotExist(err) {
+				t.Errorf("source is not removed: %v", err)
+			}
+			// 보호 구간은 압축하지 않는다.
+			if _, err := os.Stat(filepath.Join(recent, "AMFTPS.csv")); err != nil {
+				t.Errorf("protected source: %v", err)
+			}
+			if m.compressed != 1 {
+				t.Errorf("compressed = %v, want 1", m.compressed)
+			}
+		})
+	}
+}
+
+// 압축에 실패하면 원본이 그대로 남고 임시파일이 남지 않는다.
+func TestCompressFileFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression string
+		setup       func(t *testing.T, path string)
+	}{
+		{
+			name:        "unsupported compression",
+			compression: "lz4",
+		},
+		{
+			name:        "rename failed",
+			compression: "gzip",
+			setup: func(t *testing.T, path string) {
+				// 대상 경로가 비어있지 않은 폴더면 rename 실패
+				if err := os.MkdirAll(filepath.Join(path+".gz", "keep"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := backup(t, t.TempDir(), "2023-11-08", "1000-1015", family("ne101,AMF_01,10"))
+			path := filepath.Join(dir, "AMFTPS.csv")
+			if tt.setup != nil {
+				tt.setup(t, path)
+			}
+
+			if err := compressFile(path, tt.compression); err == nil {
+				t.Fatal("compressFile succeeded")
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != family("ne101,AMF_01,10") {
+				t.Errorf("source = %q", content)
+			}
+			files, _ := os.ReadDir(dir)
+			for _, file := range files {
+				if file.Name() != "AMFTPS.csv" && file.Name() != "AMFTPS.csv.gz" {
+					t.Errorf("leftover file %s", file.Name())
+				}
+			}
+		})
+	}
+}
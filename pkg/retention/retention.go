@@ -0,0 +1,458 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package retention
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/health"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/history"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveDir 일별 Family 아카이브 폴더명 (CSV_PATH/archive/YYYY-MM-DD/Family.csv.gz)
+const ArchiveDir = "archive"
+
+// 수집 중이거나 직전 수집의 폴더는 삭제/압축하지 않는다.
+const protectWindow = 30 * time.Minute
+
+// Manager 백업 폴더 보관 정책 실행 및 디스크 사용량 메트릭
+type Manager struct {
+	Root   string
+	Site   string // SITES 설정시 사이트 이름 (메트릭 site 라벨)
+	Policy cfg.Retention
+	Now    func() time.Time
+	// Leading false 이면 정책 실행 생략 (leader 선출시 follower), nil 이면 항상 실행
+	Leading func() bool
+
+	mu         sync.Mutex
+	usage      map[string]float64
+	folders    float64
+	deleted    float64
+	compressed float64
+	rollups    float64
+	lastRun    time.Time
+
+	usageDesc      *prometheus.Desc
+	foldersDesc    *prometheus.Desc
+	deletedDesc    *prometheus.Desc
+	compressedDesc *prometheus.Desc
+	rollupsDesc    *prometheus.Desc
+	lastRunDesc    *prometheus.Desc
+}
+
+func NewManager(root, site string, policy cfg.Retention) *Manager {
+	var labels prometheus.Labels
+	if site != "" {
+		labels = prometheus.Labels{"site": site}
+	}
+	return &Manager{
+		Root:   root,
+		Site:   site,
+		Policy: policy,
+		Now:    time.Now,
+		usage:  map[string]float64{},
+		usageDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "backup", "disk_usage_bytes"),
+			"disk usage of the CSV backup tree",
+			[]string{"kind"}, labels,
+		),
+		foldersDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "backup", "folders"),
+			"number of hhmm-hhmm backup folders",
+			nil, labels,
+		),
+		deletedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "backup", "deleted_folders_total"),
+			"backup folders deleted by the retention policy",
+			nil, labels,
+		),
+		compressedDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "backup", "compressed_files_total"),
+			"backup CSV files compressed by the retention policy",
+			nil, labels,
+		),
+		rollupsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "backup", "rollup_files_total"),
+			"daily family archives written by the retention policy",
+			nil, labels,
+		),
+		lastRunDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("cnf_exporter", "backup", "retention_last_run_timestamp_seconds"),
+			"last time the retention policy ran",
+			nil, labels,
+		),
+	}
+}
+
+// Start Interval(분) 마다 RunOnce 를 백그라운드로 실행, ctx 가 끝나면 중단
+func (m *Manager) Start(ctx context.Context) {
+	interval := time.Duration(m.Policy.Interval) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	name := "retention"
+	if m.Site != "" {
+		name += ":" + m.Site
+	}
+	go func() {
+		defer health.Stop(name)
+		for {
+			health.Beat(name, interval)
+			if m.Leading == nil || m.Leading() {
+				if err := m.RunOnce(); err != nil {
+					logger.LogErr("retention run failed", err)
+				}
+			}
+			if !lifecycle.Sleep(ctx, interval) {
+				return
+			}
+		}
+	}()
+}
+
+// RunOnce 압축 -> 일별 아카이브 -> 기간 삭제 -> 용량 삭제 순서로 한번 실행
+func (m *Manager) RunOnce() error {
+	now := m.Now()
+
+	windows, err := history.ListWindows(m.Root)
+	if err != nil {
+		return err
+	}
+	protected := m.protected(windows, now)
+
+	if m.Policy.CompressAfter > 0 {
+		m.compress(windows, protected, now)
+	}
+	if m.Policy.Rollup {
+		m.rollup(windows, protected, now)
+	}
+
+	var deleted []history.Window
+	if m.Policy.MaxAge > 0 {
+		maxAge := time.Duration(m.Policy.MaxAge) * time.Hour
+		for _, window := range windows {
+			if protected[window.Path] || now.Sub(window.End) < maxAge {
+				continue
+			}
+			if m.remove(window.Path) {
+				deleted = append(deleted, window)
+			}
+		}
+	}
+	if m.Policy.ArchiveMaxAge > 0 {
+		m.expireArchives(now)
+	}
+	windows = without(windows, deleted)
+
+	if m.Policy.MaxSize > 0 {
+		m.enforceSize(windows, protected)
+	}
+
+	m.removeEmptyDays()
+	m.updateUsage(now)
+	return nil
+}
+
+// 가장 최근 2개 구간(현재, 직전 수집)과 아직 끝나지 않은 구간은 보호
+func (m *Manager) protected(windows []history.Window, now time.Time) map[string]bool {
+	protected := map[string]bool{}
+	for i := len(windows) - 1; i >= 0 && i >= len(windows)-2; i-- {
+		protected[windows[i].Path] = true
+	}
+	for _, window := range windows {
+		if window.End.After(now.Add(-protectWindow)) {
+			protected[window.Path] = true
+		}
+	}
+	return protected
+}
+
+func (m *Manager) compress(windows []history.Window, protected map[string]bool, now time.Time) {
+	after := time.Duration(m.Policy.CompressAfter) * time.Hour
+	for _, window := range windows {
+		if protected[window.Path] || now.Sub(window.End) < after {
+			continue
+		}
+		files, err := os.ReadDir(window.Path)
+		if err != nil {
+			logger.LogErr("backup folder read failed", err)
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(file.Name(), ".csv") {
+				continue
+			}
+			if err := compressFile(filepath.Join(window.Path, file.Name()), m.Policy.Compression); err != nil {
+				logger.LogErr("backup file compress failed", err)
+				continue
+			}
+			m.mu.Lock()
+			m.compressed++
+			m.mu.Unlock()
+		}
+	}
+}
+
+// 지난 날짜의 구간 폴더를 Family 별 한 파일로 묶어 archive 폴더에 저장
+// 보호 구간이 포함된 날짜는 다음 실행에서 처리한다.
+func (m *Manager) rollup(windows []history.Window, protected map[string]bool, now time.Time) {
+	today := now.Format(history.DayLayout)
+
+	days := map[string][]history.Window{}
+	var order []string
+	for _, window := range windows {
+		if window.Day >= today {
+			continue
+		}
+		if _, ok := days[window.Day]; !ok {
+			order = append(order, window.Day)
+		}
+		days[window.Day] = append(days[window.Day], window)
+	}
+
+	for _, day := range order {
+		skip := false
+		for _, window := range days[day] {
+			if protected[window.Path] {
+				skip = true
+			}
+		}
+		if skip {
+			continue
+		}
+
+		families := map[string][]string{}
+		var names []string
+		for _, window := range days[day] {
+			files, err := os.ReadDir(window.Path)
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				family, ok := csv.FamilyName(file.Name())
+				if !ok {
+					continue
+				}
+				if _, exists := families[family]; !exists {
+					names = append(names, family)
+				}
+				families[family] = append(families[family], filepath.Join(window.Path, file.Name()))
+			}
+		}
+
+		for _, family := range names {
+			dest := filepath.Join(m.Root, ArchiveDir, day, family+".csv"+extension(m.Policy.Compression))
+			if _, err := os.Stat(dest); err == nil {
+				continue
+			}
+			var data [][][]string
+			for _, path := range families[family] {
+				rows, err := csv.LoadCsv(path)
+				if err != nil {
+					logger.LogErr(path+" CSV File is not Open", err)
+					continue
+				}
+				data = append(data, rows)
+			}
+			if err := writeRollup(dest, m.Policy.Compression, data); err != nil {
+				logger.LogErr("daily rollup failed", err)
+				continue
+			}
+			logger.LogInfo("일별 아카이브 생성", zap.String("family", family), zap.String("day", day))
+			m.mu.Lock()
+			m.rollups++
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *Manager) expireArchives(now time.Time) {
+	maxAge := time.Duration(m.Policy.ArchiveMaxAge) * 24 * time.Hour
+	for _, day := range m.archiveDays() {
+		date, _ := time.ParseInLocation(history.DayLayout, day, time.Local)
+		// 날짜 폴더는 해당일 종료 시점 기준으로 경과시간 계산
+		if now.Sub(date.Add(24*time.Hour)) < maxAge {
+			continue
+		}
+		m.remove(filepath.Join(m.Root, ArchiveDir, day))
+	}
+}
+
+// 용량 초과시 오래된 구간 폴더부터 삭제하고, 그래도 초과하면 오래된 아카이브를 삭제
+func (m *Manager) enforceSize(windows []history.Window, protected map[string]bool) {
+	limit := int64(m.Policy.MaxSize) * 1024 * 1024
+
+	usage := int64(0)
+	for _, window := range windows {
+		usage += dirSize(window.Path)
+	}
+	usage += dirSize(filepath.Join(m.Root, ArchiveDir))
+
+	for _, window := range windows {
+		if usage <= limit {
+			return
+		}
+		if protected[window.Path] {
+			continue
+		}
+		size := dirSize(window.Path)
+		if m.remove(window.Path) {
+			usage -= size
+		}
+	}
+	for _, day := range m.archiveDays() {
+		if usage <= limit {
+			return
+		}
+		path := filepath.Join(m.Root, ArchiveDir, day)
+		size := dirSize(path)
+		if m.remove(path) {
+			usage -= size
+		}
+	}
+	if usage > limit {
+		logger.LogWarn("백업 폴더 용량이 MAX_SIZE를 초과합니다.", zap.Int64("usage", usage), zap.Int64("limit", limit))
+	}
+}
+
+func (m *Manager) remove(path string) bool {
+	if err := os.RemoveAll(path); err != nil {
+		logger.LogErr("backup folder delete failed", err)
+		return false
+	}
+	logger.LogInfo("백업 폴더 삭제", zap.String("path", path))
+	m.mu.Lock()
+	m.deleted++
+	m.mu.Unlock()
+	return true
+}
+
+// 구간 폴더가 모두 삭제된 날짜 폴더 정리
+func (m *Manager) removeEmptyDays() {
+	days, err := os.ReadDir(m.Root)
+	if err != nil {
+		return
+	}
+	for _, day := range days {
+		if !day.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(history.DayLayout, day.Name()); err != nil {
+			continue
+		}
+		path := filepath.Join(m.Root, day.Name())
+		if entries, err := os.ReadDir(path); err == nil && len(entries) == 0 {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// archive 폴더의 날짜 목록 (오래된 순)
+func (m *Manager) archiveDays() []string {
+	entries, err := os.ReadDir(filepath.Join(m.Root, ArchiveDir))
+	if err != nil {
+		return nil
+	}
+	var days []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(history.DayLayout, entry.Name()); err != nil {
+			continue
+		}
+		days = append(days, entry.Name())
+	}
+	sort.Strings(days)
+	return days
+}
+
+func (m *Manager) updateUsage(now time.Time) {
+	windows, _ := history.ListWindows(m.Root)
+	var backup int64
+	for _, window := range windows {
+		backup += dirSize(window.Path)
+	}
+	archive := dirSize(filepath.Join(m.Root, ArchiveDir))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage["window"] = float64(backup)
+	m.usage["archive"] = float64(archive)
+	m.folders = float64(len(windows))
+	m.lastRun = now
+}
+
+// Describe prometheus describe
+func (m *Manager) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.usageDesc
+	ch <- m.foldersDesc
+	ch <- m.deletedDesc
+	ch <- m.compressedDesc
+	ch <- m.rollupsDesc
+	ch <- m.lastRunDesc
+}
+
+// Collect prometheus collect
+func (m *Manager) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for kind, value := range m.usage {
+		ch <- prometheus.MustNewConstMetric(m.usageDesc, prometheus.GaugeValue, value, kind)
+	}
+	ch <- prometheus.MustNewConstMetric(m.foldersDesc, prometheus.GaugeValue, m.folders)
+	ch <- prometheus.MustNewConstMetric(m.deletedDesc, prometheus.CounterValue, m.deleted)
+	ch <- prometheus.MustNewConstMetric(m.compressedDesc, prometheus.CounterValue, m.compressed)
+	ch <- prometheus.MustNewConstMetric(m.rollupsDesc, prometheus.CounterValue, m.rollups)
+	if !m.lastRun.IsZero() {
+		ch <- prometheus.MustNewConstMetric(m.lastRunDesc, prometheus.GaugeValue, float64(m.lastRun.Unix()))
+	}
+}
+
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func without(windows, removed []history.Window) []history.Window {
+	if len(removed) == 0 {
+		return windows
+	}
+	skip := map[string]bool{}
+	for _, window := range removed {
+		skip[window.Path] = true
+	}
+	var result []history.Window
+	for _, window := range windows {
+		if !skip[window.Path] {
+			result = append(result, window)
+		}
+	}
+	return result
+}
@@ -0,0 +1,245 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package retention
+
+import (
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/history"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2023, 11, 8, 13, 31, 0, 0, time.Local)
+
+// backup CSV_PATH/day/window/AMFTPS.csv 생성
+func backup(t *testing.T, root, day, window, content string) string {
+	t.Helper()
+	dir := filepath.Join(root, day, window)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "AMFTPS.csv"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func family(row string) string {
+	return "Family name : AMFTPS\nPeriod : 15min\nNE ID,LOCATION,TotalMsg(count)\n" + row + "\n"
+}
+
+func newManager(root string, policy cfg.Retention) *Manager {
+	m := NewManager(root, "", policy)
+	m.Now = func() time.Time { return now }
+	return m
+}
+
+// root 아래 남아있는 day/window 폴더 목록
+func windows(t *testing.T, root string) []string {
+	t.Helper()
+	list, err := history.ListWindows(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, window := range list {
+		names = append(names, window.Day+"/"+window.Name)
+	}
+	return names
+}
+
+func TestProtected(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []string
+		want    []string
+	}{
+		{
+			// 마지막 2개 + 30분 이내에 끝난 구간
+			name:    "recent",
+			windows: []string{"2023-11-08/1200-1215", "2023-11-08/1230-1245", "2023-11-08/1245-1300", "2023-11-08/1300-1315", "2023-11-08/1315-1330"},
+			want:    []string{"2023-11-08/1300-1315", "2023-11-08/1315-1330"},
+		},
+		{
+			name:    "within 30 minutes",
+			windows: []string{"2023-11-08/1245-1300", "2023-11-08/1300-1302", "2023-11-08/1302-1304", "2023-11-08/1304-1306"},
+			want:    []string{"2023-11-08/1300-1302", "2023-11-08/1302-1304", "2023-11-08/1304-1306"},
+		},
+		{
+			// 수집이 멈춰도 마지막 2개 구간은 보호
+			name:    "stale",
+			windows: []string{"2023-11-07/0900-0915", "2023-11-07/0915-0930", "2023-11-07/2345-0000"},
+			want:    []string{"2023-11-07/0915-0930", "2023-11-07/2345-0000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, window := range tt.windows {
+				parts := strings.Split(window, "/")
+				backup(t, root, parts[0], parts[1], family("ne101,AMF_01,1"))
+			}
+			list, err := history.ListWindows(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for path := range newManager(root, cfg.Retention{}).protected(list, now) {
+				rel, _ := filepath.Rel(root, path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("protected = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	root := t.TempDir()
+	for _, window := range []string{"2023-11-07/2345-0000", "2023-11-08/1100-1115", "2023-11-08/1215-1230", "2023-11-08/1230-1245", "2023-11-08/1300-1315", "2023-11-08/1315-1330"} {
+		parts := strings.Split(window, "/")
+		backup(t, root, parts[0], parts[1], family("ne101,AMF_01,1"))
+	}
+
+	m := newManager(root, cfg.Retention{MaxAge: 1})
+	if err := m.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"2023-11-08/1230-1245", "2023-11-08/1300-1315", "2023-11-08/1315-1330"}
+	if got := windows(t, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("windows = %v, want %v", got, want)
+	}
+	// 구간이 모두 삭제된 날짜 폴더도 정리
+	if _, err := os.Stat(filepath.Join(root, "2023-11-07")); !os.IsNotExist(err) {
+		t.Errorf("empty day folder is not removed: %v", err)
+	}
+	if m.deleted != 3 {
+		t.Errorf("deleted = %v, want 3", m.deleted)
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	root := t.TempDir()
+	// 400KB 구간 4개(마지막 2개 보호) + 400KB 아카이브, 제한 1MB
+	chunk := family(strings.Repeat("x", 400*1024))
+	for _, window := range []string{"2023-11-08/1100-1115", "2023-11-08/1115-1130", "2023-11-08/1300-1315", "2023-11-08/1315-1330"} {
+		parts := strings.Split(window, "/")
+		backup(t, root, parts[0], parts[1], chunk)
+	}
+	archive := filepath.Join(root, ArchiveDir, "2023-11-06")
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(archive, "AMFTPS.csv.gz"), []byte(chunk), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newManager(root, cfg.Retention{MaxSize: 1})
+	if err := m.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 오래된 구간 -> 아카이브 순서로 삭제, 보호 구간은 남김
+	want := []string{"2023-11-08/1300-1315", "2023-11-08/1315-1330"}
+	if got := windows(t, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("windows = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Errorf("archive is not removed: %v", err)
+	}
+	if m.usage["window"] > 1024*1024 {
+		t.Errorf("usage = %v, want <= 1MB", m.usage["window"])
+	}
+}
+
+func TestMaxSizeKeepsArchiveWhenEnough(t *testing.T) {
+	root := t.TempDir()
+	chunk := family(strings.Repeat("x", 400*1024))
+	for _, window := range []string{"2023-11-08/1100-1115", "2023-11-08/1115-1130", "2023-11-08/1130-1145", "2023-11-08/1300-1315", "2023-11-08/1315-1330"} {
+		parts := strings.Split(window, "/")
+		backup(t, root, parts[0], parts[1], chunk)
+	}
+
+	m := newManager(root, cfg.Retention{MaxSize: 1})
+	if err := m.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2000KB -> 1600 -> 1200 -> 800KB 에서 중단
+	want := []string{"2023-11-08/1300-1315", "2023-11-08/1315-1330"}
+	if got := windows(t, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("windows = %v, want %v", got, want)
+	}
+	if m.deleted != 3 {
+		t.Errorf("deleted = %v, want 3", m.deleted)
+	}
+}
+
+func TestRollup(t *testing.T) {
+	root := t.TempDir()
+	backup(t, root, "2023-11-07", "2300-2315", family("ne101,AMF_01,10"))
+	backup(t, root, "2023-11-07", "2315-2330", family("ne101,AMF_01,20\nne102,AMF_02,5"))
+	backup(t, root, "2023-11-08", "1300-1315", family("ne101,AMF_01,30"))
+	backup(t, root, "2023-11-08", "1315-1330", family("ne101,AMF_01,40"))
+
+	m := newManager(root, cfg.Retention{Rollup: true, Compression: "gzip"})
+	if err := m.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := csv.LoadCsv(filepath.Join(root, ArchiveDir, "2023-11-07", "AMFTPS.csv.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Family name : AMFTPS"},
+		{"Period : 15min"},
+		{"NE ID", "LOCATION", "TotalMsg(count)"},
+		{"ne101", "AMF_01", "10"},
+		{"ne101", "AMF_01", "20"},
+		{"ne102", "AMF_02", "5"},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("rollup = %v, want %v", data, want)
+	}
+	// 오늘 날짜는 아카이브하지 않는다.
+	if _, err := os.Stat(filepath.Join(root, ArchiveDir, "2023-11-08")); !os.IsNotExist(err) {
+		t.Errorf("today is archived: %v", err)
+	}
+	if m.rollups != 1 {
+		t.Errorf("rollups = %v, want 1", m.rollups)
+	}
+}
+
+// 보호 구간이 포함된 날짜는 아카이브하지 않는다.
+func TestRollupSkipsProtectedDay(t *testing.T) {
+	root := t.TempDir()
+	backup(t, root, "2023-11-07", "2330-2345", family("ne101,AMF_01,10"))
+	backup(t, root, "2023-11-07", "2345-0000", family("ne101,AMF_01,20"))
+
+	m := newManager(root, cfg.Retention{Rollup: true})
+	if err := m.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, ArchiveDir, "2023-11-07")); !os.IsNotExist(err) {
+		t.Errorf("protected day is archived: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package sink
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"sync"
+	"time"
+)
+
+// Cache 마지막 Gather 결과를 보관하는 Gatherer
+// /metrics 와 sink 가 같이 사용하여, scrape 로 수집한 결과가 있으면 sink 가 OSS 수집과 백업을 다시 실행하지 않는다.
+type Cache struct {
+	gatherer prometheus.Gatherer
+	now      func() time.Time
+
+	// 수집중에는 잠겨 있어 동시에 들어온 sink 는 진행중인 수집 결과를 사용한다.
+	mu       sync.Mutex
+	families []*dto.MetricFamily
+	gathered time.Time
+}
+
+func NewCache(gatherer prometheus.Gatherer) *Cache {
+	return &Cache{gatherer: gatherer, now: time.Now}
+}
+
+// Gather 수집 후 결과 보관 (/metrics scrape)
+func (c *Cache) Gather() ([]*dto.MetricFamily, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gather()
+}
+
+func (c *Cache) gather() ([]*dto.MetricFamily, error) {
+	families, err := c.gatherer.Gather()
+	// 일부 메트릭 오류가 있어도 나머지 결과는 보관한다.
+	if len(families) > 0 {
+		c.families = families
+		c.gathered = c.now()
+	}
+	return families, err
+}
+
+// Recent maxAge 이내에 수집한 결과가 있으면 그대로, 없으면(scrape 가 없는 사이트) 직접 수집하는 Gatherer
+func (c *Cache) Recent(maxAge time.Duration) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.families != nil && c.now().Sub(c.gathered) < maxAge {
+			return c.families, nil
+		}
+		return c.gather()
+	})
+}
@@ -0,0 +1,95 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package sink
+
+import (
+	"crypto/tls"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupFunc 메트릭 이름으로 pushgateway grouping key(family) 값을 정한다.
+type GroupFunc func(metricName string) string
+
+// Pushgateway grouping key 별로 나누어 PUT 전송 (같은 group 의 이전 값은 교체)
+type Pushgateway struct {
+	config cfg.Pushgateway
+	group  GroupFunc
+	client *http.Client
+}
+
+func NewPushgateway(config cfg.Pushgateway, group GroupFunc) *Pushgateway {
+	if config.Job == "" {
+		config.Job = "cnf_exporter"
+	}
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Pushgateway{
+		config: config,
+		group:  group,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+			},
+			Timeout: timeout,
+		},
+	}
+}
+
+func (p *Pushgateway) Name() string {
+	return "pushgateway"
+}
+
+// Write group 별로 push, group 이 없으면 path 를 grouping key 로 사용
+func (p *Pushgateway) Write(path string, families []*dto.MetricFamily) error {
+	groups := map[string][]*dto.MetricFamily{}
+	for _, family := range families {
+		group := ""
+		if p.group != nil {
+			group = p.group(family.GetName())
+		}
+		groups[group] = append(groups[group], family)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lastErr error
+	for _, name := range names {
+		list := groups[name]
+		pusher := push.New(p.config.Url, p.config.Job).
+			Client(p.client).
+			Grouping("path", strings.ReplaceAll(path, "/", "_")).
+			Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return list, nil }))
+		if name != "" {
+			pusher = pusher.Grouping("family", name)
+		}
+		if p.config.Username != "" {
+			pusher = pusher.BasicAuth(p.config.Username, p.config.Password)
+		}
+		if err := pusher.Push(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
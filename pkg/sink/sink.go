@@ -0,0 +1,117 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package sink
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/health"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/lifecycle"
+	"strings"
+	"time"
+)
+
+// Output 수집한 메트릭을 내보내는 대상 (pushgateway, textfile)
+type Output interface {
+	Name() string
+	Write(path string, families []*dto.MetricFamily) error
+}
+
+// Scheduler scrape 없이 INTERVAL 마다 registry 를 수집하여 Output 으로 내보낸다.
+// 방화벽으로 scrape 가 불가능한 사이트용이며, 한번 수집한 결과를 모든 Output 에 같이 쓴다.
+type Scheduler struct {
+	ctx     context.Context
+	config  cfg.Sink
+	outputs map[string][]Output
+}
+
+// NewScheduler ctx 가 취소되면 주기 실행을 멈춘다.
+func NewScheduler(ctx context.Context, config cfg.Sink) *Scheduler {
+	return &Scheduler{ctx: ctx, config: config, outputs: map[string][]Output{}}
+}
+
+// Add PUSHGATEWAY_PATHS, TEXTFILE_PATHS 에 설정된 경로면 Output 을 연결하고 연결 여부 반환
+func (s *Scheduler) Add(path string, group GroupFunc) bool {
+	path = strings.TrimPrefix(path, "/")
+	if contains(s.config.Pushgateway.Paths, path) {
+		s.outputs[path] = append(s.outputs[path], NewPushgateway(s.config.Pushgateway, group))
+	}
+	if contains(s.config.Textfile.Paths, path) {
+		s.outputs[path] = append(s.outputs[path], NewTextfile(s.config.Textfile.Dir))
+	}
+	return len(s.outputs[path]) > 0
+}
+
+// Interval 내보내기 주기 (INTERVAL 초, 기본 5분)
+func (s *Scheduler) Interval() time.Duration {
+	interval := time.Duration(s.config.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return interval
+}
+
+// Start 경로별로 INTERVAL(초) 마다 수집 후 내보내기
+func (s *Scheduler) Start(path string, gatherer prometheus.Gatherer) {
+	path = strings.TrimPrefix(path, "/")
+	outputs := s.outputs[path]
+	if len(outputs) == 0 {
+		return
+	}
+	interval := s.Interval()
+
+	go func() {
+		defer health.Stop("sink:" + path)
+		for {
+			health.Beat("sink:"+path, interval)
+			s.run(path, gatherer, outputs)
+			if !lifecycle.Sleep(s.ctx, interval) {
+				return
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) run(path string, gatherer prometheus.Gatherer, outputs []Output) {
+	families, err := gatherer.Gather()
+	// 수집 도중 종료가 시작되면 불완전한 결과로 덮어쓰지 않는다.
+	if s.ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		// 일부 메트릭 오류는 기록하고 나머지는 내보낸다.
+		logger.LogErr("sink gather error : "+path, err)
+	}
+	if len(families) == 0 {
+		return
+	}
+	for _, output := range outputs {
+		if err := output.Write(path, families); err != nil {
+			logger.LogErr(output.Name()+" sink failed : "+path, err)
+			continue
+		}
+		logger.LogInfo("sink write", zap.String("output", output.Name()), zap.String("path", path), zap.Int("families", len(families)))
+	}
+}
+
+func contains(paths []string, path string) bool {
+	for _, p := range paths {
+		if strings.TrimPrefix(p, "/") == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,308 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package sink
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/cfg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// families AMFTPS, UECON_AMF gauge 와 exporter 자체 메트릭
+func families(t *testing.T, value float64) []*dto.MetricFamily {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"p5g_exporter_amftps_total_msg", "p5g_exporter_uecon_attempt", "cnf_exporter_build_info"} {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: name})
+		gauge.Set(value)
+		registry.MustRegister(gauge)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return families
+}
+
+func group(name string) string {
+	switch {
+	case strings.Contains(name, "amftps"):
+		return "AMFTPS"
+	case strings.Contains(name, "uecon"):
+		return "UECON_AMF"
+	}
+	return "exporter"
+}
+
+func TestFileName(t *testing.T) {
+	tests := map[string]string{
+		"metrics":       "cnf_exporter_metrics.prom",
+		"/cpu/metrics":  "cnf_exporter_cpu_metrics.prom",
+		"kube/metrics/": "cnf_exporter_kube_metrics.prom",
+	}
+	for path, want := range tests {
+		if got := FileName(path); got != want {
+			t.Errorf("FileName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestTextfileWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "textfile")
+	textfile := NewTextfile(dir)
+
+	for _, value := range []float64{1, 2} {
+		if err := textfile.Write("cpu/metrics", families(t, value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 임시파일 없이 .prom 파일 하나만 남고 마지막 값으로 교체
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "cnf_exporter_cpu_metrics.prom" {
+		t.Fatalf("files = %v", entries)
+	}
+	info, err := entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	file, err := os.Open(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parsed, err := new(expfmt.TextParser).TextToMetricFamilies(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 3 {
+		t.Fatalf("families = %d, want 3", len(parsed))
+	}
+	if got := parsed["p5g_exporter_amftps_total_msg"].GetMetric()[0].GetGauge().GetValue(); got != 2 {
+		t.Errorf("value = %v, want 2", got)
+	}
+}
+
+// 쓰기에 실패하면 기존 파일을 그대로 두고 임시파일을 남기지 않는다.
+func TestTextfileWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	textfile := NewTextfile(dir)
+	if err := textfile.Write("metrics", families(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(dir, FileName("metrics")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 이름이 없는 family 는 text 변환에서 실패
+	invalid := []*dto.MetricFamily{{Name: nil}}
+	if err := textfile.Write("metrics", invalid); err == nil {
+		t.Fatal("Write succeeded")
+	}
+
+	after, err := os.ReadFile(filepath.Join(dir, FileName("metrics")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("file is changed by a failed write")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("leftover files = %v", entries)
+	}
+}
+
+type pushed struct {
+	method   string
+	grouping map[string]string
+	names    []string
+	auth     string
+}
+
+func TestPushgatewayWrite(t *testing.T) {
+	var mu sync.Mutex
+	var requests []pushed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /metrics/job/<job>/<label>/<value>...
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/metrics/"), "/")
+		grouping := map[string]string{}
+		for i := 0; i+1 < len(parts); i += 2 {
+			grouping[parts[i]] = parts[i+1]
+		}
+		var names []string
+		decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			family := &dto.MetricFamily{}
+			if err := decoder.Decode(family); err != nil {
+				if err != io.EOF {
+					t.Error(err)
+				}
+				break
+			}
+			names = append(names, family.GetName())
+		}
+		user, password, _ := r.BasicAuth()
+
+		mu.Lock()
+		requests = append(requests, pushed{method: r.Method, grouping: grouping, names: names, auth: user + ":" + password})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	pushgateway := NewPushgateway(cfg.Pushgateway{Url: server.URL, Username: "user", Password: "secret"}, group)
+	if err := pushgateway.Write("cpu/metrics", families(t, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// family group 별로 한번씩, 이름순으로 PUT
+	want := []pushed{
+		{method: http.MethodPut, grouping: map[string]string{"job": "cnf_exporter", "path": "cpu_metrics", "family": "AMFTPS"}, names: []string{"p5g_exporter_amftps_total_msg"}, auth: "user:secret"},
+		{method: http.MethodPut, grouping: map[string]string{"job": "cnf_exporter", "path": "cpu_metrics", "family": "UECON_AMF"}, names: []string{"p5g_exporter_uecon_attempt"}, auth: "user:secret"},
+		{method: http.MethodPut, grouping: map[string]string{"job": "cnf_exporter", "path": "cpu_metrics", "family": "exporter"}, names: []string{"cnf_exporter_build_info"}, auth: "user:secret"},
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].grouping["family"] < requests[j].grouping["family"] })
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %+v, want %+v", requests, want)
+	}
+}
+
+func TestPushgatewayWriteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	pushgateway := NewPushgateway(cfg.Pushgateway{Url: server.URL}, nil)
+	if err := pushgateway.Write("metrics", families(t, 1)); err == nil {
+		t.Fatal("Write succeeded on 503")
+	}
+}
+
+type countingGatherer struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (g *countingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls++
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "p5g_exporter_amftps_total_msg", Help: "total"})
+	gauge.Set(float64(g.calls))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(gauge)
+	return registry.Gather()
+}
+
+func value(t *testing.T, gatherer prometheus.Gatherer) float64 {
+	t.Helper()
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return families[0].GetMetric()[0].GetGauge().GetValue()
+}
+
+// scrape 로 수집한 결과가 maxAge 이내면 sink 는 다시 수집하지 않는다.
+func TestCacheRecent(t *testing.T) {
+	now := time.Date(2023, 11, 8, 13, 31, 0, 0, time.Local)
+	source := &countingGatherer{}
+	cache := NewCache(source)
+	cache.now = func() time.Time { return now }
+	recent := cache.Recent(5 * time.Minute)
+
+	// 수집 결과가 없으면 직접 수집
+	if got := value(t, recent); got != 1 {
+		t.Fatalf("first = %v, want 1", got)
+	}
+	// scrape
+	if got := value(t, cache); got != 2 {
+		t.Fatalf("scrape = %v, want 2", got)
+	}
+	now = now.Add(4 * time.Minute)
+	if got := value(t, recent); got != 2 {
+		t.Errorf("recent = %v, want cached 2", got)
+	}
+	now = now.Add(time.Minute)
+	if got := value(t, recent); got != 3 {
+		t.Errorf("expired = %v, want 3", got)
+	}
+	if source.calls != 3 {
+		t.Errorf("gathers = %d, want 3", source.calls)
+	}
+}
+
+type recordOutput struct {
+	mu     sync.Mutex
+	writes int
+	done   chan struct{}
+}
+
+func (o *recordOutput) Name() string {
+	return "record"
+}
+
+func (o *recordOutput) Write(path string, families []*dto.MetricFamily) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.writes++
+	if o.writes == 1 {
+		close(o.done)
+	}
+	return nil
+}
+
+// 종료가 시작되면 수집한 결과를 내보내지 않는다.
+func TestSchedulerRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler := NewScheduler(ctx, cfg.Sink{Interval: 3600})
+	output := &recordOutput{done: make(chan struct{})}
+	scheduler.outputs["metrics"] = []Output{output}
+
+	scheduler.Start("/metrics", &countingGatherer{})
+	select {
+	case <-output.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sink did not write")
+	}
+
+	cancel()
+	scheduler.run("metrics", &countingGatherer{}, []Output{output})
+	output.mu.Lock()
+	defer output.mu.Unlock()
+	if output.writes != 1 {
+		t.Errorf("writes = %d, want 1", output.writes)
+	}
+}
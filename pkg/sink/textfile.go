@@ -0,0 +1,74 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package sink
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Textfile node_exporter textfile collector 용 .prom 파일 작성
+// 같은 폴더의 임시파일에 쓰고 rename 하여 node_exporter 가 쓰는 도중의 파일을 읽지 않도록 한다.
+type Textfile struct {
+	dir string
+}
+
+func NewTextfile(dir string) *Textfile {
+	return &Textfile{dir: dir}
+}
+
+func (t *Textfile) Name() string {
+	return "textfile"
+}
+
+// FileName 경로별 파일명 ("cpu/metrics" -> cnf_exporter_cpu_metrics.prom)
+func FileName(path string) string {
+	return "cnf_exporter_" + strings.ReplaceAll(strings.Trim(path, "/"), "/", "_") + ".prom"
+}
+
+func (t *Textfile) Write(path string, families []*dto.MetricFamily) error {
+	buf := &bytes.Buffer{}
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(buf, family); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return errors.Cause(err)
+	}
+	target := filepath.Join(t.dir, FileName(path))
+	// node_exporter 는 *.prom 만 읽으므로 임시파일은 다른 확장자로 둔다.
+	tmp, err := os.CreateTemp(t.dir, "."+FileName(path)+".*.tmp")
+	if err != nil {
+		return errors.Cause(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return errors.Cause(err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return errors.Cause(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Cause(err)
+	}
+	return errors.Cause(os.Rename(tmp.Name(), target))
+}
@@ -0,0 +1,237 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// API_PATH 아래 구조
+//
+//	current            -> snapshots/<id> (symlink, 지원하지 않으면 대상 경로를 담은 파일)
+//	snapshots/<id>/    Family CSV 와 meta.json
+const (
+	CurrentName  = "current"
+	SnapshotsDir = "snapshots"
+	MetaName     = "meta.json"
+)
+
+// Keep 보관할 snapshot 수
+const Keep = 3
+
+// 교체된 snapshot 은 이 시간이 지난 뒤 삭제 (교체 전에 열어둔 요청이 끝날 때까지 남겨둔다)
+const grace = time.Minute
+
+// Period snapshot 의 OSS 수집 기간과 게시 시간 (OSS 요청과 같은 "2006-01-02 15:04:05" 형식)
+type Period struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Published string `json:"published"`
+}
+
+// Meta snapshot 폴더의 meta.json
+type Meta struct {
+	Period
+	Families []string `json:"families"`
+}
+
+// Snapshot 요청 하나가 읽을 폴더, 이전 구조(API_PATH 에 바로 CSV)면 Meta 가 nil
+type Snapshot struct {
+	Dir  string
+	Meta *Meta
+}
+
+// Period 수집 기간 (이전 구조면 nil)
+func (s *Snapshot) Period() *Period {
+	if s.Meta == nil {
+		return nil
+	}
+	period := s.Meta.Period
+	return &period
+}
+
+// Publish srcDir 의 names(.csv) 를 새 snapshot 폴더에 복사한 뒤 current 를 한번에 교체한다.
+// 파일이 하나라도 없으면 게시하지 않고 이전 snapshot 을 유지한다.
+func Publish(apiPath, srcDir string, names []string, period Period) (*Snapshot, error) {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(srcDir, name+".csv")); err != nil {
+			return nil, fmt.Errorf("snapshot incomplete, keeping previous: %v", err)
+		}
+	}
+	root := filepath.Join(apiPath, SnapshotsDir)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(root, ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, name := range names {
+		if err := copyFile(filepath.Join(srcDir, name+".csv"), filepath.Join(tmp, name+".csv")); err != nil {
+			return nil, err
+		}
+	}
+	published := utils.Now()
+	period.Published = published.Format("2006-01-02 15:04:05")
+	meta := &Meta{Period: period, Families: names}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, MetaName), data, 0644); err != nil {
+		return nil, err
+	}
+
+	// 이름 순서가 게시 순서
+	id := published.Format("20060102T150405.000000000")
+	for i := 1; exists(filepath.Join(root, id)); i++ {
+		id = fmt.Sprintf("%s-%d", published.Format("20060102T150405.000000000"), i)
+	}
+	dir := filepath.Join(root, id)
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, err
+	}
+	if err := swap(apiPath, filepath.Join(SnapshotsDir, id)); err != nil {
+		return nil, err
+	}
+	prune(root, id)
+	return &Snapshot{Dir: dir, Meta: meta}, nil
+}
+
+// Open 현재 snapshot, current 가 없으면 API_PATH 를 그대로 사용 (이전 구조)
+func Open(apiPath string) (*Snapshot, error) {
+	target, err := current(apiPath)
+	if os.IsNotExist(err) {
+		return &Snapshot{Dir: apiPath}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dir := target
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(apiPath, dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, MetaName))
+	if err != nil {
+		return nil, err
+	}
+	meta := &Meta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("%s: %v", filepath.Join(dir, MetaName), err)
+	}
+	return &Snapshot{Dir: dir, Meta: meta}, nil
+}
+
+// IsSnapshot dir 이 게시된 snapshot 폴더인지 (Open 이 반환한 Dir 이 이전 구조의 API_PATH 면 false)
+func IsSnapshot(dir string) bool {
+	return exists(filepath.Join(dir, MetaName))
+}
+
+// current current 가 가리키는 snapshot 경로
+func current(apiPath string) (string, error) {
+	path := filepath.Join(apiPath, CurrentName)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return os.Readlink(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// swap 임시 symlink 를 만들어 current 위로 rename (symlink 를 만들 수 없으면 경로를 담은 파일)
+func swap(apiPath, target string) error {
+	tmp := filepath.Join(apiPath, fmt.Sprintf(".%s.tmp-%d", CurrentName, time.Now().UnixNano()))
+	if err := os.Symlink(target, tmp); err != nil {
+		if err := os.WriteFile(tmp, []byte(target+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, filepath.Join(apiPath, CurrentName)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// prune 최근 Keep 개와 교체된 지 grace 가 지나지 않은 snapshot 을 제외하고 삭제, 남은 임시 폴더 삭제
+func prune(root, current string) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			// 중단된 게시 (게시는 사이트별 한 곳에서만 실행)
+			_ = os.RemoveAll(filepath.Join(root, entry.Name()))
+			continue
+		}
+		ids = append(ids, entry.Name())
+	}
+	sort.Strings(ids)
+	for i := 0; i < len(ids)-Keep; i++ {
+		if ids[i] == current {
+			continue
+		}
+		// 다음 snapshot 이 게시된 시간 = 이 snapshot 이 교체된 시간
+		next, err := os.Stat(filepath.Join(root, ids[i+1]))
+		if err != nil || time.Since(next.ModTime()) < grace {
+			continue
+		}
+		_ = os.RemoveAll(filepath.Join(root, ids[i]))
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,157 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	segmentLayout = "2006-01-02"
+	segmentExt    = ".jsonl"
+)
+
+// line 세그먼트 파일 한 줄. 수집 배치마다 Header 줄을 먼저 쓰고 행을 이어서 쓴다.
+type line struct {
+	Header    [][]string `json:"header,omitempty"`
+	Collected int64      `json:"collected"`
+	Period    int64      `json:"period,omitempty"`
+	Row       []string   `json:"row,omitempty"`
+}
+
+// 세그먼트 파일 경로 (STORE_PATH/Family/YYYY-MM-DD.jsonl)
+func segmentPath(dir, family string, collected time.Time) string {
+	return filepath.Join(dir, family, collected.Format(segmentLayout)+segmentExt)
+}
+
+// appendSegment 배치 하나를 세그먼트 파일 끝에 추가하고 fsync
+// 데이터가 없는 배치도 Header 줄은 기록하여 "마지막 수집에 데이터 없음"을 복구할 수 있게 한다.
+func appendSegment(path string, header [][]string, collected time.Time, records []Record) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Cause(err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Cause(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	// 비정상 종료로 마지막 줄이 잘려 있으면 새 줄에서 시작해 다음 Header 줄이 잘린 줄에 붙지 않게 한다.
+	if truncated, err := endsWithoutNewline(file); err != nil {
+		return err
+	} else if truncated {
+		if err := writer.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	encoder := json.NewEncoder(writer)
+	if err := encoder.Encode(line{Header: header, Collected: collected.Unix()}); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := encoder.Encode(line{
+			Collected: record.Collected.Unix(),
+			Period:    record.Period.Unix(),
+			Row:       record.Row,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return errors.Cause(err)
+	}
+	return file.Sync()
+}
+
+// 파일이 비어있지 않고 마지막 바이트가 줄바꿈이 아니면 true
+func endsWithoutNewline(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, errors.Cause(err)
+	}
+	if info.Size() == 0 {
+		return false, nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return false, errors.Cause(err)
+	}
+	return last[0] != '\n', nil
+}
+
+// readSegment 세그먼트 파일을 읽어 header, 배치 시간, record 를 복구한다.
+// 비정상 종료로 마지막 줄이 잘린 경우 해당 줄만 버린다.
+func readSegment(path string) ([][]string, []time.Time, []Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, errors.Cause(err)
+	}
+	defer file.Close()
+
+	var header [][]string
+	var batches []time.Time
+	var records []Record
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var l line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			logger.LogWarn("store segment line is corrupted, skip : " + path)
+			continue
+		}
+		if l.Header != nil {
+			header = l.Header
+			batches = append(batches, time.Unix(l.Collected, 0))
+			continue
+		}
+		records = append(records, Record{
+			Collected: time.Unix(l.Collected, 0),
+			Period:    time.Unix(l.Period, 0),
+			Row:       l.Row,
+		})
+	}
+	return header, batches, records, scanner.Err()
+}
+
+// 세그먼트 파일 목록 (날짜 순)
+func listSegments(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []string
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), segmentExt) {
+			continue
+		}
+		segments = append(segments, filepath.Join(dir, file.Name()))
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// 세그먼트 파일명의 날짜
+func segmentDay(path string) (time.Time, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), segmentExt)
+	day, err := time.ParseInLocation(segmentLayout, name, time.Local)
+	return day, err == nil
+}
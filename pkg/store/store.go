@@ -0,0 +1,238 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package store
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/pkg/csv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 저장된 Family 데이터가 없음
+var ErrNotFound = errors.New("family is not found in store")
+
+// Record CSV 데이터 행 하나와 수집시간, period 시간
+type Record struct {
+	Collected time.Time
+	Period    time.Time
+	Row       []string
+}
+
+type series struct {
+	header  [][]string
+	batches []time.Time
+	records []Record
+}
+
+// Store Family 별 append-only 세그먼트 파일(STORE_PATH/Family/YYYY-MM-DD.jsonl)에
+// OSS CSV 행을 보관하고, 재시작시 세그먼트를 다시 읽어 복구한다.
+type Store struct {
+	dir       string
+	retention time.Duration
+
+	mu       sync.RWMutex
+	families map[string]*series
+}
+
+// Open 저장소를 열고 보관기간 내의 세그먼트를 메모리로 복구
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		dir:       dir,
+		retention: retention,
+	}
+	families, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.families = families
+	logger.LogInfo("store recovered", zap.String("path", dir), zap.Int("families", len(s.families)))
+	return s, nil
+}
+
+// Reload 다른 프로세스(leader)가 기록한 세그먼트를 다시 읽어 메모리 데이터 교체
+func (s *Store) Reload() error {
+	families, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.families = families
+	s.mu.Unlock()
+	return nil
+}
+
+// load 보관기간 내의 세그먼트를 Family 별로 읽는다.
+func (s *Store) load() (map[string]*series, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	families := map[string]*series{}
+	cutoff := time.Now().Add(-s.retention)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		segments, err := listSegments(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			logger.LogErr("store segment list failed", err)
+			continue
+		}
+		sr := &series{}
+		for _, segment := range segments {
+			header, batches, records, err := readSegment(segment)
+			if err != nil {
+				logger.LogErr("store segment read failed", err)
+			}
+			if header != nil {
+				sr.header = header
+			}
+			for _, batch := range batches {
+				if batch.After(cutoff) {
+					sr.batches = append(sr.batches, batch)
+				}
+			}
+			for _, record := range records {
+				if record.Collected.After(cutoff) {
+					sr.records = append(sr.records, record)
+				}
+			}
+		}
+		if sr.header != nil {
+			families[entry.Name()] = sr
+		}
+	}
+	return families, nil
+}
+
+// Family 이름의 공백은 파일명과 같이 '_' 로 변환
+func normalize(family string) string {
+	return strings.ReplaceAll(family, " ", "_")
+}
+
+// Append 수집한 CSV 데이터(header 3줄 + 데이터 행)를 저장
+func (s *Store) Append(family string, data [][]string, collected time.Time) error {
+	if len(data) < 3 {
+		return fmt.Errorf("%s: csv header is missing", family)
+	}
+	family = normalize(family)
+
+	records := make([]Record, 0, len(data)-3)
+	for _, row := range data[3:] {
+		records = append(records, Record{
+			Collected: collected,
+			Period:    csv.ParsePeriod(row, collected),
+			Row:       row,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := appendSegment(segmentPath(s.dir, family, collected), data[:3], collected, records); err != nil {
+		return err
+	}
+
+	sr, ok := s.families[family]
+	if !ok {
+		sr = &series{}
+		s.families[family] = sr
+	}
+	sr.header = data[:3]
+	sr.batches = append(sr.batches, collected)
+	sr.records = append(sr.records, records...)
+
+	s.prune(sr, family, collected)
+	return nil
+}
+
+// 보관기간이 지난 record 와 세그먼트 파일 삭제
+func (s *Store) prune(sr *series, family string, now time.Time) {
+	cutoff := now.Add(-s.retention)
+
+	i := 0
+	for i < len(sr.records) && !sr.records[i].Collected.After(cutoff) {
+		i++
+	}
+	sr.records = sr.records[i:]
+
+	j := 0
+	for j < len(sr.batches) && !sr.batches[j].After(cutoff) {
+		j++
+	}
+	sr.batches = sr.batches[j:]
+
+	segments, err := listSegments(filepath.Join(s.dir, family))
+	if err != nil {
+		return
+	}
+	for _, segment := range segments {
+		day, ok := segmentDay(segment)
+		// 세그먼트는 하루 단위이므로 해당일 종료 시점이 cutoff 이전일 때 삭제
+		if ok && day.Add(24*time.Hour).Before(cutoff) {
+			_ = os.Remove(segment)
+		}
+	}
+}
+
+// Latest 마지막 수집 배치를 CSV 형식(header 3줄 + 데이터 행)으로 반환
+func (s *Store) Latest(family string) ([][]string, error) {
+	return s.batch(family, 1)
+}
+
+// Previous 마지막 직전 수집 배치를 CSV 형식으로 반환
+func (s *Store) Previous(family string) ([][]string, error) {
+	return s.batch(family, 2)
+}
+
+// 뒤에서 n 번째 배치
+func (s *Store) batch(family string, n int) ([][]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sr, ok := s.families[normalize(family)]
+	if !ok || len(sr.batches) < n {
+		return nil, fmt.Errorf("%s: %w", family, ErrNotFound)
+	}
+	collected := sr.batches[len(sr.batches)-n]
+
+	data := append([][]string{}, sr.header...)
+	for _, record := range sr.records {
+		if record.Collected.Equal(collected) {
+			data = append(data, record.Row)
+		}
+	}
+	return data, nil
+}
+
+// Families 저장된 Family 목록
+func (s *Store) Families() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var names []string
+	for name := range s.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,210 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var header = [][]string{
+	{"Family name : AMFTPS"},
+	{"Period : 15min"},
+	{"NE ID", "SYSTEM ID", "NE NAME", "INIT TIME", "TIME OFFSET", "GRAN PERIOD", "LOCATION", "TotalMsg(count)"},
+}
+
+func batch(rows ...string) [][]string {
+	data := append([][]string{}, header...)
+	for _, value := range rows {
+		data = append(data, []string{"ne101", "1", "NE-101", "2023-11-08 13:15:00", "+09:00", "900", "AMF_01", value})
+	}
+	return data
+}
+
+func open(t *testing.T, dir string) *Store {
+	t.Helper()
+	s, err := Open(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func appendBatch(t *testing.T, s *Store, data [][]string, collected time.Time) {
+	t.Helper()
+	if err := s.Append("AMFTPS", data, collected); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertBatch(t *testing.T, name string, got [][]string, err error, want [][]string) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestAppendLatestPrevious(t *testing.T) {
+	s := open(t, t.TempDir())
+	now := time.Now().Truncate(time.Second)
+
+	if _, err := s.Latest("AMFTPS"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Latest(empty) err = %v, want ErrNotFound", err)
+	}
+
+	appendBatch(t, s, batch("10", "20"), now.Add(-30*time.Minute))
+	if _, err := s.Previous("AMFTPS"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Previous(one batch) err = %v, want ErrNotFound", err)
+	}
+	appendBatch(t, s, batch("30"), now.Add(-15*time.Minute))
+
+	latest, err := s.Latest("AMFTPS")
+	assertBatch(t, "Latest", latest, err, batch("30"))
+	previous, err := s.Previous("AMFTPS")
+	assertBatch(t, "Previous", previous, err, batch("10", "20"))
+
+	// 데이터가 없는 배치도 마지막 수집으로 본다.
+	appendBatch(t, s, batch(), now)
+	latest, err = s.Latest("AMFTPS")
+	assertBatch(t, "Latest(empty batch)", latest, err, batch())
+
+	// 공백이 포함된 Family 이름은 '_' 로 조회
+	if err := s.Append("Air MAC Packet", batch("1"), now); err != nil {
+		t.Fatal(err)
+	}
+	latest, err = s.Latest("Air_MAC_Packet")
+	assertBatch(t, "Latest(Air MAC Packet)", latest, err, batch("1"))
+	if got, want := s.Families(), []string{"AMFTPS", "Air_MAC_Packet"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Families = %v, want %v", got, want)
+	}
+
+	if err := s.Append("AMFTPS", header[:2], now); err == nil {
+		t.Error("Append without header succeeded")
+	}
+}
+
+func TestReopenAndReload(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+
+	s := open(t, dir)
+	appendBatch(t, s, batch("10"), now.Add(-15*time.Minute))
+	appendBatch(t, s, batch("20"), now)
+
+	// 재시작 후 세그먼트에서 복구
+	restarted := open(t, dir)
+	latest, err := restarted.Latest("AMFTPS")
+	assertBatch(t, "Latest", latest, err, batch("20"))
+	previous, err := restarted.Previous("AMFTPS")
+	assertBatch(t, "Previous", previous, err, batch("10"))
+
+	// 다른 프로세스(leader)가 추가한 배치를 Reload 로 반영
+	follower := open(t, dir)
+	appendBatch(t, s, batch("30"), now.Add(time.Second))
+	latest, _ = follower.Latest("AMFTPS")
+	if reflect.DeepEqual(latest, batch("30")) {
+		t.Fatal("follower sees the new batch before Reload")
+	}
+	if err := follower.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	latest, err = follower.Latest("AMFTPS")
+	assertBatch(t, "Latest(reload)", latest, err, batch("30"))
+}
+
+// 비정상 종료로 마지막 줄이 잘린 세그먼트는 해당 줄만 버리고 열린다.
+func TestOpenTruncatedSegment(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+
+	s := open(t, dir)
+	appendBatch(t, s, batch("10"), now.Add(-15*time.Minute))
+	appendBatch(t, s, batch("20", "30"), now)
+
+	path := segmentPath(dir, "AMFTPS", now)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 마지막 행 "30" 의 중간에서 잘림
+	if err := os.WriteFile(path, content[:len(content)-10], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := open(t, dir)
+	latest, err := restarted.Latest("AMFTPS")
+	assertBatch(t, "Latest", latest, err, batch("20"))
+
+	// 잘린 줄 뒤에 이어쓴 배치도 다시 읽을 수 있어야 한다.
+	appendBatch(t, restarted, batch("40"), now.Add(time.Second))
+	if err := restarted.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	latest, err = restarted.Latest("AMFTPS")
+	assertBatch(t, "Latest(after append)", latest, err, batch("40"))
+	previous, err := restarted.Previous("AMFTPS")
+	assertBatch(t, "Previous(after append)", previous, err, batch("20"))
+}
+
+// 세그먼트는 해당일이 끝난 시점이 보관기간을 지나야 삭제된다.
+func TestPruneDayBoundary(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir)
+	day := func(value string) time.Time {
+		t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, "AMFTPS", name+segmentExt))
+		return err == nil
+	}
+
+	appendBatch(t, s, batch("10"), day("2023-11-07 10:00"))
+	appendBatch(t, s, batch("20"), day("2023-11-08 00:05"))
+
+	// cutoff 2023-11-07 23:59 : 11-07 세그먼트는 남고 10:00 배치는 메모리에서 제거
+	appendBatch(t, s, batch("30"), day("2023-11-08 23:59"))
+	if !exists("2023-11-07") || !exists("2023-11-08") {
+		t.Fatal("segment is removed before the day ends past the retention")
+	}
+	previous, err := s.Previous("AMFTPS")
+	assertBatch(t, "Previous", previous, err, batch("20"))
+	if len(s.families["AMFTPS"].batches) != 2 {
+		t.Errorf("batches = %v, want 2", s.families["AMFTPS"].batches)
+	}
+
+	// cutoff 2023-11-08 00:15 : 11-07 세그먼트 삭제, 00:05 배치 제거
+	appendBatch(t, s, batch("40"), day("2023-11-09 00:15"))
+	if exists("2023-11-07") {
+		t.Error("2023-11-07 segment is not removed")
+	}
+	if !exists("2023-11-08") || !exists("2023-11-09") {
+		t.Error("recent segments are removed")
+	}
+	latest, err := s.Latest("AMFTPS")
+	assertBatch(t, "Latest", latest, err, batch("40"))
+	previous, err = s.Previous("AMFTPS")
+	assertBatch(t, "Previous", previous, err, batch("30"))
+	if len(s.families["AMFTPS"].records) != 2 {
+		t.Errorf("records = %d, want 2", len(s.families["AMFTPS"].records))
+	}
+}
@@ -22,11 +22,15 @@ import (
 	"time"
 )
 
+// Now 수집 기간 계산에 사용하는 현재 시간 (테스트에서 고정)
+var Now = time.Now
+
 // Startime과 Endtime을 반환한다.
 func IntervalTime() (start string, end string) {
 	//15분전 ~ 현재시간 조회 설정
-	startime := time.Now().Add(-16 * time.Minute)
-	endtime := time.Now().Add(1 * time.Minute)
+	now := Now()
+	startime := now.Add(-16 * time.Minute)
+	endtime := now.Add(1 * time.Minute)
 	// 초 부분을 00으로 설정
 	startime = time.Date(startime.Year(), startime.Month(), startime.Day(), startime.Hour(), startime.Minute(), 0, 0, startime.Location())
 	endtime = time.Date(endtime.Year(), endtime.Month(), endtime.Day(), endtime.Hour(), endtime.Minute(), 0, 0, endtime.Location())
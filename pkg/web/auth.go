@@ -0,0 +1,102 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package web
+
+import (
+	"crypto/sha256"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// 없는 사용자도 같은 시간이 걸리도록 비교하는 hash
+const dummyHash = "$2a$10$CftF1ZV4iYFvEYk9ymgEEe7h/X/GUqVqHKOBaRONdvKnbpugTR6y."
+
+// bcrypt 비교 결과 캐시 (요청마다 bcrypt 를 계산하지 않도록)
+var (
+	cacheMu sync.Mutex
+	cache   = map[[32]byte]bool{}
+)
+
+func compare(hash, secret string) bool {
+	key := sha256.Sum256([]byte(hash + "\x00" + secret))
+	cacheMu.Lock()
+	ok, found := cache[key]
+	cacheMu.Unlock()
+	if found {
+		return ok
+	}
+
+	ok = bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+	cacheMu.Lock()
+	if len(cache) > 1024 {
+		cache = map[[32]byte]bool{}
+	}
+	cache[key] = ok
+	cacheMu.Unlock()
+	return ok
+}
+
+// Headers http_server_config.headers 를 모든 응답에 추가
+func (s *Server) Headers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config, _ := s.current()
+		for key, value := range config.HTTPConfig.Headers {
+			c.Header(key, value)
+		}
+		c.Next()
+	}
+}
+
+// Auth route group(metrics, api, health) 별 basic/bearer 인증
+func (s *Server) Auth(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config, _ := s.current()
+		mode := config.AuthMode(group)
+		if mode == AuthNone || authorized(config, mode, c.Request) {
+			c.Next()
+			return
+		}
+
+		if mode == AuthBasic || mode == AuthAny {
+			c.Header("WWW-Authenticate", `Basic realm="cnf-exporter"`)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"data": "unauthorized"})
+	}
+}
+
+func authorized(config *Config, mode string, r *http.Request) bool {
+	if mode == AuthBasic || mode == AuthAny {
+		if user, password, ok := r.BasicAuth(); ok {
+			hash, exists := config.Users[user]
+			if !exists {
+				_ = bcrypt.CompareHashAndPassword([]byte(dummyHash), []byte(password))
+				return false
+			}
+			return compare(hash, password)
+		}
+	}
+	if mode == AuthBearer || mode == AuthAny {
+		header := r.Header.Get("Authorization")
+		if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+			token := strings.TrimSpace(header[7:])
+			for _, hash := range config.BearerTokens {
+				if compare(hash, token) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config Prometheus exporter-toolkit web config 파일 형식
+// (tls_server_config, http_server_config, basic_auth_users) 에 bearer_tokens, route_auth 를 확장
+type Config struct {
+	TLSConfig  TLSConfig         `yaml:"tls_server_config"`
+	HTTPConfig HTTPConfig        `yaml:"http_server_config"`
+	Users      map[string]string `yaml:"basic_auth_users"` // 사용자: bcrypt hash
+
+	// 확장 설정
+	BearerTokens map[string]string `yaml:"bearer_tokens"` // 이름: 토큰 bcrypt hash
+	RouteAuth    map[string]string `yaml:"route_auth"`    // route group(metrics, api, health): none|basic|bearer|any
+}
+
+type TLSConfig struct {
+	CertFile                 string   `yaml:"cert_file"`
+	KeyFile                  string   `yaml:"key_file"`
+	ClientAuth               string   `yaml:"client_auth_type"`
+	ClientCAs                string   `yaml:"client_ca_file"`
+	ClientAllowedSans        []string `yaml:"client_allowed_sans"`
+	MinVersion               string   `yaml:"min_version"`
+	MaxVersion               string   `yaml:"max_version"`
+	CipherSuites             []string `yaml:"cipher_suites"`
+	CurvePreferences         []string `yaml:"curve_preferences"`
+	PreferServerCipherSuites bool     `yaml:"prefer_server_cipher_suites"`
+}
+
+type HTTPConfig struct {
+	HTTP2   *bool             `yaml:"http2"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// 인증 방식
+const (
+	AuthNone   = "none"
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAny    = "any"
+)
+
+// LoadConfig web config 파일 읽기, 파일 경로는 config 파일 위치 기준으로 변환
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Cause(err)
+	}
+	config := &Config{}
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return nil, errors.Wrap(err, "web config")
+	}
+
+	dir := filepath.Dir(path)
+	for _, file := range []*string{&config.TLSConfig.CertFile, &config.TLSConfig.KeyFile, &config.TLSConfig.ClientCAs} {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(dir, *file)
+		}
+	}
+
+	if (config.TLSConfig.CertFile == "") != (config.TLSConfig.KeyFile == "") {
+		return nil, fmt.Errorf("web config: cert_file and key_file must be set together")
+	}
+	if config.TLSConfig.ClientCAs != "" && !config.TLSEnabled() {
+		return nil, fmt.Errorf("web config: client_ca_file needs cert_file and key_file")
+	}
+	for group, mode := range config.RouteAuth {
+		switch strings.ToLower(mode) {
+		case AuthNone, AuthBasic, AuthBearer, AuthAny:
+		default:
+			return nil, fmt.Errorf("web config: route_auth %s: unknown mode %q", group, mode)
+		}
+	}
+	return config, nil
+}
+
+// TLSEnabled TLS 사용 여부
+func (c *Config) TLSEnabled() bool {
+	return c.TLSConfig.CertFile != ""
+}
+
+// AuthMode route group 의 인증 방식
+// route_auth 에 없으면 계정/토큰이 있을때 health 는 none, 나머지는 any
+func (c *Config) AuthMode(group string) string {
+	if mode, ok := c.RouteAuth[group]; ok {
+		return strings.ToLower(mode)
+	}
+	if group == "health" || (len(c.Users) == 0 && len(c.BearerTokens) == 0) {
+		return AuthNone
+	}
+	return AuthAny
+}
+
+// files 변경 확인 대상 파일
+func (c *Config) files() []string {
+	var files []string
+	for _, file := range []string{c.TLSConfig.CertFile, c.TLSConfig.KeyFile, c.TLSConfig.ClientCAs} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// buildTLS 인증서를 읽어 tls.Config 생성
+func (c *Config) buildTLS() (*tls.Config, error) {
+	if !c.TLSEnabled() {
+		return nil, nil
+	}
+	t := c.TLSConfig
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "web config: load certificate")
+	}
+	config := &tls.Config{
+		Certificates:             []tls.Certificate{cert},
+		MinVersion:               tls.VersionTLS12,
+		PreferServerCipherSuites: t.PreferServerCipherSuites,
+	}
+
+	if t.MinVersion != "" {
+		if config.MinVersion, err = tlsVersion(t.MinVersion); err != nil {
+			return nil, err
+		}
+	}
+	if t.MaxVersion != "" {
+		if config.MaxVersion, err = tlsVersion(t.MaxVersion); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range t.CipherSuites {
+		id, err := cipherSuite(name)
+		if err != nil {
+			return nil, err
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+	for _, name := range t.CurvePreferences {
+		curve, err := curveID(name)
+		if err != nil {
+			return nil, err
+		}
+		config.CurvePreferences = append(config.CurvePreferences, curve)
+	}
+
+	if t.ClientCAs != "" {
+		pem, err := os.ReadFile(t.ClientCAs)
+		if err != nil {
+			return nil, errors.Cause(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("web config: no certificate in client_ca_file")
+		}
+		config.ClientCAs = pool
+	}
+
+	switch t.ClientAuth {
+	case "", "NoClientCert":
+		config.ClientAuth = tls.NoClientCert
+	case "RequestClientCert":
+		config.ClientAuth = tls.RequestClientCert
+	case "RequireAnyClientCert", "RequireClientCert":
+		config.ClientAuth = tls.RequireAnyClientCert
+	case "VerifyClientCertIfGiven":
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case "RequireAndVerifyClientCert":
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("web config: unknown client_auth_type %q", t.ClientAuth)
+	}
+	if config.ClientCAs == nil && (config.ClientAuth == tls.VerifyClientCertIfGiven || config.ClientAuth == tls.RequireAndVerifyClientCert) {
+		return nil, fmt.Errorf("web config: client_auth_type %s needs client_ca_file", t.ClientAuth)
+	}
+
+	if len(t.ClientAllowedSans) > 0 {
+		allowed := t.ClientAllowedSans
+		config.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			for _, chain := range chains {
+				if len(chain) == 0 {
+					continue
+				}
+				leaf := chain[0]
+				sans := append(append([]string{}, leaf.DNSNames...), leaf.EmailAddresses...)
+				for _, ip := range leaf.IPAddresses {
+					sans = append(sans, ip.String())
+				}
+				for _, uri := range leaf.URIs {
+					sans = append(sans, uri.String())
+				}
+				for _, san := range sans {
+					for _, a := range allowed {
+						if san == a {
+							return nil
+						}
+					}
+				}
+			}
+			return fmt.Errorf("client certificate SAN is not allowed")
+		}
+	}
+	return config, nil
+}
+
+func tlsVersion(name string) (uint16, error) {
+	switch name {
+	case "TLS10":
+		return tls.VersionTLS10, nil
+	case "TLS11":
+		return tls.VersionTLS11, nil
+	case "TLS12":
+		return tls.VersionTLS12, nil
+	case "TLS13":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("web config: unknown TLS version %q", name)
+}
+
+func cipherSuite(name string) (uint16, error) {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if suite.Name == name {
+			return suite.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("web config: unknown cipher suite %q", name)
+}
+
+func curveID(name string) (tls.CurveID, error) {
+	switch name {
+	case "CurveP256":
+		return tls.CurveP256, nil
+	case "CurveP384":
+		return tls.CurveP384, nil
+	case "CurveP521":
+		return tls.CurveP521, nil
+	case "X25519":
+		return tls.X25519, nil
+	}
+	return 0, fmt.Errorf("web config: unknown curve %q", name)
+}
@@ -0,0 +1,132 @@
+/*
+* Samsung-cpc version 1.0
+*
+*  Copyright ⓒ 2023 kt corp. All rights reserved.
+*
+*  This is a proprietary software of kt corp, and you may not use this file except in
+*  compliance with license agreement with kt corp. Any redistribution or use of this
+*  software, with or without modification shall be strictly prohibited without prior written
+*  approval of kt corp, and the copyright notice above does not evidence any actual or
+*  intended publication of such software.
+ */
+package web
+
+import (
+	"crypto/tls"
+	"fmt"
+	"go.uber.org/zap"
+	"kt.com/p5g/cnf-exporter/samsung-cpc/logger"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 파일 변경 확인 최소 간격
+const reloadCheckInterval = time.Second
+
+// Server web config 파일과 인증서를 감시하여 바뀌면 다시 읽는다. (인증서 교체시 재시작 불필요)
+// 새 설정이 잘못되었으면 오류를 기록하고 이전 설정을 계속 사용한다.
+// TLS 사용 여부(cert_file 유무) 변경은 재시작해야 적용된다.
+type Server struct {
+	path string
+
+	mu      sync.Mutex
+	config  *Config
+	tls     *tls.Config
+	stamp   string
+	checked time.Time
+}
+
+// NewServer path 가 비어있으면 TLS, 인증 없이 동작
+func NewServer(path string) (*Server, error) {
+	s := &Server{path: path, config: &Config{}}
+	if path == "" {
+		return s, nil
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Server) reload() error {
+	config, err := LoadConfig(s.path)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := config.buildTLS()
+	if err != nil {
+		return err
+	}
+	s.config = config
+	s.tls = tlsConfig
+	s.stamp = stamp(append([]string{s.path}, config.files()...))
+	return nil
+}
+
+// current 변경된 파일이 있으면 다시 읽고 현재 설정 반환
+func (s *Server) current() (*Config, *tls.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" || time.Since(s.checked) < reloadCheckInterval {
+		return s.config, s.tls
+	}
+	s.checked = time.Now()
+
+	if stamp(append([]string{s.path}, s.config.files()...)) != s.stamp {
+		if err := s.reload(); err != nil {
+			logger.LogErr("web config reload failed, keep previous config", err)
+		} else {
+			logger.LogInfo("web config reloaded", zap.String("file", s.path))
+		}
+	}
+	return s.config, s.tls
+}
+
+// 파일별 수정시간, 크기
+func stamp(files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			fmt.Fprintf(&b, "%s:missing;", file)
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+	}
+	return b.String()
+}
+
+// ListenAndServe web config 에 따라 HTTP 또는 HTTPS 로 실행
+func (s *Server) ListenAndServe(server *http.Server) error {
+	config, tlsConfig := s.current()
+	if config.HTTPConfig.HTTP2 != nil && !*config.HTTPConfig.HTTP2 {
+		server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	if tlsConfig == nil {
+		logger.LogInfo("listening", zap.String("address", server.Addr), zap.Bool("tls", false))
+		return server.ListenAndServe()
+	}
+
+	server.TLSConfig = &tls.Config{
+		// 연결마다 최신 인증서/설정 사용
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			_, current := s.current()
+			if current == nil {
+				return nil, fmt.Errorf("tls is disabled in web config, restart required")
+			}
+			return current, nil
+		},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			_, current := s.current()
+			if current == nil || len(current.Certificates) == 0 {
+				return nil, fmt.Errorf("no certificate")
+			}
+			return &current.Certificates[0], nil
+		},
+	}
+	logger.LogInfo("listening", zap.String("address", server.Addr), zap.Bool("tls", true))
+	return server.ListenAndServeTLS("", "")
+}